@@ -0,0 +1,179 @@
+// Package bench holds the measurements of the benchmarks of the threshold
+// signature schemes, and writes them as text, JSON or CSV.
+package bench
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/montanaflynn/stats"
+)
+
+// Percentiles summarizes a set of latency samples, in milliseconds.
+type Percentiles struct {
+	Mean float64 `json:"mean"`
+	P50  float64 `json:"p50"`
+	P90  float64 `json:"p90"`
+	P99  float64 `json:"p99"`
+	Max  float64 `json:"max"`
+}
+
+// Summarize returns the percentiles of samples.
+func Summarize(samples []time.Duration) Percentiles {
+	if len(samples) == 0 {
+		return Percentiles{}
+	}
+	values := make([]float64, len(samples))
+	for i, d := range samples {
+		values[i] = float64(d.Nanoseconds()) / 1e6
+	}
+
+	var p Percentiles
+	p.Mean, _ = stats.Mean(values)
+	p.P50, _ = stats.PercentileNearestRank(values, 50)
+	p.P90, _ = stats.PercentileNearestRank(values, 90)
+	p.P99, _ = stats.PercentileNearestRank(values, 99)
+	p.Max, _ = stats.Max(values)
+	return p
+}
+
+// Result holds the measurements of the benchmark of one (T, N) setting.
+type Result struct {
+	Scheme string `json:"scheme"`
+
+	// Name of the parameter set, for schemes having several
+	Params string `json:"params,omitempty"`
+
+	T    int `json:"t"`
+	N    int `json:"n"`
+	Runs int `json:"runs"`
+
+	// Number of signing attempts needed per signature
+	AttemptsMean float64 `json:"attempts_mean"`
+	AttemptsMax  int     `json:"attempts_max"`
+
+	KeyGen Percentiles `json:"keygen_ms"`
+
+	// Time taken by one party for each round of one attempt
+	Rounds [3]Percentiles `json:"round_ms"`
+
+	// Time taken by the combiner for one attempt
+	Combine Percentiles `json:"combine_ms"`
+
+	// Time taken to produce a signature, running all parties one after
+	// another in a single goroutine
+	Sign Percentiles `json:"sign_ms"`
+
+	Verify Percentiles `json:"verify_ms"`
+
+	// Size of the message sent by one party in each round
+	RoundBytes     [3]int `json:"round_bytes"`
+	SignatureBytes int    `json:"signature_bytes,omitempty"`
+}
+
+// ParseFormat checks the output format given on the command line, text
+// being the default.
+func ParseFormat(format string) (string, error) {
+	switch format {
+	case "":
+		return "text", nil
+	case "text", "json", "csv":
+		return format, nil
+	}
+	return "", fmt.Errorf("format must be text, json or csv, not %q", format)
+}
+
+// Report runs bench, which passes every result it measures to each, and
+// writes the results to w in the given format: as they come for text, and
+// all at once at the end for JSON and CSV.
+func Report(w io.Writer, format string, bench func(each func(*Result)) error) error {
+	var results []*Result
+	err := bench(func(res *Result) {
+		results = append(results, res)
+		if format == "text" {
+			fmt.Fprintf(w, "t=%d n=%d: %.2f attempts, signing %.3f ms (p50), combine %.3f ms (p50)\n",
+				res.T, res.N, res.AttemptsMean, res.Sign.P50, res.Combine.P50)
+		}
+	})
+	if err != nil {
+		return err
+	}
+
+	switch format {
+	case "json":
+		return WriteJSON(w, results)
+	case "csv":
+		return WriteCSV(w, results)
+	}
+	return nil
+}
+
+// WriteJSON writes results as a JSON array.
+func WriteJSON(w io.Writer, results []*Result) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(results)
+}
+
+func percentilesHeader(name string) []string {
+	return []string{name + "_mean", name + "_p50", name + "_p90", name + "_p99", name + "_max"}
+}
+
+func (p Percentiles) record() []string {
+	f := func(v float64) string { return strconv.FormatFloat(v, 'f', 3, 64) }
+	return []string{f(p.Mean), f(p.P50), f(p.P90), f(p.P99), f(p.Max)}
+}
+
+// WriteCSV writes results as CSV, one line per (T, N) setting, latencies
+// being in milliseconds. Unknown signature sizes are left empty.
+func WriteCSV(w io.Writer, results []*Result) error {
+	header := []string{"scheme", "params", "t", "n", "runs", "attempts_mean", "attempts_max"}
+	header = append(header, percentilesHeader("keygen_ms")...)
+	for r := 1; r <= 3; r++ {
+		header = append(header, percentilesHeader("round"+strconv.Itoa(r)+"_ms")...)
+	}
+	header = append(header, percentilesHeader("combine_ms")...)
+	header = append(header, percentilesHeader("sign_ms")...)
+	header = append(header, percentilesHeader("verify_ms")...)
+	header = append(header, "round1_bytes", "round2_bytes", "round3_bytes", "signature_bytes")
+
+	cw := csv.NewWriter(w)
+	if err := cw.Write(header); err != nil {
+		return err
+	}
+	for _, res := range results {
+		rec := []string{
+			res.Scheme,
+			res.Params,
+			strconv.Itoa(res.T),
+			strconv.Itoa(res.N),
+			strconv.Itoa(res.Runs),
+			strconv.FormatFloat(res.AttemptsMean, 'f', 3, 64),
+			strconv.Itoa(res.AttemptsMax),
+		}
+		rec = append(rec, res.KeyGen.record()...)
+		for r := range res.Rounds {
+			rec = append(rec, res.Rounds[r].record()...)
+		}
+		rec = append(rec, res.Combine.record()...)
+		rec = append(rec, res.Sign.record()...)
+		rec = append(rec, res.Verify.record()...)
+		for _, b := range res.RoundBytes {
+			rec = append(rec, strconv.Itoa(b))
+		}
+		if res.SignatureBytes > 0 {
+			rec = append(rec, strconv.Itoa(res.SignatureBytes))
+		} else {
+			rec = append(rec, "")
+		}
+		if err := cw.Write(rec); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
@@ -0,0 +1,5 @@
+module bench
+
+go 1.22.0
+
+require github.com/montanaflynn/stats v0.7.1
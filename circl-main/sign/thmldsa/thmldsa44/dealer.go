@@ -0,0 +1,246 @@
+package thmldsa44
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/cloudflare/circl/internal/sha3"
+	"github.com/cloudflare/circl/sign/thmldsa/thmldsa44/internal"
+)
+
+var (
+	// ErrShareSet is returned when a party does not hold exactly the
+	// subset-shares it should, or the commitments do not cover them all.
+	ErrShareSet = internal.ErrShareSet
+
+	// ErrShareCommitment is returned when a subset-share does not match
+	// the dealer's commitment.
+	ErrShareCommitment = internal.ErrShareCommitment
+
+	// ErrPartialT is returned when the partial public keys of all
+	// subset-shares do not add up to the public key.
+	ErrPartialT = internal.ErrPartialT
+
+	// ErrCommitmentsMismatch is returned when two parties received
+	// different commitments from the dealer.
+	ErrCommitmentsMismatch = errors.New("parties received different share commitments")
+
+	// ErrCoHolderMismatch is returned when the co-holders of a subset-share
+	// disagree on it.
+	ErrCoHolderMismatch = errors.New("co-holders disagree on subset-share")
+)
+
+// ShareCommitments holds the dealer's published commitments to every
+// subset-share of a threshold key.
+type ShareCommitments internal.ShareCommitments
+
+// CommitShares computes the commitments to every subset-share of the private
+// key shares sks returned by NewThresholdKeysFromSeed. The dealer publishes
+// them to all parties along with the public key.
+func CommitShares(sks []PrivateKey, params *ThresholdParams) *ShareCommitments {
+	sks_ := make([]internal.PrivateKey, len(sks))
+	for i, v := range sks {
+		sks_[i] = internal.PrivateKey(v)
+	}
+	cmts := ShareCommitments(internal.CommitShares(sks_, (*internal.ThresholdParams)(params)))
+	return &cmts
+}
+
+// PartialKeys holds the partial public key A s₁ + s₂ of every subset-share
+// of a threshold key, with which the response of each signer can be checked
+// on its own.
+type PartialKeys map[uint8]internal.VecK
+
+// NewPartialKeys computes the partial public keys of every subset-share of
+// the private key shares sks returned by NewThresholdKeysFromSeed.
+func NewPartialKeys(sks []PrivateKey, params *ThresholdParams) *PartialKeys {
+	keys := make(PartialKeys)
+	for i := range sks {
+		isk := (*internal.PrivateKey)(&sks[i])
+		for _, u := range isk.ShareIndices() {
+			keys[u], _ = isk.PartialT(u)
+		}
+	}
+	return &keys
+}
+
+// Packs the commitments in increasing order of subset-share index.
+func (cmts *ShareCommitments) Bytes() []byte {
+	buf := make([]byte, 0, len(*cmts)*(1+internal.ShareCommitmentSize))
+	for u := 0; u < 256; u++ {
+		cmt, ok := (*cmts)[uint8(u)]
+		if !ok {
+			continue
+		}
+		buf = append(buf, uint8(u))
+		buf = append(buf, cmt[:]...)
+	}
+	return buf
+}
+
+// Packs the commitments.
+func (cmts *ShareCommitments) MarshalBinary() ([]byte, error) {
+	return cmts.Bytes(), nil
+}
+
+// Unpacks the commitments from data.
+func (cmts *ShareCommitments) UnmarshalBinary(data []byte) error {
+	const entrySize = 1 + internal.ShareCommitmentSize
+	if len(data)%entrySize != 0 {
+		return errors.New("packed share commitments have wrong length")
+	}
+	ret := make(ShareCommitments, len(data)/entrySize)
+	for off := 0; off < len(data); off += entrySize {
+		var cmt [internal.ShareCommitmentSize]byte
+		copy(cmt[:], data[off+1:off+entrySize])
+		if _, ok := ret[data[off]]; ok {
+			return errors.New("duplicate subset-share commitment")
+		}
+		ret[data[off]] = cmt
+	}
+	*cmts = ret
+	return nil
+}
+
+// Digest returns a hash of the packed commitments, so that parties can check
+// that the dealer published the same commitments to all of them.
+func (cmts *ShareCommitments) Digest() [32]byte {
+	var ret [32]byte
+	h := sha3.NewShake256()
+	_, _ = h.Write(cmts.Bytes())
+	_, _ = h.Read(ret[:])
+	return ret
+}
+
+// VerifyShares checks that sk holds exactly the subset-shares it should,
+// and that each of them matches the dealer's commitment.
+func VerifyShares(sk *PrivateKey, cmts *ShareCommitments, params *ThresholdParams) error {
+	return internal.VerifyShares(
+		(*internal.PrivateKey)(sk),
+		internal.ShareCommitments(*cmts),
+		(*internal.ThresholdParams)(params),
+	)
+}
+
+// ShareIndices returns in increasing order the index of every subset-share
+// held by sk. The index of a subset-share is the bitmask of the parties
+// holding it.
+func (sk *PrivateKey) ShareIndices() []uint8 {
+	return (*internal.PrivateKey)(sk).ShareIndices()
+}
+
+// ShareCheckMessageSize returns the size of the message sent by party id
+// in ShareCheckRound1.
+func (params *ThresholdParams) ShareCheckMessageSize(id uint8) int {
+	held := internal.HeldShareIndices(id, (*internal.ThresholdParams)(params))
+	return 32 + len(held)*(internal.ShareCommitmentSize+internal.PartialTSize)
+}
+
+// ShareCheckRound1 starts the verification of the dealer's output by party
+// sk. It checks the subset-shares of sk against the commitments and returns
+// the message to broadcast to all other parties: the digest of the
+// commitments, followed by the digest and the partial public key A s₁ + s₂
+// of every subset-share held by sk.
+func ShareCheckRound1(sk *PrivateKey, cmts *ShareCommitments, params *ThresholdParams) ([]byte, error) {
+	if err := VerifyShares(sk, cmts, params); err != nil {
+		return nil, err
+	}
+
+	isk := (*internal.PrivateKey)(sk)
+	digests := isk.ShareDigests()
+	cmtsDigest := cmts.Digest()
+
+	buf := make([]byte, 0, params.ShareCheckMessageSize(isk.Id))
+	buf = append(buf, cmtsDigest[:]...)
+	for _, u := range internal.HeldShareIndices(isk.Id, (*internal.ThresholdParams)(params)) {
+		var t [1]internal.VecK
+		var tbuf [internal.PartialTSize]byte
+
+		t[0], _ = isk.PartialT(u)
+		internal.PackW(t[:], tbuf[:])
+
+		digest := digests[u]
+		buf = append(buf, digest[:]...)
+		buf = append(buf, tbuf[:]...)
+	}
+	return buf, nil
+}
+
+// ShareCheckFinalize completes the verification of the dealer's output
+// given the messages of all N parties, msgs[i] being the one of party i.
+//
+// It checks that all parties received the same commitments, that the
+// co-holders of every subset-share agree on its digest and partial public key,
+// and that A·Σs₁ + Σs₂ rounds to the t1 of pk. A non-nil error means that
+// either the dealer or one of the parties named in the error misbehaved,
+// and that the key must not be used.
+func ShareCheckFinalize(pk *PublicKey, cmts *ShareCommitments, msgs [][]byte, params *ThresholdParams) error {
+	_, err := ShareCheckPartialKeys(pk, cmts, msgs, params)
+	return err
+}
+
+// ShareCheckPartialKeys is ShareCheckFinalize, additionally returning the
+// partial public keys of all subset-shares once they are checked.
+func ShareCheckPartialKeys(pk *PublicKey, cmts *ShareCommitments, msgs [][]byte, params *ThresholdParams) (*PartialKeys, error) {
+	iparams := (*internal.ThresholdParams)(params)
+	if len(msgs) != int(params.N) {
+		return nil, fmt.Errorf("expected %d messages, got %d", params.N, len(msgs))
+	}
+
+	cmtsDigest := cmts.Digest()
+	for i, msg := range msgs {
+		if len(msg) != params.ShareCheckMessageSize(uint8(i)) {
+			return nil, fmt.Errorf("party %d: wrong share check message length", i)
+		}
+		if [32]byte(msg[:32]) != cmtsDigest {
+			return nil, fmt.Errorf("party %d: %w", i, ErrCommitmentsMismatch)
+		}
+	}
+
+	// Offsets of the entries of each subset-share in the message of each party
+	offsets := make([]map[uint8]int, params.N)
+	for i := range offsets {
+		offsets[i] = make(map[uint8]int)
+		off := 32
+		for _, u := range internal.HeldShareIndices(uint8(i), iparams) {
+			offsets[i][u] = off
+			off += internal.ShareCommitmentSize + internal.PartialTSize
+		}
+	}
+
+	ts := make(map[uint8]internal.VecK)
+	for _, u := range internal.ShareIndices(iparams) {
+		var first []byte
+		firstID := 0
+		for i := 0; i < int(params.N); i++ {
+			if u&(1<<i) == 0 {
+				continue
+			}
+
+			off := offsets[i][u]
+			entry := msgs[i][off : off+internal.ShareCommitmentSize+internal.PartialTSize]
+			if [internal.ShareCommitmentSize]byte(entry) != (*cmts)[u] {
+				return nil, fmt.Errorf("party %d, subset-share %d: %w", i, u, ErrShareCommitment)
+			}
+
+			if first == nil {
+				first = entry
+				firstID = i
+				continue
+			}
+			if string(first) != string(entry) {
+				return nil, fmt.Errorf("parties %d and %d, subset-share %d: %w", firstID, i, u, ErrCoHolderMismatch)
+			}
+		}
+
+		var t [1]internal.VecK
+		internal.UnpackW(t[:], first[internal.ShareCommitmentSize:])
+		ts[u] = t[0]
+	}
+
+	if err := internal.CheckPartialTs((*internal.PublicKey)(pk), ts, iparams); err != nil {
+		return nil, err
+	}
+	keys := PartialKeys(ts)
+	return &keys, nil
+}
@@ -0,0 +1,193 @@
+package thmldsa44
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"testing"
+
+	common "github.com/cloudflare/circl/sign/internal/dilithium"
+	"github.com/cloudflare/circl/sign/thmldsa/thmldsa44/internal"
+)
+
+func shareCheck(pk *PublicKey, cmts []*ShareCommitments, sks []PrivateKey, params *ThresholdParams) error {
+	msgs := make([][]byte, len(sks))
+	for i := range sks {
+		var err error
+		msgs[i], err = ShareCheckRound1(&sks[i], cmts[i], params)
+		if err != nil {
+			return err
+		}
+	}
+	for i := range sks {
+		if err := ShareCheckFinalize(pk, cmts[i], msgs, params); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func TestShareCheck(t *testing.T) {
+	var seed [common.SeedSize]byte
+
+	for n := uint8(2); n <= 6; n++ {
+		for th := uint8(2); th <= n; th++ {
+			binary.LittleEndian.PutUint64(seed[:], uint64(n)<<8|uint64(th))
+			params, err := GetThresholdParams(th, n)
+			if err != nil {
+				t.Fatal(err)
+			}
+			pk, sks := NewThresholdKeysFromSeed(&seed, params)
+			cmts := CommitShares(sks, params)
+
+			var cmts2 ShareCommitments
+			if err := cmts2.UnmarshalBinary(cmts.Bytes()); err != nil {
+				t.Fatal(err)
+			}
+			if cmts2.Digest() != cmts.Digest() {
+				t.Fatal("commitments packing roundtrip failed")
+			}
+
+			all := make([]*ShareCommitments, n)
+			for i := range all {
+				all[i] = cmts
+			}
+			if err := shareCheck(pk, all, sks, params); err != nil {
+				t.Fatalf("t=%d n=%d: %v", th, n, err)
+			}
+		}
+	}
+}
+
+func TestShareCheckFaultyDealer(t *testing.T) {
+	var seed [common.SeedSize]byte
+
+	params, err := GetThresholdParams(3, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pk, sks := NewThresholdKeysFromSeed(&seed, params)
+	cmts := CommitShares(sks, params)
+	all := []*ShareCommitments{cmts, cmts, cmts, cmts, cmts}
+
+	// Wrong public key
+	seed[0] = 1
+	pk2, sks2 := NewThresholdKeysFromSeed(&seed, params)
+	if err := shareCheck(pk2, all, sks, params); !errors.Is(err, ErrPartialT) {
+		t.Fatalf("wrong public key not detected: %v", err)
+	}
+
+	// Share of another key dealt to party 1
+	bad := append([]PrivateKey{}, sks...)
+	bad[1] = sks2[1]
+	if err := shareCheck(pk, all, bad, params); !errors.Is(err, ErrShareCommitment) {
+		t.Fatalf("inconsistent share not detected: %v", err)
+	}
+
+	// Commitments consistent with the faulty share of party 1
+	badCmts := CommitShares(bad, params)
+	for i := range all {
+		all[i] = badCmts
+	}
+	if err := shareCheck(pk, all, bad, params); !errors.Is(err, ErrShareCommitment) {
+		t.Fatalf("inconsistent share not detected: %v", err)
+	}
+
+	// Different commitments published to party 0
+	all[0] = cmts
+	for i := 1; i < len(all); i++ {
+		all[i] = CommitShares(sks2, params)
+	}
+	if err := shareCheck(pk, all, sks, params); err == nil {
+		t.Fatal("equivocating dealer not detected")
+	}
+	msgs := make([][]byte, len(sks))
+	for i := range sks {
+		msgs[i], _ = ShareCheckRound1(&sks[i], cmts, params)
+	}
+	msgs[2], _ = ShareCheckRound1(&sks2[2], all[2], params)
+	if err := ShareCheckFinalize(pk, cmts, msgs, params); !errors.Is(err, ErrCommitmentsMismatch) {
+		t.Fatalf("equivocating dealer not detected: %v", err)
+	}
+}
+
+func TestVerifyResponse(t *testing.T) {
+	var seed [common.SeedSize]byte
+	msg, ctx := []byte("message"), []byte("context")
+
+	for _, tc := range []struct{ th, n, act uint8 }{{2, 2, 3}, {2, 3, 5}, {3, 5, 21}, {4, 6, 58}} {
+		binary.LittleEndian.PutUint64(seed[:], uint64(tc.n)<<8|uint64(tc.th))
+		params, err := GetThresholdParams(tc.th, tc.n)
+		if err != nil {
+			t.Fatal(err)
+		}
+		pk, sks := NewThresholdKeysFromSeed(&seed, params)
+
+		checkMsgs := make([][]byte, tc.n)
+		for i := range sks {
+			if checkMsgs[i], err = ShareCheckRound1(&sks[i], CommitShares(sks, params), params); err != nil {
+				t.Fatal(err)
+			}
+		}
+		checked, err := ShareCheckPartialKeys(pk, CommitShares(sks, params), checkMsgs, params)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var ids []uint8
+		for i := uint8(0); i < tc.n; i++ {
+			if tc.act&(1<<i) != 0 {
+				ids = append(ids, i)
+			}
+		}
+		// Retry until every signer sends a response for some iteration, as
+		// an all-zero response is accepted for any message
+		rejected := make([]byte, params.ResponseSize())
+		internal.PackResponses(make([]internal.VecL, params.K), rejected)
+		var msgs2, resps [][]byte
+		for retry := true; retry; {
+			st1s := make([]StRound1, len(ids))
+			msgs1 := make([][]byte, len(ids))
+			for j, id := range ids {
+				if msgs1[j], st1s[j], err = Round1(&sks[id], params); err != nil {
+					t.Fatal(err)
+				}
+			}
+			st2s := make([]StRound2, len(ids))
+			msgs2 = make([][]byte, len(ids))
+			for j, id := range ids {
+				if msgs2[j], st2s[j], err = Round2(&sks[id], tc.act, msg, ctx, msgs1, &st1s[j], params); err != nil {
+					t.Fatal(err)
+				}
+			}
+			resps = make([][]byte, len(ids))
+			for j, id := range ids {
+				if resps[j], err = Round3(&sks[id], msgs2, &st1s[j], &st2s[j], params); err != nil {
+					t.Fatal(err)
+				}
+			}
+			retry = false
+			for _, resp := range resps {
+				retry = retry || bytes.Equal(resp, rejected)
+			}
+		}
+
+		for _, keys := range []*PartialKeys{NewPartialKeys(sks, params), checked} {
+			for j, id := range ids {
+				if err := VerifyResponse(pk, keys, tc.act, id, msg, ctx, msgs2, resps[j], params); err != nil {
+					t.Fatalf("t=%d n=%d, party %d: %v", tc.th, tc.n, id, err)
+				}
+
+				// The response of another signer, or the response to
+				// another message
+				other := resps[(j+1)%len(ids)]
+				if err := VerifyResponse(pk, keys, tc.act, id, msg, ctx, msgs2, other, params); !errors.Is(err, ErrWrongResponse) {
+					t.Fatalf("t=%d n=%d, party %d: response of another party accepted", tc.th, tc.n, id)
+				}
+				if err := VerifyResponse(pk, keys, tc.act, id, []byte("other"), ctx, msgs2, resps[j], params); !errors.Is(err, ErrWrongResponse) {
+					t.Fatalf("t=%d n=%d, party %d: response to another message accepted", tc.th, tc.n, id)
+				}
+			}
+		}
+	}
+}
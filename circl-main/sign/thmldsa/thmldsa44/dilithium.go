@@ -7,6 +7,7 @@ import (
 	"crypto"
 	cryptoRand "crypto/rand"
 	"errors"
+	"fmt"
 	"io"
 
 	"github.com/cloudflare/circl/sign"
@@ -24,6 +25,19 @@ const (
 
 	// Size of a signature
 	SignatureSize = internal.SignatureSize
+
+	// Size of the Round1 message, a hash of the commitment sent in Round2
+	CommitmentHashSize = 32
+)
+
+var (
+	// ErrWrongCommitment is returned when a party's Round2 message does not
+	// open the commitment it sent in Round1.
+	ErrWrongCommitment = errors.New("wrong commitment")
+
+	// ErrWrongResponse is returned when a party's Round3 message is not a
+	// response to its commitment under its partial public key.
+	ErrWrongResponse = errors.New("wrong response")
 )
 
 // ThresholdParams contains parameters for threshold ML-DSA-44
@@ -33,6 +47,11 @@ func (params *ThresholdParams) ResponseSize() int {
 	return int(params.K) * internal.SingleResponseSize
 }
 
+// PrivateKeySize returns the size of a packed PrivateKey of one party.
+func (params *ThresholdParams) PrivateKeySize() int {
+	return (*internal.ThresholdParams)(params).PrivateKeySize()
+}
+
 func (params *ThresholdParams) CommitmentSize() int {
 	return int(params.K) * internal.SingleCommitmentSize
 }
@@ -110,7 +129,7 @@ func Round1(sk *PrivateKey, params *ThresholdParams) ([]byte, StRound1, error) {
 		return nil, StRound1{}, err
 	}
 
-	cmt := make([]byte, 32)
+	cmt := make([]byte, CommitmentHashSize)
 	wbuf := make([]byte, int(params.K) * internal.SingleCommitmentSize)
 
 	w, tmpcmtst := internal.GenThCommitment(
@@ -121,15 +140,29 @@ func Round1(sk *PrivateKey, params *ThresholdParams) ([]byte, StRound1, error) {
 	)
 	internal.PackW(w, wbuf[:])
 
-	s := sha3.NewShake256()
-	s.Write((*internal.PrivateKey)(sk).Tr[:])
-	s.Write([]byte{(*internal.PrivateKey)(sk).Id})
-	s.Write(wbuf)
-	s.Read(cmt[:])
+	hash := hashCommitment(&(*internal.PrivateKey)(sk).Tr, (*internal.PrivateKey)(sk).Id, wbuf)
+	copy(cmt, hash[:])
 
 	return cmt, StRound1{wbuf, tmpcmtst}, nil
 }
 
+// Computes the commitment H(tr ‖ id ‖ w) of party id to w.
+func hashCommitment(tr *[internal.TRSize]byte, id uint8, w []byte) [CommitmentHashSize]byte {
+	var hash [CommitmentHashSize]byte
+	s := sha3.NewShake256()
+	s.Write(tr[:])
+	s.Write([]byte{id})
+	s.Write(w)
+	s.Read(hash[:])
+	return hash
+}
+
+// CheckCommitment reports whether w, the message sent by party id in Round2,
+// opens the commitment cmt it sent in Round1.
+func CheckCommitment(pk *PublicKey, id uint8, cmt, w []byte) bool {
+	return len(cmt) == CommitmentHashSize && hashCommitment((*internal.PublicKey)(pk).Tr, id, w) == [CommitmentHashSize]byte(cmt)
+}
+
 // Sample a commitment w.
 func Round2(sk *PrivateKey, act uint8, msg, ctx []byte, msgsrd1 [][]byte, strd1 *StRound1, params *ThresholdParams) ([]byte, StRound2, error) {
 
@@ -160,6 +193,14 @@ func Round2(sk *PrivateKey, act uint8, msg, ctx []byte, msgsrd1 [][]byte, strd1
 
 // Compute a response to sign (msg, ctx) according to the commitments in cmts, with randomness cmtst.
 func Round3(sk *PrivateKey, msgsrd2 [][]byte, strd1 *StRound1, strd2 *StRound2, params *ThresholdParams) ([]byte, error) {
+	response, _, err := Round3WithReport(sk, msgsrd2, strd1, strd2, params)
+	return response, err
+}
+
+// Round3WithReport is Round3, additionally reporting for each of the K
+// iterations whether sk rejected its response, in which case it sends
+// a zero response for that iteration.
+func Round3WithReport(sk *PrivateKey, msgsrd2 [][]byte, strd1 *StRound1, strd2 *StRound2, params *ThresholdParams) ([]byte, []Rejection, error) {
 	wtmp := make([]internal.VecK, params.K)
 	wfinal := make([]internal.VecK, params.K)
 
@@ -176,15 +217,9 @@ func Round3(sk *PrivateKey, msgsrd2 [][]byte, strd1 *StRound1, strd2 *StRound2,
 		}
 
 		// Check that the commitments correspond to the one hashed in round 1
-		s := sha3.NewShake256()
-		s.Write((*internal.PrivateKey)(sk).Tr[:])
-		s.Write([]byte{j})
-		s.Write(msgsrd2[i])
-
-		var hash [32]byte
-		s.Read(hash[:])
+		hash := hashCommitment(&(*internal.PrivateKey)(sk).Tr, j, msgsrd2[i])
 		if hash != strd2.hashes[i] {
-			return nil, errors.New("wrong commitment")
+			return nil, nil, fmt.Errorf("party %d: %w", j, ErrWrongCommitment)
 		}
 
 		internal.UnpackW(wtmp, msgsrd2[i][:])
@@ -193,21 +228,30 @@ func Round3(sk *PrivateKey, msgsrd2 [][]byte, strd1 *StRound1, strd2 *StRound2,
 		j++
 	}
 
-	zs := internal.ComputeResponses((*internal.PrivateKey)(sk), strd2.act, strd2.mu, wfinal, strd1.cmtst, (*internal.ThresholdParams)(params))
+	zs, reasons := internal.ComputeResponsesWithReport((*internal.PrivateKey)(sk), strd2.act, strd2.mu, wfinal, strd1.cmtst, (*internal.ThresholdParams)(params))
 
 	response := make([]byte, params.ResponseSize())
 	internal.PackResponses(zs, response[:])
-	return response, nil
+	return response, reasons, nil
 }
 
 func Combine(pk *PublicKey, msg, ctx []byte, cmts [][]byte, resps [][]byte, sig []byte, params *ThresholdParams) bool {
+	ok, _ := CombineWithReport(pk, msg, ctx, cmts, resps, sig, params)
+	return ok
+}
+
+// CombineWithReport is Combine, additionally reporting for each of the K
+// iterations which check rejected it. Iterations for which some party sent
+// a zero response are reported as RejectedResponseNorm, as that party
+// rejected its own response.
+func CombineWithReport(pk *PublicKey, msg, ctx []byte, cmts [][]byte, resps [][]byte, sig []byte, params *ThresholdParams) (bool, []Rejection) {
 	zfinal := make([]internal.VecL, params.K)
 	ztmp := make([]internal.VecL, params.K)
 	wfinal := make([]internal.VecK, params.K)
 	wtmp := make([]internal.VecK, params.K)
 
 	if len(resps) < int(params.T) {
-		return false // Not enough responses to meet threshold
+		return false, nil // Not enough responses to meet threshold
 	}
 
 	// Compute wfinal
@@ -221,6 +265,7 @@ func Combine(pk *PublicKey, msg, ctx []byte, cmts [][]byte, resps [][]byte, sig
 	}
 
 	// Compute zfinal
+	rejected := make([]bool, params.K)
 	for i := 0; i < len(resps); i++ {
 		if len(resps[i]) != params.ResponseSize() {
 			panic("wrong commitment byte length")
@@ -228,10 +273,15 @@ func Combine(pk *PublicKey, msg, ctx []byte, cmts [][]byte, resps [][]byte, sig
 
 		internal.UnpackResponses(ztmp, resps[i][:])
 		internal.AggregateResponses(zfinal, ztmp)
+		for k := range ztmp {
+			if ztmp[k] == (internal.VecL{}) {
+				rejected[k] = true
+			}
+		}
 	}
 
 	// Combine
-	ret := internal.Combine((*internal.PublicKey)(pk), func(w io.Writer) {
+	ret, reasons := internal.CombineWithReport((*internal.PublicKey)(pk), func(w io.Writer) {
 		_, _ = w.Write([]byte{0})
 		_, _ = w.Write([]byte{byte(len(ctx))})
 
@@ -241,7 +291,70 @@ func Combine(pk *PublicKey, msg, ctx []byte, cmts [][]byte, resps [][]byte, sig
 		w.Write(msg)
 	}, wfinal, zfinal, sig[:], (*internal.ThresholdParams)(params))
 
-	return ret
+	for k := range reasons {
+		if rejected[k] && reasons[k] != Accepted && reasons[k] != NotTried {
+			reasons[k] = RejectedResponseNorm
+		}
+	}
+	return ret, reasons
+}
+
+// VerifyResponse checks resp, the Round3 message of party id for the signer
+// set act, against its own commitment under its partial public key, so that
+// a party sending a response of the right length but wrong content can be
+// told apart when Combine fails. cmts holds the Round2 messages of every
+// member of act, in member order.
+//
+// Returns ErrWrongResponse if the response does not match.
+func VerifyResponse(pk *PublicKey, keys *PartialKeys, act, id uint8, msg, ctx []byte, cmts [][]byte, resp []byte, params *ThresholdParams) error {
+	if act&(1<<id) == 0 {
+		return fmt.Errorf("party %d is not in the signer set", id)
+	}
+	if len(ctx) > 255 {
+		return sign.ErrContextTooLong
+	}
+	if len(resp) != params.ResponseSize() {
+		return ErrWrongResponse
+	}
+
+	wfinal := make([]internal.VecK, params.K)
+	wtmp := make([]internal.VecK, params.K)
+	var ws []internal.VecK
+	j := 0
+	for i := uint8(0); i < 8; i++ {
+		if act&(1<<i) == 0 {
+			continue
+		}
+		if j >= len(cmts) || len(cmts[j]) != params.CommitmentSize() {
+			return errors.New("wrong commitments")
+		}
+		internal.UnpackW(wtmp, cmts[j])
+		internal.AggregateCommitments(wfinal, wtmp)
+		if i == id {
+			ws = make([]internal.VecK, params.K)
+			copy(ws, wtmp)
+		}
+		j++
+	}
+	if j != len(cmts) {
+		return errors.New("wrong commitments")
+	}
+
+	zs := make([]internal.VecL, params.K)
+	internal.UnpackResponses(zs, resp)
+
+	if !internal.CheckResponse((*internal.PublicKey)(pk), *keys, id, act, func(w io.Writer) {
+		_, _ = w.Write([]byte{0})
+		_, _ = w.Write([]byte{byte(len(ctx))})
+
+		if ctx != nil {
+			_, _ = w.Write(ctx)
+		}
+		_, _ = w.Write(msg)
+	}, wfinal, ws, zs, (*internal.ThresholdParams)(params)) {
+		return ErrWrongResponse
+	}
+	return nil
 }
 
 // SignTo signs the given message and writes the signature into signature.
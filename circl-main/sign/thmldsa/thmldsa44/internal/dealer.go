@@ -0,0 +1,180 @@
+package internal
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/cloudflare/circl/internal/sha3"
+)
+
+const (
+	// Size of a packed subset-share s₁ ‖ s₂
+	ShareSize = PolyLeqEtaSize * (L + K)
+
+	// Size of a commitment to a subset-share
+	ShareCommitmentSize = 32
+
+	// Size of a packed partial public key A s₁ + s₂ of a subset-share
+	PartialTSize = K * PolyQSize
+)
+
+var (
+	// ErrShareSet is returned when a party does not hold exactly the
+	// subset-shares it should.
+	ErrShareSet = errors.New("unexpected set of subset-shares")
+
+	// ErrShareCommitment is returned when a subset-share does not match
+	// the dealer's commitment.
+	ErrShareCommitment = errors.New("subset-share does not match commitment")
+
+	// ErrPartialT is returned when the partial public keys do not add up
+	// to the public key.
+	ErrPartialT = errors.New("partial public keys do not match t1")
+)
+
+// ShareCommitments maps the index of every subset-share to the dealer's
+// commitment to it.
+type ShareCommitments map[uint8][ShareCommitmentSize]byte
+
+// Packs s₁ and s₂ into buf, which must be of length ShareSize.
+func (s *Share) pack(buf []byte) {
+	s.s1.PackLeqEta(buf)
+	s.s2.PackLeqEta(buf[PolyLeqEtaSize*L:])
+}
+
+// Computes the commitment H(tr ‖ u ‖ s₁ ‖ s₂) to the subset-share with index u.
+func (s *Share) commit(tr *[TRSize]byte, u uint8) [ShareCommitmentSize]byte {
+	var buf [ShareSize]byte
+	var ret [ShareCommitmentSize]byte
+
+	s.pack(buf[:])
+	h := sha3.NewShake256()
+	_, _ = h.Write(tr[:])
+	_, _ = h.Write([]byte{u})
+	_, _ = h.Write(buf[:])
+	_, _ = h.Read(ret[:])
+	return ret
+}
+
+// CommitShares computes the commitments to every subset-share dealt in sks.
+//
+// Panics if some subset-share is not held by any of the parties.
+func CommitShares(sks []PrivateKey, params *ThresholdParams) ShareCommitments {
+	cmts := make(ShareCommitments)
+	for _, u := range shareIndices(params) {
+		for i := range sks {
+			if share, ok := sks[i].shares[u]; ok {
+				cmts[u] = share.commit(&sks[i].Tr, u)
+				break
+			}
+		}
+		if _, ok := cmts[u]; !ok {
+			panic("subset-share not held by any party")
+		}
+	}
+	return cmts
+}
+
+// ShareDigests returns the commitment to every subset-share held by sk.
+func (sk *PrivateKey) ShareDigests() ShareCommitments {
+	ret := make(ShareCommitments, len(sk.shares))
+	for u, share := range sk.shares {
+		ret[u] = share.commit(&sk.Tr, u)
+	}
+	return ret
+}
+
+// ShareIndices returns in increasing order the index of every subset-share
+// held by sk.
+func (sk *PrivateKey) ShareIndices() []uint8 {
+	us := make([]uint8, 0, len(sk.shares))
+	for u := 0; u < 256; u++ {
+		if _, ok := sk.shares[uint8(u)]; ok {
+			us = append(us, uint8(u))
+		}
+	}
+	return us
+}
+
+// HeldShareIndices returns in increasing order the index of every
+// subset-share that party id should hold.
+func HeldShareIndices(id uint8, params *ThresholdParams) []uint8 {
+	var us []uint8
+	for _, u := range shareIndices(params) {
+		if u&(1<<id) != 0 {
+			us = append(us, u)
+		}
+	}
+	return us
+}
+
+// ShareIndices returns in increasing order the index of every subset-share.
+func ShareIndices(params *ThresholdParams) []uint8 {
+	return shareIndices(params)
+}
+
+// VerifyShares checks that cmts commits to exactly the subset-shares
+// of params, and that sk holds exactly its subset-shares, each of them
+// matching its commitment.
+func VerifyShares(sk *PrivateKey, cmts ShareCommitments, params *ThresholdParams) error {
+	us := shareIndices(params)
+	if len(cmts) != len(us) {
+		return ErrShareSet
+	}
+	for _, u := range us {
+		if _, ok := cmts[u]; !ok {
+			return ErrShareSet
+		}
+	}
+
+	held := HeldShareIndices(sk.Id, params)
+	if len(sk.shares) != len(held) {
+		return ErrShareSet
+	}
+	for _, u := range held {
+		share, ok := sk.shares[u]
+		if !ok {
+			return ErrShareSet
+		}
+		if share.commit(&sk.Tr, u) != cmts[u] {
+			return fmt.Errorf("subset-share %d: %w", u, ErrShareCommitment)
+		}
+	}
+	return nil
+}
+
+// PartialT returns A s₁ + s₂ for the subset-share with index u held by sk.
+func (sk *PrivateKey) PartialT(u uint8) (VecK, bool) {
+	var t VecK
+	share, ok := sk.shares[u]
+	if !ok {
+		return t, false
+	}
+	computeT(&sk.A, &share.s1h, &share.s2, &t)
+	return t, true
+}
+
+// CheckPartialTs checks that the sum of the partial public keys of all
+// subset-shares rounds to the t1 of pk.
+func CheckPartialTs(pk *PublicKey, ts map[uint8]VecK, params *ThresholdParams) error {
+	var t, t0, t1 VecK
+
+	us := shareIndices(params)
+	if len(ts) != len(us) {
+		return ErrShareSet
+	}
+	for _, u := range us {
+		tu, ok := ts[u]
+		if !ok {
+			return ErrShareSet
+		}
+		t.Add(&t, &tu)
+		t.Normalize()
+	}
+
+	t.Power2Round(&t0, &t1)
+	if t1 != pk.t1 {
+		return ErrPartialT
+	}
+	return nil
+}
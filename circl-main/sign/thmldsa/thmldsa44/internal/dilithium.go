@@ -5,6 +5,7 @@ package internal
 import (
 	"crypto/subtle"
 	"io"
+	"math"
 	"errors"
 
 	"github.com/cloudflare/circl/internal/sha3"
@@ -315,8 +316,7 @@ func NewThresholdKeysFromSeed(seed *[common.SeedSize]byte, params *ThresholdPara
 	}
 
 	// Sample the shares
-	honestSigners := uint8((1 << (params.N-params.T+1)) - 1)
-	for honestSigners < (1 << params.N) {
+	for _, honestSigners := range shareIndices(params) {
 		var share Share
 		var sSeed [64]byte
 		_, _ = h.Read(sSeed[:])	
@@ -345,11 +345,6 @@ func NewThresholdKeysFromSeed(seed *[common.SeedSize]byte, params *ThresholdPara
 		sktot.s1h.Add(&sktot.s1h, &share.s1h)
 		sktot.s2.Add(&sktot.s2, &share.s2)
 		sktot.s2h.Add(&sktot.s2h, &share.s2h)
-
-		// next possible set of honest signers
-		c := honestSigners & -honestSigners
-		r := honestSigners + c
-		honestSigners = (((r^honestSigners) >> 2) / c) | r
 	}
 
 	sktot.s1.Normalize()
@@ -397,21 +392,42 @@ func binomial(n, k uint8) int {
 	return c
 }
 
+// shareIndices returns the index of every subset-share for the given
+// parameters in increasing order. The index of a subset-share is the bitmask
+// of the N-T+1 parties holding it.
+func shareIndices(params *ThresholdParams) []uint8 {
+	var us []uint8
+	honestSigners := uint8((1 << (params.N - params.T + 1)) - 1)
+	for honestSigners < (1 << params.N) {
+		us = append(us, honestSigners)
+
+		// next possible set of honest signers
+		c := honestSigners & -honestSigners
+		r := honestSigners + c
+		honestSigners = (((r ^ honestSigners) >> 2) / c) | r
+	}
+	return us
+}
+
 // Computes t0 and t1 from s1h, s2 and A.
 func computeT0andT1(A *Mat, s1h *VecL, s2, t1 *VecK) {
 	var t0, t VecK
 
-	// Set t to A s₁ + s₂
+	computeT(A, s1h, s2, &t)
+
+	// Compute t₀, t₁ = Power2Round(t)
+	t.Power2Round(&t0, t1)
+}
+
+// Sets t to the normalized A s₁ + s₂.
+func computeT(A *Mat, s1h *VecL, s2, t *VecK) {
 	for i := 0; i < K; i++ {
 		PolyDotHat(&t[i], &A[i], s1h)
 		t[i].ReduceLe2Q()
 		t[i].InvNTT()
 	}
-	t.Add(&t, s2)
+	t.Add(t, s2)
 	t.Normalize()
-
-	// Compute t₀, t₁ = Power2Round(t)
-	t.Power2Round(&t0, t1)
 }
 
 // Verify checks whether the given signature by pk on msg is valid.
@@ -530,14 +546,12 @@ func ComputeMu(sk *PrivateKey, msg func(io.Writer)) [64]byte {
 	return mu
 }
 
-func recoverShare(sk *PrivateKey, act uint8, params *ThresholdParams) (s1h VecL, s2h VecK) {
+// SignerShareIndices returns the index of every subset-share which party id
+// adds up into its partial secret when signing with the signer set act.
+func SignerShareIndices(id, act uint8, params *ThresholdParams) []uint8 {
 	// Base case, when the party has only one share to use
 	if params.T == 1 || params.T == params.N {
-		for u := range sk.shares {
-			s1h = sk.shares[u].s1h
-			s2h = sk.shares[u].s2h
-			return
-		}
+		return HeldShareIndices(id, params)
 	}
 
 	// Otherwise, we rely on hardcoded sharing patterns
@@ -579,7 +593,7 @@ func recoverShare(sk *PrivateKey, act uint8, params *ThresholdParams) (s1h VecL,
 	i2 := params.T
 	currenti := 0
 	for j := uint8(0); j < params.N; j++ {
-		if j == sk.Id {
+		if j == id {
 			currenti = i1
 		}
 		if act & (1 << j) != 0 {
@@ -591,6 +605,7 @@ func recoverShare(sk *PrivateKey, act uint8, params *ThresholdParams) (s1h VecL,
 		}
 	}
 
+	us := make([]uint8, 0, len(sharing[currenti]))
 	for _, u := range sharing[currenti] {
 		// Translate the share index u to the share index u_
 		// by applying the permutation
@@ -600,10 +615,25 @@ func recoverShare(sk *PrivateKey, act uint8, params *ThresholdParams) (s1h VecL,
 				u_ |= (1 << perm[i])
 			}
 		}
+		us = append(us, u_)
+	}
+	return us
+}
+
+func recoverShare(sk *PrivateKey, act uint8, params *ThresholdParams) (s1h VecL, s2h VecK) {
+	us := SignerShareIndices(sk.Id, act, params)
 
+	// Base case, when the party has only one share to use
+	if params.T == 1 || params.T == params.N {
+		s1h = sk.shares[us[0]].s1h
+		s2h = sk.shares[us[0]].s2h
+		return
+	}
+
+	for _, u := range us {
 		// Add the share to the partial secret
-		s1h.Add(&s1h, &sk.shares[u_].s1h)
-		s2h.Add(&s2h, &sk.shares[u_].s2h)
+		s1h.Add(&s1h, &sk.shares[u].s1h)
+		s2h.Add(&s2h, &sk.shares[u].s2h)
 	}
 	s1h.Normalize()
 	s2h.Normalize()
@@ -612,6 +642,13 @@ func recoverShare(sk *PrivateKey, act uint8, params *ThresholdParams) (s1h VecL,
 }
 
 func ComputeResponses(sk *PrivateKey, act uint8, mu [64]byte, wfinals []VecK, stws []FVec, params *ThresholdParams) []VecL {
+	zs, _ := ComputeResponsesWithReport(sk, act, mu, wfinals, stws, params)
+	return zs
+}
+
+// ComputeResponsesWithReport is ComputeResponses, additionally reporting
+// for each of the K iterations whether its response was rejected.
+func ComputeResponsesWithReport(sk *PrivateKey, act uint8, mu [64]byte, wfinals []VecK, stws []FVec, params *ThresholdParams) ([]VecL, []Rejection) {
 	if act & (1 << sk.Id) == 0 {
 		panic("Specified user is not part of the signing set")
 	}
@@ -623,6 +660,7 @@ func ComputeResponses(sk *PrivateKey, act uint8, mu [64]byte, wfinals []VecK, st
 	var ch common.Poly
 	
 	zs := make([]VecL, params.K)
+	reasons := make([]Rejection, params.K)
 
 	h := sha3.NewShake256()
 
@@ -665,13 +703,14 @@ func ComputeResponses(sk *PrivateKey, act uint8, mu [64]byte, wfinals []VecK, st
 		zf.Add(&zf, &stws[i])
 
 		if zf.Excess(params.r, params.nu) { 
+			reasons[i] = RejectedResponseNorm
 			continue
 		}
 
 		zf.Round(&zs[i], &y)
 	}
 
-	return zs
+	return zs, reasons
 }
 
 func AggregateResponses(zfinals []VecL, zs []VecL) {
@@ -701,6 +740,14 @@ func UnpackResponses(zs []VecL, buf []byte) {
 }
 
 func Combine(pk *PublicKey, msg func(io.Writer), wfinals []VecK, zs []VecL, signature []byte, params *ThresholdParams) bool {
+	ok, _ := CombineWithReport(pk, msg, wfinals, zs, signature, params)
+	return ok
+}
+
+// CombineWithReport is Combine, additionally reporting for each of the K
+// iterations which check rejected it. Iterations after the accepted one
+// are reported as NotTried.
+func CombineWithReport(pk *PublicKey, msg func(io.Writer), wfinals []VecK, zs []VecL, signature []byte, params *ThresholdParams) (bool, []Rejection) {
 	var mu [64]byte
 	var zh VecL
 	var Az, Az2dct1, w0, w1, w0pf VecK
@@ -708,6 +755,11 @@ func Combine(pk *PublicKey, msg func(io.Writer), wfinals []VecK, zs []VecL, sign
 	var w1Packed [PolyW1Size * K]byte
 	var sig unpackedSignature
 
+	reasons := make([]Rejection, params.K)
+	for i := range reasons {
+		reasons[i] = NotTried
+	}
+
 	// μ = CRH(tr ‖ msg)
 	h := sha3.NewShake256()
 	_, _ = h.Write(pk.Tr[:])
@@ -724,6 +776,7 @@ func Combine(pk *PublicKey, msg func(io.Writer), wfinals []VecK, zs []VecL, sign
 
 		// Ensure ‖z‖_∞ < γ1 - beta.
 		if zs[i].Exceeds(Gamma1 - Beta) {
+			reasons[i] = RejectedZNorm
 			continue
 		}
 
@@ -761,6 +814,7 @@ func Combine(pk *PublicKey, msg func(io.Writer), wfinals []VecK, zs []VecL, sign
 
 		// Ensure ‖c*t0 - c*s2 - e_2‖_∞ < γ₂.
 		if f.Exceeds(Gamma2) {
+			reasons[i] = RejectedHintNorm
 			continue
 		}
 
@@ -773,11 +827,86 @@ func Combine(pk *PublicKey, msg func(io.Writer), wfinals []VecK, zs []VecL, sign
 
 		if hintPop <= Omega {
 			sig.Pack(signature)
-			return true
+			reasons[i] = Accepted
+			return true, reasons
 		}
+		reasons[i] = RejectedHintCount
 	}
 
-	return false
+	return false, reasons
+}
+
+// CheckResponse reports whether zs, the responses of party id for the
+// signer set act, are consistent with its commitments ws, given the
+// aggregated commitments wfinals and the partial public key A s₁ + s₂ of
+// every subset-share in ts.
+//
+// For an honest party, A z − c t − w = −c s₂ − e where t is the sum of the
+// partial public keys of the subset-shares it signs with, which is bounded
+// by r' + τ η times their number. Iterations for which the party sent a zero
+// response are skipped, as it rejected its own response.
+func CheckResponse(pk *PublicKey, ts map[uint8]VecK, id, act uint8, msg func(io.Writer), wfinals, ws []VecK, zs []VecL, params *ThresholdParams) bool {
+	var mu [64]byte
+	var c [CTildeSize]byte
+	var ch common.Poly
+	var w0, w1, t, th, Az, f VecK
+	var zh VecL
+	var w1Packed [PolyW1Size * K]byte
+
+	us := SignerShareIndices(id, act, params)
+	for _, u := range us {
+		tu, ok := ts[u]
+		if !ok {
+			return false
+		}
+		t.Add(&t, &tu)
+		t.Normalize()
+	}
+	th = t
+	th.NTT()
+	bound := uint32(math.Ceil(params.rPrime)) + 1 + Tau*Eta*uint32(len(us))
+
+	// μ = CRH(tr ‖ msg)
+	h := sha3.NewShake256()
+	_, _ = h.Write(pk.Tr[:])
+	msg(&h)
+	_, _ = h.Read(mu[:])
+
+	for i := uint16(0); i < params.K; i++ {
+		if zs[i] == (VecL{}) {
+			continue
+		}
+
+		// c~ = H(μ ‖ w₁)
+		wfinals[i].Decompose(&w0, &w1)
+		w1.PackW1(w1Packed[:])
+		h.Reset()
+		_, _ = h.Write(mu[:])
+		_, _ = h.Write(w1Packed[:])
+		_, _ = h.Read(c[:])
+
+		PolyDeriveUniformBall(&ch, c[:])
+		ch.NTT()
+
+		// Compute A z - c t - w
+		zh = zs[i]
+		zh.NTT()
+		for j := 0; j < K; j++ {
+			PolyDotHat(&Az[j], &pk.A[j], &zh)
+			f[j].MulHat(&th[j], &ch)
+		}
+		f.Sub(&Az, &f)
+		f.ReduceLe2Q()
+		f.InvNTT()
+		f.NormalizeAssumingLe2Q()
+		f.Sub(&f, &ws[i])
+		f.Normalize()
+
+		if f.Exceeds(bound) {
+			return false
+		}
+	}
+	return true
 }
 
 
@@ -0,0 +1,65 @@
+package internal
+
+// Sets s to the subset-share packed in buf, which must be of length ShareSize.
+func (s *Share) unpack(buf []byte) {
+	s.s1.UnpackLeqEta(buf)
+	s.s2.UnpackLeqEta(buf[PolyLeqEtaSize*L:])
+
+	s.s1h = s.s1
+	s.s1h.NTT()
+	s.s2h = s.s2
+	s.s2h.NTT()
+}
+
+// CommonShareIndices returns in increasing order the index of every
+// subset-share held by both party a and party b.
+func CommonShareIndices(a, b uint8, params *ThresholdParams) []uint8 {
+	var us []uint8
+	for _, u := range HeldShareIndices(a, params) {
+		if u&(1<<b) != 0 {
+			us = append(us, u)
+		}
+	}
+	return us
+}
+
+// PackShare packs the subset-share with index u held by sk into buf,
+// which must be of length ShareSize.
+//
+// Returns false if sk does not hold that subset-share.
+func (sk *PrivateKey) PackShare(u uint8, buf []byte) bool {
+	share, ok := sk.shares[u]
+	if !ok {
+		return false
+	}
+	share.pack(buf)
+	return true
+}
+
+// ShareCommitment returns the commitment to the subset-share with index u
+// packed in buf, for the key with public key pk.
+func ShareCommitment(pk *PublicKey, u uint8, buf []byte) [ShareCommitmentSize]byte {
+	var share Share
+	share.unpack(buf)
+	return share.commit(pk.Tr, u)
+}
+
+// NewPrivateKeyFromShares rebuilds the private key of party id from pk,
+// the seed key only used for non-threshold signing, and the packed
+// subset-shares indexed by their index.
+func NewPrivateKeyFromShares(pk *PublicKey, id uint8, key [32]byte, shares map[uint8][]byte) *PrivateKey {
+	sk := &PrivateKey{
+		Id:     id,
+		rho:    pk.rho,
+		key:    key,
+		Tr:     *pk.Tr,
+		A:      *pk.A,
+		shares: make(map[uint8]*Share, len(shares)),
+	}
+	for u, buf := range shares {
+		share := new(Share)
+		share.unpack(buf)
+		sk.shares[u] = share
+	}
+	return sk
+}
@@ -0,0 +1,47 @@
+package internal
+
+// Rejection tells why one of the K parallel iterations of a threshold
+// signing attempt did not yield a signature.
+type Rejection uint8
+
+const (
+	// The iteration yielded the signature.
+	Accepted Rejection = iota
+
+	// A party rejected its response: ‖(z, e)‖ exceeded the radius r.
+	RejectedResponseNorm
+
+	// The combined response failed ‖z‖_∞ < γ₁ - β.
+	RejectedZNorm
+
+	// The combined response failed ‖c·t₀ - c·s₂ - e‖_∞ < γ₂.
+	RejectedHintNorm
+
+	// The signature would have more than ω hints.
+	RejectedHintCount
+
+	// The iteration was not checked, as an earlier one was accepted.
+	NotTried
+
+	// Number of distinct rejection reasons
+	NumRejections = iota
+)
+
+func (r Rejection) String() string {
+	switch r {
+	case Accepted:
+		return "accepted"
+	case RejectedResponseNorm:
+		return "response norm"
+	case RejectedZNorm:
+		return "z bound"
+	case RejectedHintNorm:
+		return "gamma2 bound"
+	case RejectedHintCount:
+		return "hint count"
+	case NotTried:
+		return "not tried"
+	default:
+		return "unknown"
+	}
+}
@@ -0,0 +1,269 @@
+package thmldsa44
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdh"
+	"crypto/ed25519"
+	cryptoRand "crypto/rand"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/cloudflare/circl/internal/sha3"
+	"github.com/cloudflare/circl/sign/thmldsa/thmldsa44/internal"
+)
+
+const (
+	// Size of the recovery request sent in RecoveryRound1
+	RecoveryRequestSize = 1 + 32 + ed25519.SignatureSize
+
+	recoveryHeaderSize = 2 + 32
+	recoveryTagSize    = 16
+)
+
+var (
+	// ErrNotEnoughCoHolders is returned when a subset-share cannot be
+	// recovered from enough co-holders to detect a malicious sender.
+	ErrNotEnoughCoHolders = errors.New("not enough co-holders to recover subset-share")
+
+	// ErrRecoveryMessage is returned when a recovery message is malformed
+	// or fails to decrypt.
+	ErrRecoveryMessage = errors.New("invalid recovery message")
+)
+
+// StRecovery is the state of a party recovering its key share.
+type StRecovery struct {
+	id  uint8
+	key *ecdh.PrivateKey
+}
+
+// RecoveryRound1 starts the recovery of the key share of party id, e.g. after
+// it lost its storage. It samples an ephemeral encryption key and returns the
+// request to send to the other parties, signed with identity, the long-term
+// identity key of party id, which is kept apart from its key share.
+func RecoveryRound1(rand io.Reader, id uint8, identity ed25519.PrivateKey, pk *PublicKey) ([]byte, *StRecovery, error) {
+	if rand == nil {
+		rand = cryptoRand.Reader
+	}
+	if len(identity) != ed25519.PrivateKeySize {
+		return nil, nil, errors.New("invalid identity key")
+	}
+
+	key, err := ecdh.X25519().GenerateKey(rand)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	req := make([]byte, 0, RecoveryRequestSize)
+	req = append(req, id)
+	req = append(req, key.PublicKey().Bytes()...)
+	req = append(req, ed25519.Sign(identity, recoveryRequestMessage(pk, req))...)
+	return req, &StRecovery{id, key}, nil
+}
+
+// Returns the message signed in a recovery request for the party and the
+// ephemeral key in req, bound to the public key pk.
+func recoveryRequestMessage(pk *PublicKey, req []byte) []byte {
+	msg := []byte("thmldsa44 share recovery request")
+	msg = append(msg, (*internal.PublicKey)(pk).Tr[:]...)
+	return append(msg, req[:1+32]...)
+}
+
+// Derives the key protecting the subset-shares sent by party from to party id.
+func recoveryKey(pk *PublicKey, from, id uint8, senderPub, recipientPub, shared []byte) []byte {
+	key := make([]byte, 32)
+	h := sha3.NewShake256()
+	_, _ = h.Write([]byte("thmldsa44 share recovery"))
+	_, _ = h.Write((*internal.PublicKey)(pk).Tr[:])
+	_, _ = h.Write([]byte{from, id})
+	_, _ = h.Write(senderPub)
+	_, _ = h.Write(recipientPub)
+	_, _ = h.Write(shared)
+	_, _ = h.Read(key)
+	return key
+}
+
+func recoveryAEAD(key []byte) cipher.AEAD {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		panic(err)
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		panic(err)
+	}
+	return aead
+}
+
+// RecoveryRound2 is run by every surviving party sk upon a recovery request
+// req. The request must be signed with the identity key of the recovering
+// party, identities[i] being the public identity key of party i, or it is
+// rejected with ErrRecoveryMessage. It returns the subset-shares that sk has
+// in common with the recovering party, encrypted to the ephemeral key of the
+// request, or nil if they have none in common.
+//
+// The message must be sent over a channel authenticating sk as its sender.
+func RecoveryRound2(rand io.Reader, sk *PrivateKey, pk *PublicKey, req []byte, identities []ed25519.PublicKey, params *ThresholdParams) ([]byte, error) {
+	if rand == nil {
+		rand = cryptoRand.Reader
+	}
+
+	if len(req) != RecoveryRequestSize || req[0] >= params.N {
+		return nil, ErrRecoveryMessage
+	}
+	isk := (*internal.PrivateKey)(sk)
+	id := req[0]
+	if int(id) >= len(identities) || len(identities[id]) != ed25519.PublicKeySize ||
+		!ed25519.Verify(identities[id], recoveryRequestMessage(pk, req), req[1+32:]) {
+		return nil, ErrRecoveryMessage
+	}
+	if id == isk.Id {
+		return nil, errors.New("cannot send own subset-shares to oneself")
+	}
+
+	us := internal.CommonShareIndices(isk.Id, id, (*internal.ThresholdParams)(params))
+	if len(us) == 0 {
+		return nil, nil
+	}
+
+	recipientPub, err := ecdh.X25519().NewPublicKey(req[1 : 1+32])
+	if err != nil {
+		return nil, ErrRecoveryMessage
+	}
+	eph, err := ecdh.X25519().GenerateKey(rand)
+	if err != nil {
+		return nil, err
+	}
+	shared, err := eph.ECDH(recipientPub)
+	if err != nil {
+		return nil, ErrRecoveryMessage
+	}
+
+	pt := make([]byte, len(us)*internal.ShareSize)
+	for i, u := range us {
+		if !isk.PackShare(u, pt[i*internal.ShareSize:]) {
+			return nil, ErrShareSet
+		}
+	}
+
+	senderPub := eph.PublicKey().Bytes()
+	aead := recoveryAEAD(recoveryKey(pk, isk.Id, id, senderPub, req[1:1+32], shared))
+	nonce := make([]byte, aead.NonceSize())
+
+	msg := make([]byte, 0, recoveryHeaderSize+len(pt)+recoveryTagSize)
+	msg = append(msg, isk.Id, id)
+	msg = append(msg, senderPub...)
+	msg = aead.Seal(msg, nonce, pt, msg[:recoveryHeaderSize])
+
+	for i := range pt {
+		pt[i] = 0
+	}
+	return msg, nil
+}
+
+// RecoveryFinalize rebuilds the private key of the recovering party from the
+// responses msgs of the other parties, msgs[i] being the one of party i.
+// Missing responses may be left nil.
+//
+// Every subset-share must be received from at least two co-holders that
+// agree on it. If cmts, the dealer's commitments, is not nil, every received
+// copy must also match its commitment, and a single co-holder is enough when
+// it is the only one. A non-nil error names the sender that misbehaved, or
+// the co-holders that disagree when the culprit cannot be told.
+func RecoveryFinalize(rand io.Reader, st *StRecovery, pk *PublicKey, cmts *ShareCommitments, msgs [][]byte, params *ThresholdParams) (*PrivateKey, error) {
+	if rand == nil {
+		rand = cryptoRand.Reader
+	}
+
+	iparams := (*internal.ThresholdParams)(params)
+	if len(msgs) != int(params.N) {
+		return nil, fmt.Errorf("expected %d messages, got %d", params.N, len(msgs))
+	}
+
+	// Decrypt every response
+	copies := make(map[uint8]map[int][]byte)
+	for i, msg := range msgs {
+		if msg == nil || i == int(st.id) {
+			continue
+		}
+
+		us := internal.CommonShareIndices(uint8(i), st.id, iparams)
+		if len(msg) != recoveryHeaderSize+len(us)*internal.ShareSize+recoveryTagSize ||
+			msg[0] != uint8(i) || msg[1] != st.id {
+			return nil, fmt.Errorf("party %d: %w", i, ErrRecoveryMessage)
+		}
+
+		senderPub, err := ecdh.X25519().NewPublicKey(msg[2:recoveryHeaderSize])
+		if err != nil {
+			return nil, fmt.Errorf("party %d: %w", i, ErrRecoveryMessage)
+		}
+		shared, err := st.key.ECDH(senderPub)
+		if err != nil {
+			return nil, fmt.Errorf("party %d: %w", i, ErrRecoveryMessage)
+		}
+
+		aead := recoveryAEAD(recoveryKey(pk, uint8(i), st.id, msg[2:recoveryHeaderSize], st.key.PublicKey().Bytes(), shared))
+		nonce := make([]byte, aead.NonceSize())
+		pt, err := aead.Open(nil, nonce, msg[recoveryHeaderSize:], msg[:recoveryHeaderSize])
+		if err != nil {
+			return nil, fmt.Errorf("party %d: %w", i, ErrRecoveryMessage)
+		}
+
+		for j, u := range us {
+			if copies[u] == nil {
+				copies[u] = make(map[int][]byte)
+			}
+			copies[u][i] = pt[j*internal.ShareSize : (j+1)*internal.ShareSize]
+		}
+	}
+
+	// Cross-check the copies of every subset-share
+	shares := make(map[uint8][]byte)
+	for _, u := range internal.HeldShareIndices(st.id, iparams) {
+		var first []byte
+		firstID := 0
+		for i := 0; i < int(params.N); i++ {
+			buf, ok := copies[u][i]
+			if !ok {
+				continue
+			}
+
+			if cmts != nil && internal.ShareCommitment((*internal.PublicKey)(pk), u, buf) != (*cmts)[u] {
+				return nil, fmt.Errorf("party %d, subset-share %d: %w", i, u, ErrShareCommitment)
+			}
+			if first == nil {
+				first = buf
+				firstID = i
+				continue
+			}
+			if string(first) != string(buf) {
+				return nil, fmt.Errorf("parties %d and %d, subset-share %d: %w", firstID, i, u, ErrCoHolderMismatch)
+			}
+		}
+
+		// Number of other parties that hold the subset-share
+		coHolders := int(params.N - params.T)
+		needed := 2
+		if cmts != nil && coHolders < needed {
+			needed = coHolders
+		}
+		if needed == 0 || len(copies[u]) < needed {
+			return nil, fmt.Errorf("subset-share %d: %w", u, ErrNotEnoughCoHolders)
+		}
+		shares[u] = first
+	}
+
+	var key [32]byte
+	if _, err := io.ReadFull(rand, key[:]); err != nil {
+		return nil, err
+	}
+
+	sk := (*PrivateKey)(internal.NewPrivateKeyFromShares((*internal.PublicKey)(pk), st.id, key, shares))
+	if cmts != nil {
+		if err := VerifyShares(sk, cmts, params); err != nil {
+			return nil, err
+		}
+	}
+	return sk, nil
+}
@@ -0,0 +1,150 @@
+package thmldsa44
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"errors"
+	"testing"
+
+	common "github.com/cloudflare/circl/sign/internal/dilithium"
+)
+
+// Returns the identity keys of n parties and their public keys.
+func recoveryIdentities(n uint8) ([]ed25519.PrivateKey, []ed25519.PublicKey) {
+	keys := make([]ed25519.PrivateKey, n)
+	pubs := make([]ed25519.PublicKey, n)
+	for i := range keys {
+		seed := make([]byte, ed25519.SeedSize)
+		seed[0] = uint8(i)
+		keys[i] = ed25519.NewKeyFromSeed(seed)
+		pubs[i] = keys[i].Public().(ed25519.PublicKey)
+	}
+	return keys, pubs
+}
+
+func recoverShare(t *testing.T, pk *PublicKey, cmts *ShareCommitments, senders []PrivateKey, id uint8, params *ThresholdParams) (*PrivateKey, error) {
+	keys, identities := recoveryIdentities(params.N)
+	req, st, err := RecoveryRound1(nil, id, keys[id], pk)
+	if err != nil {
+		t.Fatal(err)
+	}
+	msgs := make([][]byte, params.N)
+	for i := range senders {
+		if uint8(i) == id {
+			continue
+		}
+		msgs[i], err = RecoveryRound2(nil, &senders[i], pk, req, identities, params)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	return RecoveryFinalize(nil, st, pk, cmts, msgs, params)
+}
+
+func TestRecovery(t *testing.T) {
+	var seed [common.SeedSize]byte
+
+	for n := uint8(2); n <= 6; n++ {
+		for th := uint8(2); th <= n; th++ {
+			params, err := GetThresholdParams(th, n)
+			if err != nil {
+				t.Fatal(err)
+			}
+			pk, sks := NewThresholdKeysFromSeed(&seed, params)
+			cmts := CommitShares(sks, params)
+
+			for id := uint8(0); id < n; id++ {
+				for _, c := range []*ShareCommitments{nil, cmts} {
+					sk, err := recoverShare(t, pk, c, sks, id, params)
+					if th == n || (th == n-1 && c == nil) {
+						if !errors.Is(err, ErrNotEnoughCoHolders) {
+							t.Fatalf("t=%d n=%d: expected ErrNotEnoughCoHolders, got %v", th, n, err)
+						}
+						continue
+					}
+					if err != nil {
+						t.Fatalf("t=%d n=%d id=%d: %v", th, n, id, err)
+					}
+
+					msg1, _ := ShareCheckRound1(&sks[id], cmts, params)
+					msg2, err := ShareCheckRound1(sk, cmts, params)
+					if err != nil || !bytes.Equal(msg1, msg2) {
+						t.Fatalf("t=%d n=%d id=%d: recovered share differs", th, n, id)
+					}
+				}
+			}
+		}
+	}
+}
+
+func TestRecoveryMaliciousSender(t *testing.T) {
+	var seed [common.SeedSize]byte
+
+	params, err := GetThresholdParams(3, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pk, sks := NewThresholdKeysFromSeed(&seed, params)
+	cmts := CommitShares(sks, params)
+	seed[0] = 1
+	_, sks2 := NewThresholdKeysFromSeed(&seed, params)
+
+	senders := append([]PrivateKey{}, sks...)
+	senders[3] = sks2[3]
+
+	if _, err := recoverShare(t, pk, nil, senders, 0, params); !errors.Is(err, ErrCoHolderMismatch) {
+		t.Fatalf("malicious sender not detected: %v", err)
+	}
+	_, err = recoverShare(t, pk, cmts, senders, 0, params)
+	if !errors.Is(err, ErrShareCommitment) || err.Error()[:7] != "party 3" {
+		t.Fatalf("malicious sender not identified: %v", err)
+	}
+
+	// Response encrypted to another request
+	keys, identities := recoveryIdentities(params.N)
+	req, st, _ := RecoveryRound1(nil, 0, keys[0], pk)
+	req2, _, _ := RecoveryRound1(nil, 0, keys[0], pk)
+	msgs := make([][]byte, params.N)
+	for i := 1; i < int(params.N); i++ {
+		msgs[i], _ = RecoveryRound2(nil, &sks[i], pk, req, identities, params)
+	}
+	msgs[2], _ = RecoveryRound2(nil, &sks[2], pk, req2, identities, params)
+	if _, err := RecoveryFinalize(nil, st, pk, cmts, msgs, params); !errors.Is(err, ErrRecoveryMessage) {
+		t.Fatalf("undecryptable response not detected: %v", err)
+	}
+}
+
+func TestRecoveryForgedRequest(t *testing.T) {
+	var seed [common.SeedSize]byte
+
+	params, err := GetThresholdParams(3, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pk, sks := NewThresholdKeysFromSeed(&seed, params)
+	seed[0] = 1
+	otherPk, _ := NewThresholdKeysFromSeed(&seed, params)
+	keys, identities := recoveryIdentities(params.N)
+
+	// Party 1 asks for the subset-shares of party 0
+	forged, _, err := RecoveryRound1(nil, 0, keys[1], pk)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Valid request of party 0, with the ephemeral key replaced
+	tampered, _, _ := RecoveryRound1(nil, 0, keys[0], pk)
+	tampered[1] ^= 1
+	// Request of party 0 for another key
+	otherKey, _, _ := RecoveryRound1(nil, 0, keys[0], otherPk)
+
+	for name, req := range map[string][]byte{
+		"signed by another party": forged,
+		"tampered ephemeral key":  tampered,
+		"for another key":         otherKey,
+		"unsigned":                forged[:1+32],
+	} {
+		if _, err := RecoveryRound2(nil, &sks[2], pk, req, identities, params); !errors.Is(err, ErrRecoveryMessage) {
+			t.Fatalf("%s: request accepted: %v", name, err)
+		}
+	}
+}
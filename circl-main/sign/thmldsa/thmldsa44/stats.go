@@ -0,0 +1,166 @@
+package thmldsa44
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/cloudflare/circl/sign/thmldsa/thmldsa44/internal"
+)
+
+// Rejection tells why one of the K parallel iterations of a signing attempt
+// did not yield a signature.
+type Rejection = internal.Rejection
+
+const (
+	// The iteration yielded the signature.
+	Accepted = internal.Accepted
+
+	// A party rejected its response, whose norm exceeded the radius r.
+	RejectedResponseNorm = internal.RejectedResponseNorm
+
+	// The combined response z failed the bound γ₁ - β.
+	RejectedZNorm = internal.RejectedZNorm
+
+	// The combined response failed the bound γ₂ needed for the hints.
+	RejectedHintNorm = internal.RejectedHintNorm
+
+	// The signature would have more than ω hints.
+	RejectedHintCount = internal.RejectedHintCount
+
+	// The iteration was not checked, as an earlier one was accepted.
+	NotTried = internal.NotTried
+
+	// Number of distinct rejection reasons
+	NumRejections = internal.NumRejections
+)
+
+// Observer is notified of the outcome of every signing attempt, given as
+// the rejection reason of each of its K iterations.
+type Observer interface {
+	ObserveAttempt(reasons []Rejection)
+}
+
+// MergeRejections merges the reports of Round3WithReport of the signers
+// into the report of CombineWithReport: iterations rejected by a signer
+// are reported as RejectedResponseNorm.
+func MergeRejections(combine []Rejection, responses ...[]Rejection) []Rejection {
+	ret := append([]Rejection{}, combine...)
+	for _, rs := range responses {
+		for k, r := range rs {
+			if k < len(ret) && r == RejectedResponseNorm && ret[k] != Accepted && ret[k] != NotTried {
+				ret[k] = RejectedResponseNorm
+			}
+		}
+	}
+	return ret
+}
+
+// Stats accumulates the outcome of signing attempts, so that acceptance
+// rates can be compared to the ones expected from the parameters.
+// It is an Observer and is safe for concurrent use.
+type Stats struct {
+	mu         sync.Mutex
+	attempts   uint64
+	signatures uint64
+	counts     [][NumRejections]uint64 // counts[k][r]: iteration k rejected for r
+}
+
+// ObserveAttempt records the outcome of one signing attempt.
+func (s *Stats) ObserveAttempt(reasons []Rejection) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.attempts++
+	for len(s.counts) < len(reasons) {
+		s.counts = append(s.counts, [NumRejections]uint64{})
+	}
+	for k, r := range reasons {
+		if r >= NumRejections {
+			continue
+		}
+		s.counts[k][r]++
+		if r == Accepted {
+			s.signatures++
+		}
+	}
+}
+
+// Attempts returns the number of signing attempts observed.
+func (s *Stats) Attempts() uint64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.attempts
+}
+
+// Signatures returns the number of signing attempts that yielded a signature.
+func (s *Stats) Signatures() uint64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.signatures
+}
+
+// AcceptanceRate returns the fraction of attempts that yielded a signature.
+func (s *Stats) AcceptanceRate() float64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.attempts == 0 {
+		return 0
+	}
+	return float64(s.signatures) / float64(s.attempts)
+}
+
+// Count returns how many times iteration k was rejected for reason r.
+func (s *Stats) Count(k int, r Rejection) uint64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if k >= len(s.counts) || r >= NumRejections {
+		return 0
+	}
+	return s.counts[k][r]
+}
+
+// Total returns how many iterations were rejected for reason r.
+func (s *Stats) Total(r Rejection) uint64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	total := uint64(0)
+	if r >= NumRejections {
+		return 0
+	}
+	for k := range s.counts {
+		total += s.counts[k][r]
+	}
+	return total
+}
+
+// IterationAcceptanceRate returns the fraction of checked iterations that
+// were accepted.
+func (s *Stats) IterationAcceptanceRate() float64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var tried, accepted uint64
+	for k := range s.counts {
+		for r, c := range s.counts[k] {
+			if Rejection(r) != NotTried {
+				tried += c
+			}
+		}
+		accepted += s.counts[k][Accepted]
+	}
+	if tried == 0 {
+		return 0
+	}
+	return float64(accepted) / float64(tried)
+}
+
+// String summarizes the statistics.
+func (s *Stats) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "attempts: %d, signatures: %d, acceptance rate: %.4f, iteration acceptance rate: %.4f",
+		s.Attempts(), s.Signatures(), s.AcceptanceRate(), s.IterationAcceptanceRate())
+	for r := Rejection(0); r < NumRejections; r++ {
+		fmt.Fprintf(&b, ", %s: %d", r, s.Total(r))
+	}
+	return b.String()
+}
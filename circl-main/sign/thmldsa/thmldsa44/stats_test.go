@@ -0,0 +1,101 @@
+package thmldsa44
+
+import (
+	"encoding/binary"
+	"testing"
+
+	common "github.com/cloudflare/circl/sign/internal/dilithium"
+)
+
+func TestRejectionReports(t *testing.T) {
+	var (
+		seed [common.SeedSize]byte
+		msg  [8]byte
+		sig  [SignatureSize]byte
+	)
+	var stats Stats
+
+	params, err := GetThresholdParams(3, 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	binary.LittleEndian.PutUint64(seed[:], 1)
+	pk, sks := NewThresholdKeysFromSeed(&seed, params)
+	act := uint8(0b1011)
+	signers := []int{0, 1, 3}
+
+	for signatures := 0; signatures < 3; {
+		st1s := make([]StRound1, len(signers))
+		msgs1 := make([][]byte, len(signers))
+		for j, i := range signers {
+			msgs1[j], st1s[j], err = Round1(&sks[i], params)
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+		st2s := make([]StRound2, len(signers))
+		msgs2 := make([][]byte, len(signers))
+		for j, i := range signers {
+			msgs2[j], st2s[j], err = Round2(&sks[i], act, msg[:], nil, msgs1, &st1s[j], params)
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+		msgs3 := make([][]byte, len(signers))
+		reports := make([][]Rejection, len(signers))
+		for j, i := range signers {
+			msgs3[j], reports[j], err = Round3WithReport(&sks[i], msgs2, &st1s[j], &st2s[j], params)
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		ok, reasons := CombineWithReport(pk, msg[:], nil, msgs2, msgs3, sig[:], params)
+		if len(reasons) != int(params.K) {
+			t.Fatal("wrong report length")
+		}
+		for _, rep := range reports {
+			for k, r := range rep {
+				if r == RejectedResponseNorm && reasons[k] != RejectedResponseNorm && reasons[k] != NotTried {
+					t.Fatalf("iteration %d rejected by a signer reported as %s", k, reasons[k])
+				}
+			}
+		}
+		merged := MergeRejections(reasons, reports...)
+		for k := range merged {
+			if merged[k] != reasons[k] {
+				t.Fatal("zero responses not detected by Combine")
+			}
+		}
+
+		accepted := 0
+		for k, r := range reasons {
+			if r == Accepted {
+				accepted++
+				for _, r2 := range reasons[k+1:] {
+					if r2 != NotTried {
+						t.Fatal("iteration checked after acceptance")
+					}
+				}
+			}
+		}
+		if ok != (accepted == 1) {
+			t.Fatal("report inconsistent with result")
+		}
+		stats.ObserveAttempt(reasons)
+		if ok {
+			signatures++
+			if !Verify(pk, msg[:], nil, sig[:]) {
+				t.Fatal("invalid signature")
+			}
+		}
+	}
+
+	if stats.Signatures() != 3 || stats.Total(Accepted) != 3 {
+		t.Fatal("wrong signature count")
+	}
+	if rate := stats.AcceptanceRate(); rate <= 0 || rate > 1 {
+		t.Fatalf("wrong acceptance rate %f", rate)
+	}
+	t.Log(&stats)
+}
@@ -24,6 +24,13 @@ const (
 	Secp256k1
 	// ECDSA is an enum for the supported ECDSA key type
 	ECDSA
+	// MLDSA44 is an enum for the supported ML-DSA-44 key type. Its value, as
+	// those of the other ML-DSA key types, is provisional (see pb.KeyType)
+	MLDSA44 = 0x300044
+	// MLDSA65 is an enum for the supported ML-DSA-65 key type
+	MLDSA65 = 0x300065
+	// MLDSA87 is an enum for the supported ML-DSA-87 key type
+	MLDSA87 = 0x300087
 )
 
 var (
@@ -35,6 +42,9 @@ var (
 		Ed25519,
 		Secp256k1,
 		ECDSA,
+		MLDSA44,
+		MLDSA65,
+		MLDSA87,
 	}
 )
 
@@ -50,6 +60,9 @@ var PubKeyUnmarshallers = map[pb.KeyType]PubKeyUnmarshaller{
 	pb.KeyType_Ed25519:   UnmarshalEd25519PublicKey,
 	pb.KeyType_Secp256k1: UnmarshalSecp256k1PublicKey,
 	pb.KeyType_ECDSA:     UnmarshalECDSAPublicKey,
+	pb.KeyType_MLDSA44:   UnmarshalMLDSA44PublicKey,
+	pb.KeyType_MLDSA65:   UnmarshalMLDSA65PublicKey,
+	pb.KeyType_MLDSA87:   UnmarshalMLDSA87PublicKey,
 }
 
 // PrivKeyUnmarshallers is a map of unmarshallers by key type
@@ -58,6 +71,9 @@ var PrivKeyUnmarshallers = map[pb.KeyType]PrivKeyUnmarshaller{
 	pb.KeyType_Ed25519:   UnmarshalEd25519PrivateKey,
 	pb.KeyType_Secp256k1: UnmarshalSecp256k1PrivateKey,
 	pb.KeyType_ECDSA:     UnmarshalECDSAPrivateKey,
+	pb.KeyType_MLDSA44:   UnmarshalMLDSA44PrivateKey,
+	pb.KeyType_MLDSA65:   UnmarshalMLDSA65PrivateKey,
+	pb.KeyType_MLDSA87:   UnmarshalMLDSA87PrivateKey,
 }
 
 // Key represents a crypto key that can be compared to another key
@@ -102,7 +118,8 @@ func GenerateKeyPair(typ, bits int) (PrivKey, PubKey, error) {
 	return GenerateKeyPairWithReader(typ, bits, rand.Reader)
 }
 
-// GenerateKeyPairWithReader returns a keypair of the given type and bit-size
+// GenerateKeyPairWithReader returns a keypair of the given type and bit-size.
+// The bit-size is ignored for the key types of fixed size.
 func GenerateKeyPairWithReader(typ, bits int, src io.Reader) (PrivKey, PubKey, error) {
 	switch typ {
 	case RSA:
@@ -113,6 +130,8 @@ func GenerateKeyPairWithReader(typ, bits int, src io.Reader) (PrivKey, PubKey, e
 		return GenerateSecp256k1Key(src)
 	case ECDSA:
 		return GenerateECDSAKeyPair(src)
+	case MLDSA44, MLDSA65, MLDSA87:
+		return GenerateMLDSAKey(pb.KeyType(typ), src)
 	default:
 		return nil, nil, ErrBadKeyType
 	}
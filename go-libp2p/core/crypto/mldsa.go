@@ -0,0 +1,310 @@
+package crypto
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/rand"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"io"
+
+	pb "github.com/libp2p/go-libp2p/core/crypto/pb"
+	"github.com/libp2p/go-libp2p/core/internal/catch"
+
+	"github.com/cloudflare/circl/sign"
+	"github.com/cloudflare/circl/sign/mldsa/mldsa44"
+	"github.com/cloudflare/circl/sign/mldsa/mldsa65"
+	"github.com/cloudflare/circl/sign/mldsa/mldsa87"
+)
+
+// MLDSAPrivateKey is an ML-DSA (FIPS 204) private key.
+type MLDSAPrivateKey struct {
+	typ pb.KeyType
+	k   sign.PrivateKey
+}
+
+// MLDSAPublicKey is an ML-DSA (FIPS 204) public key.
+type MLDSAPublicKey struct {
+	typ pb.KeyType
+	k   sign.PublicKey
+}
+
+// ErrNotMLDSAKeyType is returned when a key type is not one of the ML-DSA
+// parameter sets.
+var ErrNotMLDSAKeyType = errors.New("not an ML-DSA key type")
+
+func mldsaScheme(typ pb.KeyType) (sign.Scheme, error) {
+	switch typ {
+	case pb.KeyType_MLDSA44:
+		return mldsa44.Scheme(), nil
+	case pb.KeyType_MLDSA65:
+		return mldsa65.Scheme(), nil
+	case pb.KeyType_MLDSA87:
+		return mldsa87.Scheme(), nil
+	default:
+		return nil, ErrNotMLDSAKeyType
+	}
+}
+
+// IsMLDSAKeyType reports whether typ is one of the ML-DSA parameter sets,
+// which are believed to resist quantum computers.
+func IsMLDSAKeyType(typ pb.KeyType) bool {
+	_, err := mldsaScheme(typ)
+	return err == nil
+}
+
+// GenerateMLDSAKey generates a new ML-DSA private and public key pair of the
+// parameter set typ, one of pb.KeyType_MLDSA44, pb.KeyType_MLDSA65 and
+// pb.KeyType_MLDSA87. The key is derived from a seed read from src.
+func GenerateMLDSAKey(typ pb.KeyType, src io.Reader) (PrivKey, PubKey, error) {
+	scheme, err := mldsaScheme(typ)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	seed := make([]byte, scheme.SeedSize())
+	if _, err := io.ReadFull(src, seed); err != nil {
+		return nil, nil, err
+	}
+	pub, priv := scheme.DeriveKey(seed)
+
+	return &MLDSAPrivateKey{typ: typ, k: priv},
+		&MLDSAPublicKey{typ: typ, k: pub},
+		nil
+}
+
+// Type of the private key (one of the ML-DSA parameter sets).
+func (k *MLDSAPrivateKey) Type() pb.KeyType {
+	return k.typ
+}
+
+// Raw private key bytes, in the encoding of FIPS 204.
+func (k *MLDSAPrivateKey) Raw() ([]byte, error) {
+	return k.k.MarshalBinary()
+}
+
+// Equals compares two ML-DSA private keys.
+func (k *MLDSAPrivateKey) Equals(o Key) bool {
+	mk, ok := o.(*MLDSAPrivateKey)
+	if !ok {
+		return basicEquals(k, o)
+	}
+
+	a, err := k.Raw()
+	if err != nil {
+		return false
+	}
+	b, err := mk.Raw()
+	if err != nil {
+		return false
+	}
+	return k.typ == mk.typ && subtle.ConstantTimeCompare(a, b) == 1
+}
+
+// GetPublic returns the ML-DSA public key of a private key.
+func (k *MLDSAPrivateKey) GetPublic() PubKey {
+	return &MLDSAPublicKey{typ: k.typ, k: k.k.Public().(sign.PublicKey)}
+}
+
+// Sign returns a deterministic ML-DSA signature of msg, with an empty
+// context string.
+func (k *MLDSAPrivateKey) Sign(msg []byte) (res []byte, err error) {
+	defer func() { catch.HandlePanic(recover(), &err, "ML-DSA signing") }()
+
+	return k.k.Scheme().Sign(k.k, msg, nil), nil
+}
+
+// Type of the public key (one of the ML-DSA parameter sets).
+func (k *MLDSAPublicKey) Type() pb.KeyType {
+	return k.typ
+}
+
+// Raw public key bytes, in the encoding of FIPS 204.
+func (k *MLDSAPublicKey) Raw() ([]byte, error) {
+	return k.k.MarshalBinary()
+}
+
+// Equals compares two ML-DSA public keys.
+func (k *MLDSAPublicKey) Equals(o Key) bool {
+	mk, ok := o.(*MLDSAPublicKey)
+	if !ok {
+		return basicEquals(k, o)
+	}
+
+	a, err := k.Raw()
+	if err != nil {
+		return false
+	}
+	b, err := mk.Raw()
+	if err != nil {
+		return false
+	}
+	return k.typ == mk.typ && bytes.Equal(a, b)
+}
+
+// Verify checks an ML-DSA signature with an empty context string against the
+// input data.
+func (k *MLDSAPublicKey) Verify(data []byte, sig []byte) (success bool, err error) {
+	defer func() {
+		catch.HandlePanic(recover(), &err, "ML-DSA signature verification")
+
+		// To be safe.
+		if err != nil {
+			success = false
+		}
+	}()
+	return k.k.Scheme().Verify(k.k, data, sig, nil), nil
+}
+
+func unmarshalMLDSAPublicKey(typ pb.KeyType, data []byte) (PubKey, error) {
+	scheme, err := mldsaScheme(typ)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) != scheme.PublicKeySize() {
+		return nil, fmt.Errorf("expect %s public key data size to be %d", scheme.Name(), scheme.PublicKeySize())
+	}
+
+	pub, err := scheme.UnmarshalBinaryPublicKey(data)
+	if err != nil {
+		return nil, err
+	}
+	return &MLDSAPublicKey{typ: typ, k: pub}, nil
+}
+
+func unmarshalMLDSAPrivateKey(typ pb.KeyType, data []byte) (PrivKey, error) {
+	scheme, err := mldsaScheme(typ)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) != scheme.PrivateKeySize() {
+		return nil, fmt.Errorf("expect %s private key data size to be %d", scheme.Name(), scheme.PrivateKeySize())
+	}
+
+	priv, err := scheme.UnmarshalBinaryPrivateKey(data)
+	if err != nil {
+		return nil, err
+	}
+	return &MLDSAPrivateKey{typ: typ, k: priv}, nil
+}
+
+// UnmarshalMLDSA44PublicKey returns an ML-DSA-44 public key from input bytes.
+// The combined public key of a threshold ML-DSA-44 committee has the same
+// encoding.
+func UnmarshalMLDSA44PublicKey(data []byte) (PubKey, error) {
+	return unmarshalMLDSAPublicKey(pb.KeyType_MLDSA44, data)
+}
+
+// UnmarshalMLDSA65PublicKey returns an ML-DSA-65 public key from input bytes.
+func UnmarshalMLDSA65PublicKey(data []byte) (PubKey, error) {
+	return unmarshalMLDSAPublicKey(pb.KeyType_MLDSA65, data)
+}
+
+// UnmarshalMLDSA87PublicKey returns an ML-DSA-87 public key from input bytes.
+func UnmarshalMLDSA87PublicKey(data []byte) (PubKey, error) {
+	return unmarshalMLDSAPublicKey(pb.KeyType_MLDSA87, data)
+}
+
+// UnmarshalMLDSA44PrivateKey returns an ML-DSA-44 private key from input bytes.
+func UnmarshalMLDSA44PrivateKey(data []byte) (PrivKey, error) {
+	return unmarshalMLDSAPrivateKey(pb.KeyType_MLDSA44, data)
+}
+
+// UnmarshalMLDSA65PrivateKey returns an ML-DSA-65 private key from input bytes.
+func UnmarshalMLDSA65PrivateKey(data []byte) (PrivKey, error) {
+	return unmarshalMLDSAPrivateKey(pb.KeyType_MLDSA65, data)
+}
+
+// UnmarshalMLDSA87PrivateKey returns an ML-DSA-87 private key from input bytes.
+func UnmarshalMLDSA87PrivateKey(data []byte) (PrivKey, error) {
+	return unmarshalMLDSAPrivateKey(pb.KeyType_MLDSA87, data)
+}
+
+// ThresholdSigner produces ML-DSA-44 signatures, with an empty context
+// string, under the combined public key of a threshold ML-DSA-44 committee,
+// for instance by running a signing session with T of its members.
+type ThresholdSigner interface {
+	ThresholdSign(msg []byte) ([]byte, error)
+}
+
+// ThresholdSignerFunc adapts a function to the ThresholdSigner interface.
+type ThresholdSignerFunc func(msg []byte) ([]byte, error)
+
+// ThresholdSign calls f(msg).
+func (f ThresholdSignerFunc) ThresholdSign(msg []byte) ([]byte, error) {
+	return f(msg)
+}
+
+// ErrThresholdKeyRaw is returned when marshalling a threshold private key,
+// which is split among the members of the committee.
+var ErrThresholdKeyRaw = errors.New("threshold private key cannot be marshalled")
+
+// ThresholdMLDSA44PrivateKey is the private key of a threshold ML-DSA-44
+// committee, signing through a ThresholdSigner. As threshold signatures are
+// plain ML-DSA-44 signatures, its public key is an ML-DSA-44 public key and
+// the committee can act as a single peer identity.
+type ThresholdMLDSA44PrivateKey struct {
+	pub    *MLDSAPublicKey
+	signer ThresholdSigner
+}
+
+// NewThresholdMLDSA44PrivateKey returns the private key of the committee with
+// the given ML-DSA-44 combined public key, signing with signer.
+func NewThresholdMLDSA44PrivateKey(pub PubKey, signer ThresholdSigner) (PrivKey, error) {
+	mpub, ok := pub.(*MLDSAPublicKey)
+	if !ok || mpub.typ != pb.KeyType_MLDSA44 {
+		return nil, ErrNotMLDSAKeyType
+	}
+	return &ThresholdMLDSA44PrivateKey{pub: mpub, signer: signer}, nil
+}
+
+// Type of the private key (ML-DSA-44).
+func (k *ThresholdMLDSA44PrivateKey) Type() pb.KeyType {
+	return pb.KeyType_MLDSA44
+}
+
+// Raw always fails, as the key is split among the members of the committee.
+func (k *ThresholdMLDSA44PrivateKey) Raw() ([]byte, error) {
+	return nil, ErrThresholdKeyRaw
+}
+
+// Equals only holds for the same key, as signers cannot be compared.
+func (k *ThresholdMLDSA44PrivateKey) Equals(o Key) bool {
+	tk, ok := o.(*ThresholdMLDSA44PrivateKey)
+	return ok && k == tk
+}
+
+// GetPublic returns the combined public key of the committee.
+func (k *ThresholdMLDSA44PrivateKey) GetPublic() PubKey {
+	return k.pub
+}
+
+// Sign returns the signature of msg produced by the committee, after
+// checking it against the combined public key.
+func (k *ThresholdMLDSA44PrivateKey) Sign(msg []byte) ([]byte, error) {
+	sig, err := k.signer.ThresholdSign(msg)
+	if err != nil {
+		return nil, err
+	}
+	if ok, err := k.pub.Verify(msg, sig); err != nil || !ok {
+		return nil, errors.New("threshold signer returned an invalid signature")
+	}
+	return sig, nil
+}
+
+// ThresholdMLDSA44KeyFromSigner returns the private key of a threshold
+// ML-DSA-44 committee exposed through a standard library crypto.Signer, such
+// as the client of a signing service. The public key of s must be an
+// *mldsa44.PublicKey, and s must sign messages with an empty context string
+// when opts.HashFunc() is zero, as *mldsa44.PrivateKey does.
+func ThresholdMLDSA44KeyFromSigner(s crypto.Signer) (PrivKey, error) {
+	pk, ok := s.Public().(*mldsa44.PublicKey)
+	if !ok {
+		return nil, ErrNotMLDSAKeyType
+	}
+	pub := &MLDSAPublicKey{typ: pb.KeyType_MLDSA44, k: pk}
+	return NewThresholdMLDSA44PrivateKey(pub, ThresholdSignerFunc(func(msg []byte) ([]byte, error) {
+		return s.Sign(rand.Reader, msg, crypto.Hash(0))
+	}))
+}
@@ -0,0 +1,243 @@
+package crypto_test
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
+	"errors"
+	"testing"
+
+	. "github.com/libp2p/go-libp2p/core/crypto"
+	pb "github.com/libp2p/go-libp2p/core/crypto/pb"
+	"github.com/libp2p/go-libp2p/core/peer"
+
+	"github.com/cloudflare/circl/sign/mldsa/mldsa44"
+	"github.com/cloudflare/circl/sign/thmldsa/thmldsa44"
+)
+
+func TestMLDSAKeyTypes(t *testing.T) {
+	for typ, want := range map[int]pb.KeyType{MLDSA44: pb.KeyType_MLDSA44, MLDSA65: pb.KeyType_MLDSA65, MLDSA87: pb.KeyType_MLDSA87} {
+		if typ != int(want) {
+			t.Fatalf("key type %d is %s = %d in the protobuf", typ, want, want)
+		}
+	}
+}
+
+func TestMLDSAKeys(t *testing.T) {
+	for _, typ := range []pb.KeyType{pb.KeyType_MLDSA44, pb.KeyType_MLDSA65, pb.KeyType_MLDSA87} {
+		t.Run(typ.String(), func(t *testing.T) {
+			priv, pub, err := GenerateMLDSAKey(typ, rand.Reader)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if priv.Type() != typ || pub.Type() != typ {
+				t.Fatal("wrong key type")
+			}
+			if !priv.GetPublic().Equals(pub) {
+				t.Fatal("public key of the private key differs")
+			}
+
+			msg := []byte("hello! and welcome to some awesome crypto primitives")
+			sig, err := priv.Sign(msg)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if ok, err := pub.Verify(msg, sig); err != nil || !ok {
+				t.Fatal("signature didn't match")
+			}
+			msg[0] = ^msg[0]
+			if ok, err := pub.Verify(msg, sig); err != nil || ok {
+				t.Fatal("signature matched and shouldn't")
+			}
+			if ok, err := pub.Verify(msg, sig[:10]); err != nil || ok {
+				t.Fatal("truncated signature matched")
+			}
+
+			// Keys of other parameter sets are rejected
+			raw, err := pub.Raw()
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, other := range []pb.KeyType{pb.KeyType_MLDSA44, pb.KeyType_MLDSA65, pb.KeyType_MLDSA87} {
+				if other != typ {
+					if _, err := PubKeyUnmarshallers[other](raw); err == nil {
+						t.Fatalf("%s key unmarshalled as %s", typ, other)
+					}
+				}
+			}
+
+			id, err := peer.IDFromPublicKey(pub)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !id.MatchesPrivateKey(priv) {
+				t.Fatal("peer ID doesn't match the private key")
+			}
+		})
+	}
+}
+
+func TestMLDSAKeyFromSeed(t *testing.T) {
+	seed := bytes.Repeat([]byte{7}, 32)
+	priv1, _, err := GenerateMLDSAKey(pb.KeyType_MLDSA65, bytes.NewReader(seed))
+	if err != nil {
+		t.Fatal(err)
+	}
+	priv2, _, err := GenerateMLDSAKey(pb.KeyType_MLDSA65, bytes.NewReader(seed))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !priv1.Equals(priv2) {
+		t.Fatal("keys derived from the same seed differ")
+	}
+
+	if _, _, err := GenerateMLDSAKey(pb.KeyType_Ed25519, rand.Reader); err != ErrNotMLDSAKeyType {
+		t.Fatalf("got %v instead of ErrNotMLDSAKeyType", err)
+	}
+}
+
+// localSigner runs every round of a threshold signing session in process.
+func localSigner(pk *thmldsa44.PublicKey, sks []thmldsa44.PrivateKey, params *thmldsa44.ThresholdParams) ThresholdSignerFunc {
+	return func(msg []byte) ([]byte, error) {
+		act := uint8(1<<params.T - 1)
+		sig := make([]byte, thmldsa44.SignatureSize)
+		for attempt := 0; attempt < 570; attempt++ {
+			sts1 := make([]thmldsa44.StRound1, params.T)
+			msgs1 := make([][]byte, params.T)
+			for i := range msgs1 {
+				var err error
+				if msgs1[i], sts1[i], err = thmldsa44.Round1(&sks[i], params); err != nil {
+					return nil, err
+				}
+			}
+			sts2 := make([]thmldsa44.StRound2, params.T)
+			msgs2 := make([][]byte, params.T)
+			for i := range msgs2 {
+				var err error
+				if msgs2[i], sts2[i], err = thmldsa44.Round2(&sks[i], act, msg, nil, msgs1, &sts1[i], params); err != nil {
+					return nil, err
+				}
+			}
+			msgs3 := make([][]byte, params.T)
+			for i := range msgs3 {
+				var err error
+				if msgs3[i], err = thmldsa44.Round3(&sks[i], msgs2, &sts1[i], &sts2[i], params); err != nil {
+					return nil, err
+				}
+			}
+			if thmldsa44.Combine(pk, msg, nil, msgs2, msgs3, sig, params) {
+				return sig, nil
+			}
+		}
+		return nil, errors.New("too many attempts")
+	}
+}
+
+func TestThresholdMLDSA44Key(t *testing.T) {
+	params, err := thmldsa44.GetThresholdParams(2, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var seed [32]byte
+	tpk, sks := thmldsa44.NewThresholdKeysFromSeed(&seed, params)
+
+	pub, err := UnmarshalMLDSA44PublicKey(tpk.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	priv, err := NewThresholdMLDSA44PrivateKey(pub, localSigner(tpk, sks, params))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !priv.Equals(priv) {
+		t.Fatal("threshold key doesn't equal itself")
+	}
+	if _, err := MarshalPrivateKey(priv); err != ErrThresholdKeyRaw {
+		t.Fatalf("got %v instead of ErrThresholdKeyRaw", err)
+	}
+
+	msg := []byte("signed by the committee")
+	sig, err := priv.Sign(msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Anyone can verify the signature with the marshalled public key
+	pubBytes, err := MarshalPublicKey(priv.GetPublic())
+	if err != nil {
+		t.Fatal(err)
+	}
+	pub2, err := UnmarshalPublicKey(pubBytes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ok, err := pub2.Verify(msg, sig); err != nil || !ok {
+		t.Fatal("threshold signature didn't match")
+	}
+
+	id, err := peer.IDFromPrivateKey(priv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !id.MatchesPublicKey(pub2) {
+		t.Fatal("peer ID of the committee doesn't match its public key")
+	}
+
+	// A signer returning garbage is caught
+	bad, err := NewThresholdMLDSA44PrivateKey(pub, ThresholdSignerFunc(func(msg []byte) ([]byte, error) {
+		return make([]byte, thmldsa44.SignatureSize), nil
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := bad.Sign(msg); err == nil {
+		t.Fatal("invalid threshold signature accepted")
+	}
+
+	_, edPub, err := GenerateEd25519Key(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewThresholdMLDSA44PrivateKey(edPub, localSigner(tpk, sks, params)); err != ErrNotMLDSAKeyType {
+		t.Fatalf("got %v instead of ErrNotMLDSAKeyType", err)
+	}
+}
+
+func TestThresholdMLDSA44KeyFromSigner(t *testing.T) {
+	pk, sk, err := mldsa44.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	priv, err := ThresholdMLDSA44KeyFromSigner(sk)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pub, err := UnmarshalMLDSA44PublicKey(pk.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !priv.GetPublic().Equals(pub) {
+		t.Fatal("public key of the signer differs")
+	}
+
+	msg := []byte("signed through crypto.Signer")
+	sig, err := priv.Sign(msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ok, err := pub.Verify(msg, sig); err != nil || !ok {
+		t.Fatal("signature didn't match")
+	}
+
+	edPriv, _, err := GenerateEd25519Key(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdPriv, err := PrivKeyToStdKey(edPriv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ThresholdMLDSA44KeyFromSigner(stdPriv.(*ed25519.PrivateKey)); err != ErrNotMLDSAKeyType {
+		t.Fatalf("got %v instead of ErrNotMLDSAKeyType", err)
+	}
+}
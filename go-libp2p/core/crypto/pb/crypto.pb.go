@@ -28,21 +28,34 @@ const (
 	KeyType_Ed25519   KeyType = 1
 	KeyType_Secp256k1 KeyType = 2
 	KeyType_ECDSA     KeyType = 3
+	// The ML-DSA key types are provisional and local to this fork: libp2p
+	// assigns them no value yet. They are taken from the range the multicodec
+	// table reserves for private use, 0x300000 to 0x3fffff, far from the
+	// values assigned upstream, and will change if libp2p assigns others.
+	KeyType_MLDSA44 KeyType = 3145796
+	KeyType_MLDSA65 KeyType = 3145829
+	KeyType_MLDSA87 KeyType = 3145863
 )
 
 // Enum value maps for KeyType.
 var (
 	KeyType_name = map[int32]string{
-		0: "RSA",
-		1: "Ed25519",
-		2: "Secp256k1",
-		3: "ECDSA",
+		0:       "RSA",
+		1:       "Ed25519",
+		2:       "Secp256k1",
+		3:       "ECDSA",
+		3145796: "MLDSA44",
+		3145829: "MLDSA65",
+		3145863: "MLDSA87",
 	}
 	KeyType_value = map[string]int32{
 		"RSA":       0,
 		"Ed25519":   1,
 		"Secp256k1": 2,
 		"ECDSA":     3,
+		"MLDSA44":   3145796,
+		"MLDSA65":   3145829,
+		"MLDSA87":   3145863,
 	}
 )
 
@@ -201,11 +214,14 @@ var file_core_crypto_pb_crypto_proto_rawDesc = string([]byte{
 	0x26, 0x0a, 0x04, 0x54, 0x79, 0x70, 0x65, 0x18, 0x01, 0x20, 0x02, 0x28, 0x0e, 0x32, 0x12, 0x2e,
 	0x63, 0x72, 0x79, 0x70, 0x74, 0x6f, 0x2e, 0x70, 0x62, 0x2e, 0x4b, 0x65, 0x79, 0x54, 0x79, 0x70,
 	0x65, 0x52, 0x04, 0x54, 0x79, 0x70, 0x65, 0x12, 0x12, 0x0a, 0x04, 0x44, 0x61, 0x74, 0x61, 0x18,
-	0x02, 0x20, 0x02, 0x28, 0x0c, 0x52, 0x04, 0x44, 0x61, 0x74, 0x61, 0x2a, 0x39, 0x0a, 0x07, 0x4b,
+	0x02, 0x20, 0x02, 0x28, 0x0c, 0x52, 0x04, 0x44, 0x61, 0x74, 0x61, 0x2a, 0x69, 0x0a, 0x07, 0x4b,
 	0x65, 0x79, 0x54, 0x79, 0x70, 0x65, 0x12, 0x07, 0x0a, 0x03, 0x52, 0x53, 0x41, 0x10, 0x00, 0x12,
 	0x0b, 0x0a, 0x07, 0x45, 0x64, 0x32, 0x35, 0x35, 0x31, 0x39, 0x10, 0x01, 0x12, 0x0d, 0x0a, 0x09,
 	0x53, 0x65, 0x63, 0x70, 0x32, 0x35, 0x36, 0x6b, 0x31, 0x10, 0x02, 0x12, 0x09, 0x0a, 0x05, 0x45,
-	0x43, 0x44, 0x53, 0x41, 0x10, 0x03, 0x42, 0x2c, 0x5a, 0x2a, 0x67, 0x69, 0x74, 0x68, 0x75, 0x62,
+	0x43, 0x44, 0x53, 0x41, 0x10, 0x03, 0x12, 0x0e, 0x0a, 0x07, 0x4d, 0x4c, 0x44, 0x53, 0x41, 0x34,
+	0x34, 0x10, 0xc4, 0x80, 0xc0, 0x01, 0x12, 0x0e, 0x0a, 0x07, 0x4d, 0x4c, 0x44, 0x53, 0x41, 0x36,
+	0x35, 0x10, 0xe5, 0x80, 0xc0, 0x01, 0x12, 0x0e, 0x0a, 0x07, 0x4d, 0x4c, 0x44, 0x53, 0x41, 0x38,
+	0x37, 0x10, 0x87, 0x81, 0xc0, 0x01, 0x42, 0x2c, 0x5a, 0x2a, 0x67, 0x69, 0x74, 0x68, 0x75, 0x62,
 	0x2e, 0x63, 0x6f, 0x6d, 0x2f, 0x6c, 0x69, 0x62, 0x70, 0x32, 0x70, 0x2f, 0x67, 0x6f, 0x2d, 0x6c,
 	0x69, 0x62, 0x70, 0x32, 0x70, 0x2f, 0x63, 0x6f, 0x72, 0x65, 0x2f, 0x63, 0x72, 0x79, 0x70, 0x74,
 	0x6f, 0x2f, 0x70, 0x62,
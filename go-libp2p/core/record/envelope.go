@@ -45,44 +45,96 @@ type Envelope struct {
 var ErrEmptyDomain = errors.New("envelope domain must not be empty")
 var ErrEmptyPayloadType = errors.New("payloadType must not be empty")
 var ErrInvalidSignature = errors.New("invalid signature or incorrect domain")
+var ErrUnexpectedSigner = errors.New("envelope signed with an unexpected key")
 
 // Seal marshals the given Record, places the marshaled bytes inside an Envelope,
 // and signs with the given private key.
 func Seal(rec Record, privateKey crypto.PrivKey) (*Envelope, error) {
-	payload, err := rec.MarshalRecord()
+	payload, payloadType, unsigned, err := prepareSeal(rec)
 	if err != nil {
-		return nil, fmt.Errorf("error marshaling record: %v", err)
+		return nil, err
 	}
+	defer pool.Put(unsigned)
 
-	domain := rec.Domain()
-	payloadType := rec.Codec()
-	if domain == "" {
-		return nil, ErrEmptyDomain
+	sig, err := privateKey.Sign(unsigned)
+	if err != nil {
+		return nil, err
 	}
 
-	if len(payloadType) == 0 {
-		return nil, ErrEmptyPayloadType
-	}
+	return &Envelope{
+		PublicKey:   privateKey.GetPublic(),
+		PayloadType: payloadType,
+		RawPayload:  payload,
+		signature:   sig,
+	}, nil
+}
 
-	unsigned, err := makeUnsigned(domain, payloadType, payload)
+// SigningPayload returns the bytes signed when sealing the given Record in an
+// Envelope. It lets a key that is not held by a single crypto.PrivKey, such as
+// the key of a threshold ML-DSA committee, sign them on its own before calling
+// SealWithSignature.
+func SigningPayload(rec Record) ([]byte, error) {
+	_, _, unsigned, err := prepareSeal(rec)
 	if err != nil {
 		return nil, err
 	}
 	defer pool.Put(unsigned)
 
-	sig, err := privateKey.Sign(unsigned)
+	return append([]byte(nil), unsigned...), nil
+}
+
+// SealWithSignature marshals the given Record and places the marshaled bytes
+// inside an Envelope, along with sig, the signature of SigningPayload(rec)
+// under publicKey. It returns ErrInvalidSignature if sig does not verify.
+func SealWithSignature(rec Record, publicKey crypto.PubKey, sig []byte) (*Envelope, error) {
+	payload, payloadType, unsigned, err := prepareSeal(rec)
 	if err != nil {
 		return nil, err
 	}
+	defer pool.Put(unsigned)
+
+	valid, err := publicKey.Verify(unsigned, sig)
+	if err != nil {
+		return nil, fmt.Errorf("failed while verifying signature: %w", err)
+	}
+	if !valid {
+		return nil, ErrInvalidSignature
+	}
 
 	return &Envelope{
-		PublicKey:   privateKey.GetPublic(),
+		PublicKey:   publicKey,
 		PayloadType: payloadType,
 		RawPayload:  payload,
 		signature:   sig,
 	}, nil
 }
 
+// prepareSeal marshals the given Record and returns its payload, its payload
+// type and the buffer to sign, which comes from a pool. The caller MUST
+// return this buffer to the pool.
+func prepareSeal(rec Record) (payload, payloadType, unsigned []byte, err error) {
+	payload, err = rec.MarshalRecord()
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("error marshaling record: %v", err)
+	}
+
+	domain := rec.Domain()
+	payloadType = rec.Codec()
+	if domain == "" {
+		return nil, nil, nil, ErrEmptyDomain
+	}
+
+	if len(payloadType) == 0 {
+		return nil, nil, nil, ErrEmptyPayloadType
+	}
+
+	unsigned, err = makeUnsigned(domain, payloadType, payload)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	return payload, payloadType, unsigned, nil
+}
+
 // ConsumeEnvelope unmarshals a serialized Envelope and validates its
 // signature using the provided 'domain' string. If validation fails, an error
 // is returned, along with the unmarshalled envelope, so it can be inspected.
@@ -125,6 +177,21 @@ func ConsumeEnvelope(data []byte, domain string) (envelope *Envelope, rec Record
 	return e, rec, nil
 }
 
+// ConsumeEnvelopeSignedBy is like ConsumeEnvelope, but also requires the
+// Envelope to be signed with the given public key, for instance the combined
+// public key of a threshold ML-DSA committee. Otherwise, it returns
+// ErrUnexpectedSigner.
+func ConsumeEnvelopeSignedBy(data []byte, domain string, publicKey crypto.PubKey) (envelope *Envelope, rec Record, err error) {
+	e, rec, err := ConsumeEnvelope(data, domain)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !e.PublicKey.Equals(publicKey) {
+		return nil, nil, ErrUnexpectedSigner
+	}
+	return e, rec, nil
+}
+
 // ConsumeTypedEnvelope unmarshals a serialized Envelope and validates its
 // signature. If validation fails, an error is returned, along with the unmarshalled
 // envelope, so it can be inspected.
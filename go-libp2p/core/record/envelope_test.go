@@ -10,6 +10,7 @@ import (
 	"github.com/libp2p/go-libp2p/core/record/pb"
 	"github.com/libp2p/go-libp2p/core/test"
 
+	"github.com/cloudflare/circl/sign/thmldsa/thmldsa44"
 	"google.golang.org/protobuf/proto"
 )
 
@@ -89,6 +90,132 @@ func TestEnvelopeHappyPath(t *testing.T) {
 	}
 }
 
+// Sign the payload separately, as a threshold committee does, then seal it
+func TestSealWithSignature(t *testing.T) {
+	var (
+		rec            = &simpleRecord{message: "hello world!"}
+		priv, pub, err = test.RandTestKeyPair(crypto.MLDSA44, 0)
+	)
+	test.AssertNilError(t, err)
+
+	unsigned, err := SigningPayload(rec)
+	test.AssertNilError(t, err)
+	sig, err := priv.Sign(unsigned)
+	test.AssertNilError(t, err)
+
+	envelope, err := SealWithSignature(rec, pub, sig)
+	test.AssertNilError(t, err)
+
+	// ML-DSA signatures are deterministic
+	sealed, err := Seal(rec, priv)
+	test.AssertNilError(t, err)
+	if !envelope.Equal(sealed) {
+		t.Error("envelope differs from the one sealed with the private key")
+	}
+
+	serialized, err := envelope.Marshal()
+	test.AssertNilError(t, err)
+
+	RegisterType(&simpleRecord{})
+	_, rec2, err := ConsumeEnvelopeSignedBy(serialized, rec.Domain(), pub)
+	test.AssertNilError(t, err)
+	if rec2.(*simpleRecord).message != "hello world!" {
+		t.Error("unexpected alteration of record")
+	}
+
+	_, otherPub, err := test.RandTestKeyPair(crypto.MLDSA44, 0)
+	test.AssertNilError(t, err)
+	_, _, err = ConsumeEnvelopeSignedBy(serialized, rec.Domain(), otherPub)
+	test.ExpectError(t, err, "ConsumeEnvelopeSignedBy should fail for another public key")
+	if !errors.Is(err, ErrUnexpectedSigner) {
+		t.Errorf("got %v instead of ErrUnexpectedSigner", err)
+	}
+
+	_, err = SealWithSignature(rec, otherPub, sig)
+	if !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("got %v instead of ErrInvalidSignature", err)
+	}
+
+	_, err = SigningPayload(&simpleRecord{testDomain: new(string)})
+	if !errors.Is(err, ErrEmptyDomain) {
+		t.Errorf("got %v instead of ErrEmptyDomain", err)
+	}
+}
+
+// thresholdKey returns the key of a 2-of-3 ML-DSA-44 committee, signing by
+// running the rounds of the first two parties in process, and the committee
+// public key.
+func thresholdKey(t *testing.T) (crypto.PrivKey, crypto.PubKey) {
+	t.Helper()
+	params, err := thmldsa44.GetThresholdParams(2, 3)
+	test.AssertNilError(t, err)
+	var seed [32]byte
+	tpk, sks := thmldsa44.NewThresholdKeysFromSeed(&seed, params)
+	pub, err := crypto.UnmarshalMLDSA44PublicKey(tpk.Bytes())
+	test.AssertNilError(t, err)
+
+	priv, err := crypto.NewThresholdMLDSA44PrivateKey(pub, crypto.ThresholdSignerFunc(func(msg []byte) ([]byte, error) {
+		act := uint8(1<<params.T - 1)
+		sig := make([]byte, thmldsa44.SignatureSize)
+		for attempt := 0; attempt < 570; attempt++ {
+			sts1 := make([]thmldsa44.StRound1, params.T)
+			msgs1 := make([][]byte, params.T)
+			for i := range msgs1 {
+				if msgs1[i], sts1[i], err = thmldsa44.Round1(&sks[i], params); err != nil {
+					return nil, err
+				}
+			}
+			sts2 := make([]thmldsa44.StRound2, params.T)
+			msgs2 := make([][]byte, params.T)
+			for i := range msgs2 {
+				if msgs2[i], sts2[i], err = thmldsa44.Round2(&sks[i], act, msg, nil, msgs1, &sts1[i], params); err != nil {
+					return nil, err
+				}
+			}
+			msgs3 := make([][]byte, params.T)
+			for i := range msgs3 {
+				if msgs3[i], err = thmldsa44.Round3(&sks[i], msgs2, &sts1[i], &sts2[i], params); err != nil {
+					return nil, err
+				}
+			}
+			if thmldsa44.Combine(tpk, msg, nil, msgs2, msgs3, sig, params) {
+				return sig, nil
+			}
+		}
+		return nil, errors.New("too many attempts")
+	}))
+	test.AssertNilError(t, err)
+	return priv, pub
+}
+
+func TestSealWithThresholdKey(t *testing.T) {
+	var (
+		rec       = &simpleRecord{message: "hello world!"}
+		priv, pub = thresholdKey(t)
+	)
+
+	envelope, err := Seal(rec, priv)
+	test.AssertNilError(t, err)
+	serialized, err := envelope.Marshal()
+	test.AssertNilError(t, err)
+
+	RegisterType(&simpleRecord{})
+	_, rec2, err := ConsumeEnvelopeSignedBy(serialized, rec.Domain(), pub)
+	test.AssertNilError(t, err)
+	if rec2.(*simpleRecord).message != "hello world!" {
+		t.Error("unexpected alteration of record")
+	}
+
+	tampered := alterMessageAndMarshal(t, envelope, func(msg *pb.Envelope) {
+		msg.Payload = []byte("totally legit, trust me")
+	})
+	_, _, err = ConsumeEnvelopeSignedBy(tampered, rec.Domain(), pub)
+	test.ExpectError(t, err, "should not be able to open envelope with modified payload")
+	if errors.Is(err, ErrUnexpectedSigner) {
+		t.Errorf("got %v instead of a signature failure", err)
+	}
+}
+
 func TestConsumeTypedEnvelope(t *testing.T) {
 	var (
 		rec          = simpleRecord{message: "hello world!"}
@@ -22,7 +22,8 @@
  * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
  * THE SOFTWARE.
  *
- * This program demonstrate a simple chat application using p2p communication.
+ * This program demonstrates threshold ML-DSA signing among a committee of peers
+ * using p2p communication.
  *
  */
 package main
@@ -30,308 +31,54 @@ package main
 import (
 	"bufio"
 	"context"
+	"crypto/ed25519"
 	"crypto/rand"
 	"encoding/binary"
 	"flag"
 	"fmt"
 	"io"
 	"log"
+	"math/bits"
 	mrand "math/rand"
 	"os"
-	"sync"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/libp2p/go-libp2p"
 	"github.com/libp2p/go-libp2p/core/crypto"
 	"github.com/libp2p/go-libp2p/core/host"
-	"github.com/libp2p/go-libp2p/core/network"
 	"github.com/libp2p/go-libp2p/core/peer"
-	"github.com/libp2p/go-libp2p/core/peerstore"
 
 	"github.com/cloudflare/circl/sign/thmldsa/thmldsa44"
+	"github.com/libp2p/go-libp2p/examples/thmldsa"
 	"github.com/multiformats/go-multiaddr"
 )
 
-type ProtocolState struct {
-	sync.Mutex
-	N      int
-	ID     int
-	Params *thmldsa44.ThresholdParams
-	pk     *thmldsa44.PublicKey
-	SKs    []thmldsa44.PrivateKey
-
-	Msgs1 [][]byte
-	Strd1 thmldsa44.StRound1
-
-	Msgs2 [][]byte
-	Strd2 thmldsa44.StRound2
-
-	Msgs3 [][]byte
-
-	CompletedR1 int
-	CompletedR2 int
-
-	done bool
-
-	PingSentTime time.Time
-}
-
-func handleStream(rw *bufio.ReadWriter, state *ProtocolState, signature *[]byte, done chan struct{}) {
-	go readData(rw, state, signature, done)
-	go runProtocolLoop(rw, state)
-}
-
-func makeStreamHandler(state *ProtocolState, signature *[]byte, done chan struct{}) network.StreamHandler {
-	return func(s network.Stream) {
-		log.Println("Got a new stream!")
-
-		rw := bufio.NewReadWriter(bufio.NewReader(s), bufio.NewWriter(s))
-		handleStream(rw, state, signature, done)
-	}
-}
-
-func runCombine(state *ProtocolState, msg, ctx []byte, pk *thmldsa44.PublicKey, sigOut *[]byte, done chan struct{}) {
-	var sig [thmldsa44.SignatureSize]byte
-
-	start := time.Now()
-
-	ok := thmldsa44.Combine(
-		pk,
-		msg,
-		ctx,
-		state.Msgs2,
-		state.Msgs3,
-		sig[:],
-		state.Params,
-	)
-
-	combineDur := time.Since(start)
-	log.Printf("[TIME] COMBINATION  %s for 2 parties out of 5", combineDur)
-
-	if !ok {
-		return
-	}
-
-	*sigOut = make([]byte, thmldsa44.SignatureSize)
-	copy(*sigOut, sig[:])
-
-	log.Printf("Combine success! Signature: %x", *sigOut)
-
-	done <- struct{}{}
-}
-
-func readData(rw *bufio.ReadWriter, state *ProtocolState, signature *[]byte, done chan struct{}) {
-	for {
-		roundTag, err := rw.ReadString('\n')
-		if err != nil {
-			log.Println("Failed to read round tag:", err)
-			return
-		}
-
-		lenStr, err := rw.ReadString('\n')
-		if err != nil {
-			log.Println("Failed to read message length:", err)
-			return
-		}
-
-		if lenStr != "\n" {
-			fmt.Printf("\x1b[32m%s\x1b[0m> ", lenStr)
-		}
-
-		var msgLen int
-		fmt.Sscanf(lenStr, "%d\n", &msgLen)
-
-		msg := make([]byte, msgLen)
-		_, err = io.ReadFull(rw, msg)
-		if err != nil {
-			log.Println("Failed to read message content:", err)
-			return
-		}
-
-		otherID := 1 - state.ID
-		state.Lock()
-
-		switch roundTag {
-		case "R1\n":
-			state.Msgs1[otherID] = msg
-			state.CompletedR1++
-			log.Printf("Received Round1 (%d/%d)", state.CompletedR1, state.N-1)
-		case "R2\n":
-			state.Msgs2[otherID] = msg
-			state.CompletedR2++
-			log.Printf("Received Round2 (%d/%d)", state.CompletedR2, state.N-1)
-		case "R3\n":
-			state.Msgs3[otherID] = msg
-			log.Printf("Received Round3 (%d/%d)", len(state.Msgs3), state.N-1)
-			if state.done == true {
-				go runCombine(state, []byte("the message"), []byte(""), state.pk, signature, done)
-			}
-		case "PING\n":
-			rw.WriteString("PONG\n")
-			rw.WriteString("0\n")
-			rw.Flush()
-		case "PONG\n":
-			rtt := time.Since(state.PingSentTime)
-			log.Printf("[LAN LATENCY] Round-trip time: %s", rtt)
-		}
-
-		state.Unlock()
-	}
-}
-
-func runProtocolLoop(rw *bufio.ReadWriter, state *ProtocolState) {
-	// Measure LAN latency once before starting rounds
-	if state.ID == 0 {
-		state.PingSentTime = time.Now()
-		rw.WriteString("PING\n")
-		rw.WriteString("0\n")
-		rw.Flush()
-		log.Println("Sent PING for LAN latency test")
-	}
-
-	// Accumulators per attempt
-	signRound1TotalDur := make([]time.Duration, 2)
-	signRound2TotalDur := make([]time.Duration, 2)
-	signRound3TotalDur := make([]time.Duration, 2)
-	networkRound1Dur := make([]time.Duration, 2)
-	networkRound2Dur := make([]time.Duration, 2)
-	networkRound3Dur := make([]time.Duration, 2)
-
-	attemptCount := uint64(0)
-
-	for attempt := 0; attempt < 570; attempt++ {
-		attemptCount++
-		log.Printf("Starting attempt %d", attempt)
-		log.Printf("Peer ID %d", state.ID)
-
-		start := time.Now()
-		msg1, stdr1, err := thmldsa44.Round1(&state.SKs[state.ID], state.Params)
-		if err != nil {
-			log.Printf("Round1 failed: %v\n", err)
-			continue
-		}
-		signRound1TotalDur[state.ID] += time.Since(start)
-
-		state.Lock()
-		state.Strd1 = stdr1
-		index := state.ID
-		state.Msgs1[index] = msg1
-		state.Unlock()
-
-		netStart := time.Now()
-		rw.WriteString("R1\n")
-		rw.WriteString(fmt.Sprintf("%d\n", len(msg1)))
-		rw.Flush()
-		rw.Write(msg1)
-		rw.Flush()
-		networkRound1Dur[state.ID] += time.Since(netStart)
-		log.Println("Sent Round1")
-
-		for {
-			state.Lock()
-			if state.CompletedR1 >= state.N-1 {
-				state.CompletedR1 = 0
-				state.Unlock()
-				break
-			}
-			state.Unlock()
-		}
-
-		act := uint8((1 << 2) - 1)
-		start = time.Now()
-		msg2, stdr2, err := thmldsa44.Round2(
-			&state.SKs[state.ID],
-			act,
-			[]byte("the message"),
-			[]byte(""),
-			state.Msgs1,
-			&state.Strd1,
-			state.Params,
-		)
-		signRound2TotalDur[state.ID] += time.Since(start)
-		if err != nil {
-			log.Println("Round2 failed:", err)
-			continue
-		}
-
-		state.Lock()
-		state.Strd2 = stdr2
-		state.Msgs2[index] = msg2
-		state.Unlock()
-
-		netStart = time.Now()
-		rw.WriteString("R2\n")
-		rw.WriteString(fmt.Sprintf("%d\n", len(msg2)))
-		rw.Flush()
-		rw.Write(msg2)
-		rw.Flush()
-		networkRound2Dur[state.ID] += time.Since(netStart)
-		log.Println("Sent Round2")
-
-		for {
-			state.Lock()
-			if state.CompletedR2 >= state.N-1 {
-				state.Unlock()
-				state.CompletedR2 = 0
-				break
-			}
-			state.Unlock()
-		}
-
-		start = time.Now()
-		msg3, err := thmldsa44.Round3(
-			&state.SKs[state.ID],
-			state.Msgs2,
-			&state.Strd1,
-			&state.Strd2,
-			state.Params,
-		)
-		signRound3TotalDur[state.ID] += time.Since(start)
-		if err != nil {
-			log.Println("Round3 failed:", err)
-			continue
-		}
-
-		state.Msgs3[index] = msg3
-
-		netStart = time.Now()
-		rw.WriteString("R3\n")
-		rw.WriteString(fmt.Sprintf("%d\n", len(msg3)))
-		rw.Flush()
-		rw.Write(msg3)
-		rw.Flush()
-		networkRound3Dur[state.ID] += time.Since(netStart)
-		log.Println("Sent Round3")
-
-		if attempt == 569 {
-			state.done = true
-		}
-	}
-
-	fmt.Printf("Party %d - Avg Round 1 Local Computation Time: %s\n", state.ID, signRound1TotalDur[state.ID]/time.Duration(attemptCount))
-	fmt.Printf("Party %d - Avg Round 1 Network Send Time: %s\n", state.ID, networkRound1Dur[state.ID]/time.Duration(attemptCount))
-	fmt.Printf("Party %d - Avg Round 2 Local Computation Time: %s\n", state.ID, signRound2TotalDur[state.ID]/time.Duration(attemptCount))
-	fmt.Printf("Party %d - Avg Round 2 Network Send Time: %s\n", state.ID, networkRound2Dur[state.ID]/time.Duration(attemptCount))
-	fmt.Printf("Party %d - Avg Round 3 Local Computation Time: %s\n", state.ID, signRound3TotalDur[state.ID]/time.Duration(attemptCount))
-	fmt.Printf("Party %d - Avg Round 3 Network Send Time: %s\n", state.ID, networkRound3Dur[state.ID]/time.Duration(attemptCount))
-}
-
 func main() {
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
 	sourcePort := flag.Int("sp", 0, "Source port number")
-	dest := flag.String("d", "", "Destination multiaddr string")
 	id := flag.Int("id", 0, "Party ID (0, 1, etc.)")
+	t := flag.Int("t", 2, "Number of parties needed to sign")
+	n := flag.Int("n", 3, "Number of parties of the committee")
+	peersFile := flag.String("peers", "", "File listing the multiaddr of every party, one per line")
+	useMDNS := flag.Bool("mdns", false, "Discover the addresses of the other parties on the local network with mDNS")
+	signers := flag.String("signers", "", "Comma-separated IDs of the parties signing, or 'auto' for the first T reachable ones, to start a session")
+	message := flag.String("msg", "the message", "Message to sign")
+	addr := flag.Bool("addr", false, "Print the multiaddr of this node and exit")
 	help := flag.Bool("help", false, "Display help")
 	debug := flag.Bool("debug", false, "Debug generates the same node ID on every execution")
 
 	flag.Parse()
 
 	if *help {
-		fmt.Printf("This program demonstrates a simple p2p chat application using libp2p\n\n")
-		fmt.Println("Usage: Run './chat -sp <SOURCE_PORT>' where <SOURCE_PORT> can be any port number.")
-		fmt.Println("Now run './chat -d <MULTIADDR>' where <MULTIADDR> is multiaddress of previous listener host.")
+		fmt.Printf("This program demonstrates threshold ML-DSA signing among a committee of libp2p peers\n\n")
+		fmt.Println("Usage: Run './chat -debug -sp <SOURCE_PORT> -addr' for every party and list the printed multiaddrs in a file.")
+		fmt.Println("Then run './chat -debug -sp <SOURCE_PORT> -id <ID> -peers <FILE>' for every party, adding '-signers <IDS>'")
+		fmt.Println("on one of the signers to start a session.")
+		fmt.Println("With '-mdns', the file only needs the peer ID of every party, printed by './chat -debug -sp <SOURCE_PORT> -mdns -addr'.")
 
 		os.Exit(0)
 	}
@@ -353,60 +100,144 @@ func main() {
 		log.Println(err)
 		return
 	}
+	defer h.Close()
+
+	if *addr {
+		if *useMDNS {
+			// The addresses are discovered on the local network.
+			fmt.Printf("/p2p/%s\n", h.ID())
+			return
+		}
+		// Replace 127.0.0.1 with the IP of this machine when running on
+		// different hosts.
+		fmt.Printf("/ip4/127.0.0.1/tcp/%d/p2p/%s\n", *sourcePort, h.ID())
+		return
+	}
+
+	peers, err := readPeers(*peersFile)
+	if err != nil {
+		log.Println(err)
+		return
+	}
 
-	var genDur time.Duration
 	start := time.Now()
+	pk, sks, params, err := demoKeys(*t, *n)
+	if err != nil {
+		log.Println(err)
+		return
+	}
+	log.Printf("[TIME] GENERATION OF KEYS %s for %d parties out of %d", time.Since(start), *t, *n)
 
-	var seed [32]byte
-	binary.LittleEndian.PutUint64(seed[:], 1)
-	params, _ := thmldsa44.GetThresholdParams(uint8(2), uint8(6))
-	pk, sk := thmldsa44.NewThresholdKeysFromSeed(&seed, params)
-
-	genDur = time.Since(start)
-	log.Printf("[TIME] GENERATION OF KEYS %s for 2 parties out of 5", genDur)
-
-	state := &ProtocolState{
-		N:      2,
-		ID:     *id,
-		Params: params,
-		SKs:    sk,
-		pk:     pk,
-		Msgs1:  make([][]byte, 2),
-		Msgs2:  make([][]byte, 2),
-		Msgs3:  make([][]byte, 2),
-		done:   false,
+	if *id < 0 || *id >= *n {
+		log.Printf("party ID must be between 0 and %d", *n-1)
+		return
+	}
+	committee := &thmldsa.Committee{PK: pk, Params: params, Peers: peers}
+	node, err := startNode(h, committee, &sks[*id])
+	if err != nil {
+		log.Println(err)
+		return
 	}
-	var signature []byte
-	done := make(chan struct{})
+	defer node.Close()
 
-	if *dest == "" {
-		startPeer(ctx, h, makeStreamHandler(state, &signature, done), state.ID)
-	} else {
-		rw, err := startPeerAndConnect(ctx, h, *dest)
+	if *useMDNS {
+		d, err := node.Discover()
 		if err != nil {
 			log.Println(err)
 			return
 		}
-
-		// Create a thread to read and write data.
-		go readData(rw, state, &signature, done)
-		go runProtocolLoop(rw, state)
+		defer d.Close()
+		log.Printf("Discovering committee %s on the local network", committee.ID())
 	}
 
-	<-done // wait for combine
+	if *signers == "" {
+		<-ctx.Done()
+		return
+	}
 
-	verDur := time.Since(start)
-	if !thmldsa44.Verify(state.pk, []byte("the message"), []byte(""), signature) {
-		fmt.Println("Signature verification failed.")
+	var reachable uint8
+	if *useMDNS {
+		if reachable, err = waitForQuorum(ctx, node); err != nil {
+			log.Println(err)
+			return
+		}
 	} else {
-		fmt.Println("Signature verified successfully.")
+		reachable = connect(ctx, node)
+	}
+
+	var act uint8
+	if *signers == "auto" {
+		act = pickSigners(reachable, node.ID(), *t)
+	} else if act, err = parseSigners(*signers); err != nil {
+		log.Println(err)
+		return
+	}
+	if _, err := sign(ctx, node, committee, act, []byte(*message)); err != nil {
+		log.Println(err)
 	}
-	log.Printf("[TIME] VERIFICATION OF SIGS %s for 2 parties out of 5", verDur)
+}
+
+// demoKeys returns the keys of a T-of-N committee derived from a fixed
+// seed, so that every party gets them without a key distribution. Never do
+// this in production code.
+func demoKeys(t, n int) (*thmldsa44.PublicKey, []thmldsa44.PrivateKey, *thmldsa44.ThresholdParams, error) {
+	params, err := thmldsa44.GetThresholdParams(uint8(t), uint8(n))
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	var seed [32]byte
+	binary.LittleEndian.PutUint64(seed[:], 1)
+	pk, sks := thmldsa44.NewThresholdKeysFromSeed(&seed, params)
+	return pk, sks, params, nil
+}
+
+// readPeers reads the multiaddr of every party from the given file, party
+// i being on line i. Empty lines and lines starting with '#' are skipped.
+func readPeers(path string) ([]peer.AddrInfo, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var peers []peer.AddrInfo
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		info, err := peer.AddrInfoFromString(line)
+		if err != nil {
+			return nil, fmt.Errorf("party %d: %w", len(peers), err)
+		}
+		peers = append(peers, *info)
+	}
+	return peers, scanner.Err()
+}
+
+// parseSigners returns the signer set of a comma-separated list of party IDs.
+func parseSigners(s string) (uint8, error) {
+	var act uint8
+	for _, f := range strings.Split(s, ",") {
+		i, err := strconv.Atoi(strings.TrimSpace(f))
+		if err != nil || i < 0 || i > 7 {
+			return 0, fmt.Errorf("invalid party ID %q", f)
+		}
+		act |= 1 << i
+	}
+	return act, nil
 }
 
 func makeHost(port int, randomness io.Reader) (host.Host, error) {
-	// Creates a new RSA key pair for this host.
-	prvKey, _, err := crypto.GenerateKeyPairWithReader(crypto.RSA, 2048, randomness)
+	// Creates a new Ed25519 key pair for this host from a seed read from
+	// randomness, so that the debug mode always derives the same peer ID.
+	var seed [ed25519.SeedSize]byte
+	if _, err := io.ReadFull(randomness, seed[:]); err != nil {
+		log.Println(err)
+		return nil, err
+	}
+	prvKey, err := crypto.UnmarshalEd25519PrivateKey(ed25519.NewKeyFromSeed(seed[:]))
 	if err != nil {
 		log.Println(err)
 		return nil, err
@@ -423,68 +254,84 @@ func makeHost(port int, randomness io.Reader) (host.Host, error) {
 	)
 }
 
-func startPeer(ctx context.Context, h host.Host, streamHandler network.StreamHandler, ID int) {
-	// Set a function as stream handler.
-	// This function is called when a peer connects, and starts a stream with this protocol.
-	// Only applies on the receiving side.
-	h.SetStreamHandler("/chat/1.0.0", streamHandler)
-
-	// Let's get the actual TCP port from our listen multiaddr, in case we're using 0 (default; random available port).
-	var port string
-	for _, la := range h.Network().ListenAddresses() {
-		if p, err := la.ValueForProtocol(multiaddr.P_TCP); err == nil {
-			port = p
-			break
-		}
+// startNode joins the committee, logging the outcome of every session
+// proposed by another party.
+func startNode(h host.Host, committee *thmldsa.Committee, sk *thmldsa44.PrivateKey) (*thmldsa.Node, error) {
+	node, err := thmldsa.NewNode(h, committee, sk)
+	if err != nil {
+		return nil, err
 	}
-
-	if port == "" {
-		log.Println("was not able to find actual local port")
-		return
+	node.OnSession = func(r *thmldsa.Result) {
+		if r.Err != nil {
+			log.Printf("Session %x failed: %v", r.Session[:8], r.Err)
+			return
+		}
+		log.Printf("Session %x signed in %d attempts", r.Session[:8], r.Attempts)
 	}
 
-	log.Printf("Run './chat -d /ip4/127.0.0.1/tcp/%v/p2p/%s -id %d' on another console.\n", port, h.ID(), ID+1)
-	log.Println("You can replace 127.0.0.1 with public IP as well.")
-	log.Println("Waiting for incoming connection")
-	log.Println()
+	log.Printf("Party %d of %d, signing with %d parties", sk.Id, committee.Params.N, committee.Params.T)
+	log.Println("Waiting for signing sessions")
+	return node, nil
 }
 
-func startPeerAndConnect(ctx context.Context, h host.Host, destination string) (*bufio.ReadWriter, error) {
-	log.Println("This node's multiaddresses:")
-	for _, la := range h.Addrs() {
-		log.Printf(" - %v\n", la)
+// connect connects to the addresses of the committee list and returns the
+// reachable parties.
+func connect(ctx context.Context, node *thmldsa.Node) uint8 {
+	if err := node.Connect(ctx); err != nil {
+		log.Println("Some parties are unreachable:", err)
+	} else {
+		log.Println("Established connection to the committee")
 	}
-	log.Println()
+	return node.Reachable()
+}
 
-	// Turn the destination into a multiaddr.
-	maddr, err := multiaddr.NewMultiaddr(destination)
+// waitForQuorum waits until T parties of the committee, including this
+// node, are discovered and reachable, and returns them.
+func waitForQuorum(ctx context.Context, node *thmldsa.Node) (uint8, error) {
+	reachable, err := node.WaitReachable(ctx)
 	if err != nil {
-		log.Println(err)
-		return nil, err
+		return 0, err
 	}
+	log.Printf("Found parties %s on the local network", formatSigners(reachable))
+	return reachable, nil
+}
 
-	// Extract the peer ID from the multiaddr.
-	info, err := peer.AddrInfoFromP2pAddr(maddr)
-	if err != nil {
-		log.Println(err)
-		return nil, err
+// pickSigners returns a signer set of the first t reachable parties,
+// including the party self.
+func pickSigners(reachable, self uint8, t int) uint8 {
+	act := uint8(1) << self
+	for i := 0; i < 8 && bits.OnesCount8(act) < t; i++ {
+		act |= reachable & (1 << i)
 	}
+	return act
+}
 
-	// Add the destination's peer multiaddress in the peerstore.
-	// This will be used during connection and stream creation by libp2p.
-	h.Peerstore().AddAddrs(info.ID, info.Addrs, peerstore.PermanentAddrTTL)
+// formatSigners returns the comma-separated IDs of the parties of act.
+func formatSigners(act uint8) string {
+	var ids []string
+	for i := 0; i < 8; i++ {
+		if act&(1<<i) != 0 {
+			ids = append(ids, strconv.Itoa(i))
+		}
+	}
+	return strings.Join(ids, ",")
+}
 
-	// Start a stream with the destination.
-	// Multiaddress of the destination peer is fetched from the peerstore using 'peerId'.
-	s, err := h.NewStream(context.Background(), info.ID, "/chat/1.0.0")
+// sign runs a session with the signer set act, then verifies the signature.
+func sign(ctx context.Context, node *thmldsa.Node, committee *thmldsa.Committee, act uint8, msg []byte) ([]byte, error) {
+	log.Printf("Signing with parties %s", formatSigners(act))
+	start := time.Now()
+	sig, attempts, err := node.Sign(ctx, act, msg, nil)
 	if err != nil {
-		log.Println(err)
 		return nil, err
 	}
-	log.Println("Established connection to destination")
+	log.Printf("[TIME] SIGNING %s in %d attempts for %d parties out of %d", time.Since(start), attempts, committee.Params.T, committee.Params.N)
 
-	// Create a buffered stream so that read and writes are non-blocking.
-	rw := bufio.NewReadWriter(bufio.NewReader(s), bufio.NewWriter(s))
-
-	return rw, nil
+	start = time.Now()
+	if !thmldsa44.Verify(committee.PK, msg, nil, sig) {
+		return nil, fmt.Errorf("signature verification failed")
+	}
+	log.Printf("[TIME] VERIFICATION OF SIGS %s", time.Since(start))
+	log.Println("Signature verified successfully.")
+	return sig, nil
 }
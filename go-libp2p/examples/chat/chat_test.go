@@ -3,71 +3,65 @@ package main
 import (
 	"context"
 	"crypto/rand"
-	"fmt"
 	"log"
 	"testing"
 	"time"
 
-	"github.com/libp2p/go-libp2p/core/network"
+	"github.com/libp2p/go-libp2p/core/host"
+	"github.com/libp2p/go-libp2p/core/peer"
 
 	"github.com/libp2p/go-libp2p/examples/testutils"
+	"github.com/libp2p/go-libp2p/examples/thmldsa"
 )
 
 func TestMain(t *testing.T) {
 	var h testutils.LogHarness
-	h.Expect("Waiting for incoming connection")
-	h.Expect("Established connection to destination")
-	h.Expect("Got a new stream!")
+	h.Expect("Waiting for signing sessions")
+	h.Expect("Established connection to the committee")
+	h.Expect("Signature verified successfully.")
 
 	h.Run(t, func() {
-		// Create a context that will stop the hosts when the tests end
-		ctx, cancel := context.WithCancel(context.Background())
+		ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
 		defer cancel()
 
-		port1, err := testutils.FindFreePort(t, "", 5)
+		pk, sks, params, err := demoKeys(2, 3)
 		if err != nil {
 			log.Println(err)
 			return
 		}
 
-		port2, err := testutils.FindFreePort(t, "", 5)
-		if err != nil {
-			log.Println(err)
-			return
-		}
-
-		h1, err := makeHost(port1, rand.Reader)
-		if err != nil {
-			log.Println(err)
-			return
-		}
-
-		go startPeer(ctx, h1, func(network.Stream) {
-			log.Println("Got a new stream!")
-			// Sleep a bit to let h2 print the logs we're waiting for
-			time.Sleep(500 * time.Millisecond)
-			cancel() // end the test
-		})
-
-		dest := fmt.Sprintf("/ip4/127.0.0.1/tcp/%v/p2p/%s", port1, h1.ID())
-
-		h2, err := makeHost(port2, rand.Reader)
-		if err != nil {
-			log.Println(err)
-			return
+		var hosts []host.Host
+		committee := &thmldsa.Committee{PK: pk, Params: params}
+		for i := 0; i < 3; i++ {
+			port, err := testutils.FindFreePort(t, "", 5)
+			if err != nil {
+				log.Println(err)
+				return
+			}
+			h, err := makeHost(port, rand.Reader)
+			if err != nil {
+				log.Println(err)
+				return
+			}
+			defer h.Close()
+			hosts = append(hosts, h)
+			committee.Peers = append(committee.Peers, peer.AddrInfo{ID: h.ID(), Addrs: h.Addrs()})
 		}
 
-		go func() {
-			rw, err := startPeerAndConnect(ctx, h2, dest)
+		var nodes []*thmldsa.Node
+		for i, h := range hosts {
+			node, err := startNode(h, committee, &sks[i])
 			if err != nil {
 				log.Println(err)
 				return
 			}
+			defer node.Close()
+			nodes = append(nodes, node)
+		}
 
-			rw.WriteString("test message")
-			rw.Flush()
-		}()
-
-		<-ctx.Done()
+		connect(ctx, nodes[2])
+		if _, err := sign(ctx, nodes[2], committee, 0b101, []byte("test message")); err != nil {
+			log.Println(err)
+		}
 	})
 }
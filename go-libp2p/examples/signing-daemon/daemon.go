@@ -0,0 +1,425 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cloudflare/circl/sign/thmldsa/thmldsa44"
+	"github.com/libp2p/go-libp2p/core/host"
+	"github.com/libp2p/go-libp2p/examples/thmldsa"
+)
+
+// maxRecentSessions bounds the number of sessions listed by the API.
+const maxRecentSessions = 100
+
+// daemon runs the node of one party and serves its local API.
+type daemon struct {
+	host      host.Host
+	node      *thmldsa.Node
+	committee *thmldsa.Committee
+	policy    *Policy
+
+	mu          sync.Mutex
+	nextID      uint64
+	invitations map[uint64]*invitation
+	recent      []sessionInfo
+}
+
+// invitation is a session proposed by another party, waiting for an
+// approval through the API.
+type invitation struct {
+	ID       uint64    `json:"id"`
+	From     uint8     `json:"from"`
+	Signers  []uint8   `json:"signers"`
+	Message  []byte    `json:"message"`
+	Context  string    `json:"context"`
+	Received time.Time `json:"received"`
+
+	decision chan bool
+}
+
+// sessionInfo is the outcome of a session joined by the daemon.
+type sessionInfo struct {
+	Session   string  `json:"session"`
+	Signers   []uint8 `json:"signers"`
+	Message   []byte  `json:"message"`
+	Context   string  `json:"context"`
+	Signature []byte  `json:"signature,omitempty"`
+	Attempts  int     `json:"attempts"`
+	Error     string  `json:"error,omitempty"`
+}
+
+func newDaemon(h host.Host, committee *thmldsa.Committee, s *share, policy *Policy) (*daemon, error) {
+	node, err := thmldsa.NewNode(h, committee, s.SK)
+	if err != nil {
+		return nil, err
+	}
+	d := &daemon{
+		host:        h,
+		node:        node,
+		committee:   committee,
+		policy:      policy,
+		invitations: make(map[uint64]*invitation),
+	}
+	node.Approve = d.approve
+	node.OnSession = d.record
+	if t := policy.approvalTimeout() + time.Minute; t > node.SessionTimeout {
+		node.SessionTimeout = t
+	}
+	return d, nil
+}
+
+func (d *daemon) Close() error {
+	return d.node.Close()
+}
+
+// approve applies the policy to a session proposed by party from.
+func (d *daemon) approve(from, act uint8, msg, ctx []byte) bool {
+	if err := d.policy.check(from, msg, ctx); err != nil {
+		log.Printf("Refused session of party %d: %v", from, err)
+		return false
+	}
+	if !d.policy.Manual {
+		return true
+	}
+
+	d.mu.Lock()
+	d.nextID++
+	inv := &invitation{
+		ID:       d.nextID,
+		From:     from,
+		Signers:  signerIDs(act),
+		Message:  msg,
+		Context:  string(ctx),
+		Received: time.Now(),
+		decision: make(chan bool, 1),
+	}
+	d.invitations[inv.ID] = inv
+	d.mu.Unlock()
+	log.Printf("Invitation %d from party %d waiting for approval", inv.ID, from)
+
+	defer func() {
+		d.mu.Lock()
+		delete(d.invitations, inv.ID)
+		d.mu.Unlock()
+	}()
+	select {
+	case ok := <-inv.decision:
+		return ok
+	case <-time.After(d.policy.approvalTimeout()):
+		log.Printf("Invitation %d expired", inv.ID)
+		return false
+	}
+}
+
+// record keeps the outcome of a session joined upon a proposal.
+func (d *daemon) record(r *thmldsa.Result) {
+	info := sessionInfo{
+		Session:   fmt.Sprintf("%x", r.Session),
+		Signers:   signerIDs(r.Act),
+		Message:   r.Msg,
+		Context:   string(r.Ctx),
+		Signature: r.Signature,
+		Attempts:  r.Attempts,
+	}
+	if r.Err != nil {
+		info.Error = r.Err.Error()
+		log.Printf("Session %x failed: %v", r.Session[:8], r.Err)
+	} else {
+		log.Printf("Session %x signed in %d attempts", r.Session[:8], r.Attempts)
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.recent = append(d.recent, info)
+	if len(d.recent) > maxRecentSessions {
+		d.recent = d.recent[1:]
+	}
+}
+
+// signerIDs returns the party IDs of the signer set act.
+func signerIDs(act uint8) []uint8 {
+	ids := []uint8{}
+	for i := uint8(0); i < 8; i++ {
+		if act&(1<<i) != 0 {
+			ids = append(ids, i)
+		}
+	}
+	return ids
+}
+
+// handler returns the local API of the daemon.
+func (d *daemon) handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /v1/info", d.handleInfo)
+	mux.HandleFunc("POST /v1/sign", d.handleSign)
+	mux.HandleFunc("GET /v1/invitations", d.handleInvitations)
+	mux.HandleFunc("POST /v1/invitations/{id}/{decision}", d.handleDecision)
+	mux.HandleFunc("GET /v1/sessions", d.handleSessions)
+	return mux
+}
+
+type infoResponse struct {
+	Party     uint8    `json:"party"`
+	T         uint8    `json:"t"`
+	N         uint8    `json:"n"`
+	PublicKey []byte   `json:"public_key"`
+	PeerID    string   `json:"peer_id"`
+	Committee []string `json:"committee"`
+	Reachable []uint8  `json:"reachable"`
+}
+
+func (d *daemon) handleInfo(w http.ResponseWriter, r *http.Request) {
+	resp := infoResponse{
+		Party:     d.node.ID(),
+		T:         d.committee.Params.T,
+		N:         d.committee.Params.N,
+		PublicKey: d.committee.PK.Bytes(),
+		PeerID:    d.host.ID().String(),
+		Reachable: signerIDs(d.node.Reachable()),
+	}
+	for _, info := range d.committee.Peers {
+		resp.Committee = append(resp.Committee, info.ID.String())
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+type signRequest struct {
+	Signers []uint8 `json:"signers"`
+	Message []byte  `json:"message"`
+	Context string  `json:"context"`
+
+	// Timeout of the session, as parsed by time.ParseDuration. It defaults
+	// to one minute.
+	Timeout string `json:"timeout,omitempty"`
+}
+
+type signResponse struct {
+	Signature []byte `json:"signature"`
+	Attempts  int    `json:"attempts"`
+}
+
+// handleSign invites the other signers to a session and runs it.
+func (d *daemon) handleSign(w http.ResponseWriter, r *http.Request) {
+	var req signRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	var act uint8
+	for _, id := range req.Signers {
+		if id >= d.committee.Params.N {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("no party %d", id))
+			return
+		}
+		act |= 1 << id
+	}
+	timeout := time.Minute
+	if req.Timeout != "" {
+		var err error
+		if timeout, err = time.ParseDuration(req.Timeout); err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), timeout)
+	defer cancel()
+	if err := d.node.Connect(ctx); err != nil {
+		log.Println("Some parties are unreachable:", err)
+	}
+	sig, attempts, err := d.node.Sign(ctx, act, req.Message, []byte(req.Context))
+	switch {
+	case errors.Is(err, thmldsa.ErrSignerSet):
+		writeError(w, http.StatusBadRequest, err)
+		return
+	case err != nil:
+		writeError(w, http.StatusBadGateway, err)
+		return
+	}
+	if !thmldsa44.Verify(d.committee.PK, req.Message, []byte(req.Context), sig) {
+		writeError(w, http.StatusInternalServerError, errors.New("invalid signature"))
+		return
+	}
+	writeJSON(w, http.StatusOK, signResponse{sig, attempts})
+}
+
+func (d *daemon) handleInvitations(w http.ResponseWriter, r *http.Request) {
+	d.mu.Lock()
+	invs := make([]*invitation, 0, len(d.invitations))
+	for _, inv := range d.invitations {
+		invs = append(invs, inv)
+	}
+	d.mu.Unlock()
+	writeJSON(w, http.StatusOK, invs)
+}
+
+// handleDecision approves or rejects an invitation.
+func (d *daemon) handleDecision(w http.ResponseWriter, r *http.Request) {
+	var approve bool
+	switch r.PathValue("decision") {
+	case "approve":
+		approve = true
+	case "reject":
+	default:
+		http.NotFound(w, r)
+		return
+	}
+	id, err := strconv.ParseUint(r.PathValue("id"), 10, 64)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	d.mu.Lock()
+	inv, ok := d.invitations[id]
+	d.mu.Unlock()
+	if !ok {
+		writeError(w, http.StatusNotFound, fmt.Errorf("no pending invitation %d", id))
+		return
+	}
+	select {
+	case inv.decision <- approve:
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		writeError(w, http.StatusConflict, fmt.Errorf("invitation %d already decided", id))
+	}
+}
+
+func (d *daemon) handleSessions(w http.ResponseWriter, r *http.Request) {
+	d.mu.Lock()
+	recent := append([]sessionInfo{}, d.recent...)
+	d.mu.Unlock()
+	writeJSON(w, http.StatusOK, recent)
+}
+
+// requireToken serves the requests of h which carry the bearer token.
+func requireToken(token string, h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(got), []byte(token)) != 1 {
+			writeError(w, http.StatusUnauthorized, errors.New("missing or wrong API token"))
+			return
+		}
+		h.ServeHTTP(w, r)
+	})
+}
+
+// apiToken returns the bearer token of the API served on addr, read from
+// path, or generated and written there if the file does not exist. A TCP
+// API, which any local user can connect to, must have one.
+func apiToken(addr, path string) (string, error) {
+	if path == "" {
+		if strings.HasPrefix(addr, "unix:") {
+			return "", nil
+		}
+		return "", fmt.Errorf("API address %s needs a token file", addr)
+	}
+
+	data, err := os.ReadFile(path)
+	if err == nil {
+		token := strings.TrimSpace(string(data))
+		if token == "" {
+			return "", fmt.Errorf("%s: empty API token", path)
+		}
+		return token, nil
+	}
+	if !os.IsNotExist(err) {
+		return "", err
+	}
+
+	var b [32]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return "", err
+	}
+	token := hex.EncodeToString(b[:])
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600)
+	if err != nil {
+		return "", err
+	}
+	if _, err := fmt.Fprintln(f, token); err != nil {
+		f.Close()
+		return "", err
+	}
+	return token, f.Close()
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, map[string]string{"error": err.Error()})
+}
+
+// unixListener removes its socket, moved from where it was created, once
+// closed.
+type unixListener struct {
+	net.Listener
+	path string
+}
+
+func (l *unixListener) Close() error {
+	err := l.Listener.Close()
+	os.Remove(l.path)
+	return err
+}
+
+// listenAPI listens on a Unix socket for addresses of the form unix:PATH,
+// and on a loopback TCP address otherwise, as the API is only meant for the
+// applications of the local host.
+func listenAPI(addr string) (net.Listener, error) {
+	if path, ok := strings.CutPrefix(addr, "unix:"); ok {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return nil, err
+		}
+
+		// The socket is created in a directory only the owner can enter,
+		// and moved into place once no one else can connect to it.
+		dir, err := os.MkdirTemp(filepath.Dir(path), ".signing-daemon-")
+		if err != nil {
+			return nil, err
+		}
+		defer os.RemoveAll(dir)
+		tmp := filepath.Join(dir, "api.sock")
+		l, err := net.Listen("unix", tmp)
+		if err != nil {
+			return nil, err
+		}
+		l.(*net.UnixListener).SetUnlinkOnClose(false)
+		if err := os.Chmod(tmp, 0o600); err != nil {
+			l.Close()
+			return nil, err
+		}
+		if err := os.Rename(tmp, path); err != nil {
+			l.Close()
+			return nil, err
+		}
+		return &unixListener{l, path}, nil
+	}
+
+	hostname, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, err
+	}
+	if ip := net.ParseIP(hostname); hostname != "localhost" && (ip == nil || !ip.IsLoopback()) {
+		return nil, fmt.Errorf("API address %s is not a loopback address", addr)
+	}
+	return net.Listen("tcp", addr)
+}
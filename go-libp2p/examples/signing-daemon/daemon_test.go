@@ -0,0 +1,228 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/cloudflare/circl/sign/thmldsa/thmldsa44"
+	"github.com/libp2p/go-libp2p/core/crypto"
+	"github.com/libp2p/go-libp2p/examples/testutils"
+)
+
+// bearer adds a bearer token to the requests.
+type bearer struct {
+	token string
+	next  http.RoundTripper
+}
+
+func (b *bearer) RoundTrip(r *http.Request) (*http.Response, error) {
+	r = r.Clone(r.Context())
+	r.Header.Set("Authorization", "Bearer "+b.token)
+	return b.next.RoundTrip(r)
+}
+
+// client returns a client of the API served at address addr of network,
+// sending the given bearer token if it is not empty.
+func client(network, addr, token string) *http.Client {
+	var rt http.RoundTripper = &http.Transport{
+		DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			var d net.Dialer
+			return d.DialContext(ctx, network, addr)
+		},
+	}
+	if token != "" {
+		rt = &bearer{token, rt}
+	}
+	return &http.Client{Transport: rt}
+}
+
+func call(t *testing.T, c *http.Client, method, path string, req, resp any) int {
+	t.Helper()
+	var body bytes.Buffer
+	if req != nil {
+		if err := json.NewEncoder(&body).Encode(req); err != nil {
+			t.Fatal(err)
+		}
+	}
+	r, err := http.NewRequest(method, "http://daemon"+path, &body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res, err := c.Do(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+	if resp != nil && res.StatusCode == http.StatusOK {
+		if err := json.NewDecoder(res.Body).Decode(resp); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return res.StatusCode
+}
+
+func TestDaemon(t *testing.T) {
+	dir := t.TempDir()
+	passphrase := []byte("correct horse battery staple")
+	port, err := testutils.FindFreePort(t, "", 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := dealKeys(dir, 2, 3, port, passphrase); err != nil {
+		t.Fatal(err)
+	}
+
+	kf, err := readKeyFile(filepath.Join(dir, "party-0.key"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := kf.open([]byte("wrong")); err != errWrongPassphrase {
+		t.Fatalf("got %v instead of errWrongPassphrase", err)
+	}
+	s, err := kf.open(passphrase)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if typ := s.Identity.Type(); typ != crypto.MLDSA44 {
+		t.Fatalf("%s identity key instead of ML-DSA-44", typ)
+	}
+
+	// Party 0 serves its API over TCP, which needs a token, on the port
+	// after those of the parties
+	tcpAPI := fmt.Sprintf("127.0.0.1:%d", port+3)
+	if _, err := apiToken(tcpAPI, ""); err == nil {
+		t.Fatal("TCP API without a token accepted")
+	}
+	tokenPath := filepath.Join(dir, "api-token")
+	token, err := apiToken(tcpAPI, tokenPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again, err := apiToken(tcpAPI, tokenPath); err != nil || again != token {
+		t.Fatal("API token not read back from its file")
+	}
+
+	// Party 1 waits for approvals, party 2 only signs payments
+	policies := []string{"", `{"manual": true}`, `{"contexts": ["payments"]}`}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	clients := make([]*http.Client, 3)
+	for i, policy := range policies {
+		policyPath := ""
+		if policy != "" {
+			policyPath = filepath.Join(dir, fmt.Sprintf("policy-%d.json", i))
+			if err := os.WriteFile(policyPath, []byte(policy), 0o600); err != nil {
+				t.Fatal(err)
+			}
+		}
+		socket := filepath.Join(dir, fmt.Sprintf("%d.sock", i))
+		api, apiTokenPath := "unix:"+socket, ""
+		clients[i] = client("unix", socket, "")
+		if i == 0 {
+			api, apiTokenPath = tcpAPI, tokenPath
+			clients[i] = client("tcp", tcpAPI, token)
+		}
+		go func() {
+			err := run(ctx, filepath.Join(dir, fmt.Sprintf("party-%d.key", i)), filepath.Join(dir, "committee.txt"),
+				fmt.Sprintf("/ip4/127.0.0.1/tcp/%d", port+i), api, apiTokenPath, policyPath, passphrase)
+			if err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+
+	var info infoResponse
+	for i, c := range clients {
+		for deadline := time.Now().Add(10 * time.Second); ; {
+			if res, err := c.Get("http://daemon/v1/info"); err == nil {
+				res.Body.Close()
+				if call(t, c, "GET", "/v1/info", nil, &info) == http.StatusOK {
+					break
+				}
+			}
+			if time.Now().After(deadline) {
+				t.Fatalf("daemon %d didn't start", i)
+			}
+			time.Sleep(50 * time.Millisecond)
+		}
+		if info.Party != uint8(i) {
+			t.Fatalf("daemon %d serves party %d", i, info.Party)
+		}
+	}
+	var pk thmldsa44.PublicKey
+	if err := pk.UnmarshalBinary(info.PublicKey); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("API access", func(t *testing.T) {
+		if status := call(t, client("tcp", tcpAPI, ""), "GET", "/v1/info", nil, nil); status != http.StatusUnauthorized {
+			t.Fatalf("status %d without a token", status)
+		}
+		if status := call(t, client("tcp", tcpAPI, "wrong"), "GET", "/v1/info", nil, nil); status != http.StatusUnauthorized {
+			t.Fatalf("status %d with a wrong token", status)
+		}
+		fi, err := os.Stat(filepath.Join(dir, "1.sock"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if perm := fi.Mode().Perm(); perm != 0o600 {
+			t.Fatalf("socket of mode %o", perm)
+		}
+	})
+
+	t.Run("manual approval", func(t *testing.T) {
+		go func() {
+			for {
+				var invs []invitation
+				call(t, clients[1], "GET", "/v1/invitations", nil, &invs)
+				if len(invs) > 0 {
+					call(t, clients[1], "POST", fmt.Sprintf("/v1/invitations/%d/approve", invs[0].ID), nil, nil)
+					return
+				}
+				time.Sleep(20 * time.Millisecond)
+			}
+		}()
+
+		req := signRequest{Signers: []uint8{0, 1}, Message: []byte("pay 10"), Context: "payments"}
+		var resp signResponse
+		if status := call(t, clients[0], "POST", "/v1/sign", req, &resp); status != http.StatusOK {
+			t.Fatalf("status %d", status)
+		}
+		if !thmldsa44.Verify(&pk, req.Message, []byte(req.Context), resp.Signature) {
+			t.Fatal("invalid signature")
+		}
+
+		var sessions []sessionInfo
+		call(t, clients[1], "GET", "/v1/sessions", nil, &sessions)
+		if len(sessions) != 1 || !bytes.Equal(sessions[0].Signature, resp.Signature) {
+			t.Fatalf("party 1 recorded %+v", sessions)
+		}
+	})
+
+	t.Run("refused by policy", func(t *testing.T) {
+		req := signRequest{Signers: []uint8{0, 2}, Message: []byte("hello"), Context: "other", Timeout: "1s"}
+		if status := call(t, clients[0], "POST", "/v1/sign", req, nil); status != http.StatusBadGateway {
+			t.Fatalf("status %d", status)
+		}
+
+		req.Context = "payments"
+		if status := call(t, clients[2], "POST", "/v1/sign", req, nil); status != http.StatusOK {
+			t.Fatalf("status %d", status)
+		}
+	})
+
+	t.Run("bad signer set", func(t *testing.T) {
+		req := signRequest{Signers: []uint8{1, 2}, Message: []byte("hello")}
+		if status := call(t, clients[0], "POST", "/v1/sign", req, nil); status != http.StatusBadRequest {
+			t.Fatalf("status %d", status)
+		}
+	})
+}
@@ -0,0 +1,161 @@
+package main
+
+import (
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/cloudflare/circl/sign/thmldsa/thmldsa44"
+	"github.com/libp2p/go-libp2p/core/crypto"
+	"golang.org/x/crypto/chacha20poly1305"
+	"golang.org/x/crypto/scrypt"
+)
+
+// keyFile is the on-disk form of the secrets of one party, its key share and
+// libp2p identity, sealed with a key derived from a passphrase. The public
+// fields are authenticated along with the secrets.
+type keyFile struct {
+	Version   int    `json:"version"`
+	Party     uint8  `json:"party"`
+	T         uint8  `json:"t"`
+	N         uint8  `json:"n"`
+	PublicKey []byte `json:"public_key"`
+	Salt      []byte `json:"salt"`
+	Nonce     []byte `json:"nonce"`
+	Sealed    []byte `json:"sealed"`
+}
+
+const keyFileVersion = 1
+
+// scrypt parameters of the key sealing the secrets
+const (
+	scryptN = 1 << 15
+	scryptR = 8
+	scryptP = 1
+)
+
+// share holds the secrets of a party once the key file is opened.
+type share struct {
+	PK       *thmldsa44.PublicKey
+	Params   *thmldsa44.ThresholdParams
+	SK       *thmldsa44.PrivateKey
+	Identity crypto.PrivKey
+}
+
+var errWrongPassphrase = errors.New("wrong passphrase or corrupted key file")
+
+// additionalData returns the public fields authenticated with the secrets.
+func (kf *keyFile) additionalData() []byte {
+	ad := fmt.Appendf(nil, "signing-daemon/v%d party=%d t=%d n=%d pk=", kf.Version, kf.Party, kf.T, kf.N)
+	return append(ad, kf.PublicKey...)
+}
+
+func (kf *keyFile) aead(passphrase []byte) (cipher.AEAD, error) {
+	key, err := scrypt.Key(passphrase, kf.Salt, scryptN, scryptR, scryptP, chacha20poly1305.KeySize)
+	if err != nil {
+		return nil, err
+	}
+	return chacha20poly1305.NewX(key)
+}
+
+// sealShare returns the key file of s, sealed with passphrase.
+func sealShare(s *share, passphrase []byte) (*keyFile, error) {
+	identity, err := crypto.MarshalPrivateKey(s.Identity)
+	if err != nil {
+		return nil, err
+	}
+	packed := make([]byte, s.Params.PrivateKeySize())
+	s.SK.Pack(packed)
+
+	secrets := binary.AppendUvarint(nil, uint64(len(identity)))
+	secrets = append(secrets, identity...)
+	secrets = append(secrets, packed...)
+
+	kf := &keyFile{
+		Version:   keyFileVersion,
+		Party:     s.SK.Id,
+		T:         s.Params.T,
+		N:         s.Params.N,
+		PublicKey: s.PK.Bytes(),
+		Salt:      make([]byte, 16),
+		Nonce:     make([]byte, chacha20poly1305.NonceSizeX),
+	}
+	if _, err := rand.Read(kf.Salt); err != nil {
+		return nil, err
+	}
+	if _, err := rand.Read(kf.Nonce); err != nil {
+		return nil, err
+	}
+	aead, err := kf.aead(passphrase)
+	if err != nil {
+		return nil, err
+	}
+	kf.Sealed = aead.Seal(nil, kf.Nonce, secrets, kf.additionalData())
+	return kf, nil
+}
+
+// open returns the secrets of the key file.
+func (kf *keyFile) open(passphrase []byte) (*share, error) {
+	if kf.Version != keyFileVersion {
+		return nil, fmt.Errorf("unsupported key file version %d", kf.Version)
+	}
+	params, err := thmldsa44.GetThresholdParams(kf.T, kf.N)
+	if err != nil {
+		return nil, err
+	}
+	var pk thmldsa44.PublicKey
+	if err := pk.UnmarshalBinary(kf.PublicKey); err != nil {
+		return nil, err
+	}
+	if len(kf.Nonce) != chacha20poly1305.NonceSizeX {
+		return nil, errWrongPassphrase
+	}
+
+	aead, err := kf.aead(passphrase)
+	if err != nil {
+		return nil, err
+	}
+	secrets, err := aead.Open(nil, kf.Nonce, kf.Sealed, kf.additionalData())
+	if err != nil {
+		return nil, errWrongPassphrase
+	}
+
+	size, n := binary.Uvarint(secrets)
+	if n <= 0 || uint64(len(secrets)-n) != size+uint64(params.PrivateKeySize()) {
+		return nil, errors.New("malformed key file secrets")
+	}
+	identity, err := crypto.UnmarshalPrivateKey(secrets[n : n+int(size)])
+	if err != nil {
+		return nil, err
+	}
+	var sk thmldsa44.PrivateKey
+	sk.Unpack(secrets[n+int(size):])
+	if sk.Id != kf.Party {
+		return nil, errors.New("key share of another party")
+	}
+	return &share{PK: &pk, Params: params, SK: &sk, Identity: identity}, nil
+}
+
+func readKeyFile(path string) (*keyFile, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var kf keyFile
+	if err := json.Unmarshal(data, &kf); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return &kf, nil
+}
+
+func writeKeyFile(path string, kf *keyFile) error {
+	data, err := json.MarshalIndent(kf, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0o600)
+}
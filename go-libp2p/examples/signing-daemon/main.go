@@ -0,0 +1,211 @@
+// The signing daemon holds the key share of one party of a threshold
+// ML-DSA-44 committee. It joins the sessions proposed by the daemons of the
+// other parties as its approval policy allows, and lets the applications of
+// the local host start sessions through an API served on a Unix socket or a
+// loopback address, so that they never hold the share themselves.
+package main
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+
+	"github.com/cloudflare/circl/sign/thmldsa/thmldsa44"
+	"github.com/libp2p/go-libp2p"
+	"github.com/libp2p/go-libp2p/core/crypto"
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/libp2p/go-libp2p/examples/thmldsa"
+	"github.com/multiformats/go-multiaddr"
+)
+
+// passphraseEnv is the environment variable holding the passphrase of the
+// key files, unless -passphrase-file is given.
+const passphraseEnv = "SIGNING_DAEMON_PASSPHRASE"
+
+func main() {
+	keyPath := flag.String("key", "", "Encrypted key file of this party")
+	committeePath := flag.String("committee", "", "File listing the multiaddr of every party, one per line")
+	listen := flag.String("listen", "/ip4/0.0.0.0/tcp/4001", "Multiaddr to listen on for the other parties")
+	api := flag.String("api", "unix:signing-daemon.sock", "Local API address: unix:PATH or a loopback HOST:PORT")
+	tokenPath := flag.String("api-token-file", "", "File holding the bearer token of the API, generated if missing; required for a TCP API")
+	policyPath := flag.String("policy", "", "JSON approval policy; every session is joined without it")
+	passphraseFile := flag.String("passphrase-file", "", "File holding the passphrase of the key files, instead of $"+passphraseEnv)
+
+	genkeys := flag.String("genkeys", "", "Deal the key files of a new committee into the given directory, then exit")
+	t := flag.Int("t", 2, "With -genkeys, number of parties needed to sign")
+	n := flag.Int("n", 3, "With -genkeys, number of parties of the committee")
+	basePort := flag.Int("base-port", 4001, "With -genkeys, TCP port of party 0 on 127.0.0.1, party i using base-port+i")
+	flag.Parse()
+
+	passphrase, err := readPassphrase(*passphraseFile)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if *genkeys != "" {
+		if err := dealKeys(*genkeys, uint8(*t), uint8(*n), *basePort, passphrase); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	if err := run(ctx, *keyPath, *committeePath, *listen, *api, *tokenPath, *policyPath, passphrase); err != nil {
+		log.Fatal(err)
+	}
+}
+
+func readPassphrase(path string) ([]byte, error) {
+	if path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		return []byte(strings.TrimRight(string(data), "\r\n")), nil
+	}
+	if p := os.Getenv(passphraseEnv); p != "" {
+		return []byte(p), nil
+	}
+	return nil, fmt.Errorf("no passphrase: set $%s or use -passphrase-file", passphraseEnv)
+}
+
+// run serves the daemon until ctx is done.
+func run(ctx context.Context, keyPath, committeePath, listen, api, tokenPath, policyPath string, passphrase []byte) error {
+	kf, err := readKeyFile(keyPath)
+	if err != nil {
+		return err
+	}
+	s, err := kf.open(passphrase)
+	if err != nil {
+		return err
+	}
+	if !crypto.IsMLDSAKeyType(s.Identity.Type()) {
+		return fmt.Errorf("%s: %s identity key instead of ML-DSA", keyPath, s.Identity.Type())
+	}
+	token, err := apiToken(api, tokenPath)
+	if err != nil {
+		return err
+	}
+	peers, err := readPeers(committeePath)
+	if err != nil {
+		return err
+	}
+	policy, err := readPolicy(policyPath)
+	if err != nil {
+		return err
+	}
+
+	h, err := libp2p.New(libp2p.ListenAddrStrings(listen), libp2p.Identity(s.Identity))
+	if err != nil {
+		return err
+	}
+	defer h.Close()
+
+	committee := &thmldsa.Committee{PK: s.PK, Params: s.Params, Peers: peers}
+	d, err := newDaemon(h, committee, s, policy)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+
+	l, err := listenAPI(api)
+	if err != nil {
+		return err
+	}
+	handler := d.handler()
+	if token != "" {
+		handler = requireToken(token, handler)
+	}
+	srv := &http.Server{Handler: handler}
+	go func() {
+		<-ctx.Done()
+		srv.Close()
+	}()
+
+	log.Printf("Party %d of committee %s, signing with %d of %d parties", s.SK.Id, committee.ID(), s.Params.T, s.Params.N)
+	log.Printf("Serving the local API on %s", api)
+	if err := srv.Serve(l); !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+// readPeers reads the multiaddr of every party from the given file, party
+// i being on line i. Empty lines and lines starting with '#' are skipped.
+func readPeers(path string) ([]peer.AddrInfo, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var peers []peer.AddrInfo
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		info, err := peer.AddrInfoFromString(line)
+		if err != nil {
+			return nil, fmt.Errorf("party %d: %w", len(peers), err)
+		}
+		peers = append(peers, *info)
+	}
+	return peers, scanner.Err()
+}
+
+// dealKeys generates the keys of a T-of-N committee as a trusted dealer, and
+// writes the key file of every party and the committee file into dir. The
+// parties listen on consecutive ports of 127.0.0.1: edit the committee file
+// to run them on different hosts.
+func dealKeys(dir string, t, n uint8, basePort int, passphrase []byte) error {
+	params, err := thmldsa44.GetThresholdParams(t, n)
+	if err != nil {
+		return err
+	}
+	pk, sks, err := thmldsa44.GenerateThresholdKey(rand.Reader, params)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return err
+	}
+
+	var committee strings.Builder
+	for i := range sks {
+		identity, _, err := crypto.GenerateMLDSAKey(crypto.MLDSA44, rand.Reader)
+		if err != nil {
+			return err
+		}
+		kf, err := sealShare(&share{PK: pk, Params: params, SK: &sks[i], Identity: identity}, passphrase)
+		if err != nil {
+			return err
+		}
+		if err := writeKeyFile(filepath.Join(dir, fmt.Sprintf("party-%d.key", i)), kf); err != nil {
+			return err
+		}
+
+		id, err := peer.IDFromPrivateKey(identity)
+		if err != nil {
+			return err
+		}
+		addr, err := multiaddr.NewMultiaddr(fmt.Sprintf("/ip4/127.0.0.1/tcp/%d/p2p/%s", basePort+i, id))
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(&committee, addr)
+	}
+	return os.WriteFile(filepath.Join(dir, "committee.txt"), []byte(committee.String()), 0o644)
+}
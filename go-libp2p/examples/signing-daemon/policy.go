@@ -0,0 +1,69 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+	"time"
+)
+
+// Policy decides which sessions proposed by other parties the daemon joins.
+type Policy struct {
+	// Proposers lists the parties allowed to propose sessions. If empty,
+	// every member of the committee is.
+	Proposers []uint8 `json:"proposers,omitempty"`
+
+	// Contexts lists the allowed signing contexts. If empty, every context
+	// is.
+	Contexts []string `json:"contexts,omitempty"`
+
+	// MaxMessageSize bounds the size of the messages signed, if not zero.
+	MaxMessageSize int `json:"max_message_size,omitempty"`
+
+	// Manual makes the sessions allowed by the rules above wait for an
+	// approval through the API.
+	Manual bool `json:"manual,omitempty"`
+
+	// ApprovalTimeout bounds how long an invitation waits for an approval,
+	// in seconds. It defaults to 30 seconds.
+	ApprovalTimeout int `json:"approval_timeout,omitempty"`
+}
+
+// check returns why a session proposed by party from is refused, if it is.
+func (p *Policy) check(from uint8, msg, ctx []byte) error {
+	if len(p.Proposers) > 0 && !slices.Contains(p.Proposers, from) {
+		return fmt.Errorf("party %d may not propose sessions", from)
+	}
+	if len(p.Contexts) > 0 && !slices.Contains(p.Contexts, string(ctx)) {
+		return fmt.Errorf("context %q not allowed", ctx)
+	}
+	if p.MaxMessageSize > 0 && len(msg) > p.MaxMessageSize {
+		return fmt.Errorf("message of %d bytes exceeds %d", len(msg), p.MaxMessageSize)
+	}
+	return nil
+}
+
+func (p *Policy) approvalTimeout() time.Duration {
+	if p.ApprovalTimeout <= 0 {
+		return 30 * time.Second
+	}
+	return time.Duration(p.ApprovalTimeout) * time.Second
+}
+
+// readPolicy reads a policy from a JSON file. An empty path gives the policy
+// joining every session.
+func readPolicy(path string) (*Policy, error) {
+	p := &Policy{}
+	if path == "" {
+		return p, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, p); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return p, nil
+}
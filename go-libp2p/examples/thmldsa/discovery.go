@@ -0,0 +1,117 @@
+package thmldsa
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"log"
+	"math/bits"
+	"time"
+
+	"github.com/libp2p/go-libp2p/core/event"
+	"github.com/libp2p/go-libp2p/core/network"
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/libp2p/go-libp2p/core/peerstore"
+	"github.com/libp2p/go-libp2p/p2p/discovery/mdns"
+)
+
+// ID identifies the committee on the local network. It binds the public key,
+// the threshold parameters and the peer of every party.
+func (c *Committee) ID() string {
+	h := sha256.New()
+	h.Write([]byte(ProtocolID))
+	h.Write(c.PK.Bytes())
+	h.Write([]byte{c.Params.T, c.Params.N})
+	for _, info := range c.Peers {
+		h.Write([]byte(info.ID))
+	}
+	return hex.EncodeToString(h.Sum(nil)[:4])
+}
+
+// ServiceName returns the mDNS service under which the members of the
+// committee advertise themselves. Its name stays within the 15 characters
+// allowed by DNS-SD.
+func (c *Committee) ServiceName() string {
+	return "_thm-" + c.ID() + "._udp"
+}
+
+// discoveryTimeout bounds the connection to a discovered member.
+const discoveryTimeout = 10 * time.Second
+
+// Discover advertises the node on the local network under the service of
+// its committee and connects to the other members found there, so that the
+// committee list only needs the peer ID of every party. The peer ID
+// advertised by a node gives its party ID through the committee list, and
+// the security handshake checks it against the public key of the peer:
+// peers that are not members of the committee are ignored.
+//
+// Discovery stops when the returned io.Closer is closed.
+func (n *Node) Discover() (io.Closer, error) {
+	s := mdns.NewMdnsService(n.host, n.committee.ServiceName(), (*discoveryNotifee)(n))
+	if err := s.Start(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+type discoveryNotifee Node
+
+// HandlePeerFound connects to the discovered peer if it is a member of the
+// committee.
+func (d *discoveryNotifee) HandlePeerFound(info peer.AddrInfo) {
+	n := (*Node)(d)
+	// mDNS also reports the node's own advertisement
+	if info.ID == n.host.ID() {
+		return
+	}
+	i, ok := n.committee.PartyOf(info.ID)
+	if !ok {
+		log.Printf("Ignoring peer %s, not a member of committee %s", info.ID, n.committee.ID())
+		return
+	}
+	if n.host.Network().Connectedness(info.ID) == network.Connected {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), discoveryTimeout)
+	defer cancel()
+	n.host.Peerstore().AddAddrs(info.ID, info.Addrs, peerstore.PermanentAddrTTL)
+	if err := n.host.Connect(ctx, info); err != nil {
+		log.Printf("Party %d found but unreachable: %v", i, err)
+	}
+}
+
+// Reachable returns the set of parties connected to the node, including
+// itself.
+func (n *Node) Reachable() uint8 {
+	act := uint8(1) << n.sk.Id
+	for i, info := range n.committee.Peers {
+		if uint8(i) != n.sk.Id && n.host.Network().Connectedness(info.ID) == network.Connected {
+			act |= 1 << i
+		}
+	}
+	return act
+}
+
+// WaitReachable waits until at least T parties, including the node, are
+// reachable, and returns them.
+func (n *Node) WaitReachable(ctx context.Context) (uint8, error) {
+	sub, err := n.host.EventBus().Subscribe(new(event.EvtPeerConnectednessChanged))
+	if err != nil {
+		return 0, err
+	}
+	defer sub.Close()
+
+	for {
+		act := n.Reachable()
+		if bits.OnesCount8(act) >= int(n.committee.Params.T) {
+			return act, nil
+		}
+		select {
+		case <-sub.Out():
+		case <-ctx.Done():
+			return act, ctx.Err()
+		}
+	}
+}
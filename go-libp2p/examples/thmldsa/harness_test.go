@@ -0,0 +1,361 @@
+package thmldsa
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/cloudflare/circl/sign/thmldsa/thmldsa44"
+)
+
+// faults are injected on the frames sent over a link, in that order.
+type faults struct {
+	// Match selects the frames the faults apply to. If nil, they apply to
+	// every frame.
+	Match func(f *frame) bool
+
+	// Delay delays the frames.
+	Delay time.Duration
+
+	// Drop drops the frames.
+	Drop bool
+
+	// Duplicate sends the frames twice.
+	Duplicate bool
+
+	// Reorder holds each frame until the next frame of the link is sent,
+	// or until reorderFlush elapses.
+	Reorder bool
+
+	// Tamper returns the payload sent instead of that of a frame.
+	Tamper func(f *frame) []byte
+}
+
+// reorderFlush bounds how long a reordered frame is held.
+const reorderFlush = 50 * time.Millisecond
+
+type link struct {
+	from, to uint8
+}
+
+// harness runs a committee on a mock network, injecting faults on the links
+// between its nodes.
+type harness struct {
+	t     *testing.T
+	nodes []*Node
+	c     *Committee
+
+	mu     sync.Mutex
+	faults map[link]*faults
+	held   map[link]*frame
+}
+
+func newHarness(t *testing.T, threshold, n uint8) *harness {
+	nodes, c := newCommittee(t, threshold, n)
+	h := &harness{
+		t:      t,
+		nodes:  nodes,
+		c:      c,
+		faults: make(map[link]*faults),
+		held:   make(map[link]*frame),
+	}
+	for _, node := range nodes {
+		node.sendHook = h.hook(node)
+	}
+	return h
+}
+
+// inject sets the faults of the link from party from to party to.
+func (h *harness) inject(from, to uint8, f *faults) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.faults[link{from, to}] = f
+}
+
+// injectFrom sets the faults of every link from party from.
+func (h *harness) injectFrom(from uint8, f *faults) {
+	for to := range h.nodes {
+		if uint8(to) != from {
+			h.inject(from, uint8(to), f)
+		}
+	}
+}
+
+func (h *harness) hook(node *Node) func(ctx context.Context, to uint8, f *frame) error {
+	return func(ctx context.Context, to uint8, f *frame) error {
+		l := link{node.ID(), to}
+		h.mu.Lock()
+		fl := h.faults[l]
+		h.mu.Unlock()
+		if fl == nil || (fl.Match != nil && !fl.Match(f)) {
+			return h.flushAfter(ctx, node, l, f)
+		}
+
+		if fl.Delay > 0 {
+			select {
+			case <-time.After(fl.Delay):
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+		if fl.Drop {
+			return nil
+		}
+		if fl.Tamper != nil {
+			g := *f
+			g.Payload = fl.Tamper(f)
+			f = &g
+		}
+		if fl.Reorder {
+			h.hold(node, l, f)
+			return nil
+		}
+		if fl.Duplicate {
+			if err := node.write(ctx, to, f); err != nil {
+				return err
+			}
+		}
+		return h.flushAfter(ctx, node, l, f)
+	}
+}
+
+// hold holds f until the next frame of the link is sent.
+func (h *harness) hold(node *Node, l link, f *frame) {
+	h.mu.Lock()
+	prev := h.held[l]
+	h.held[l] = f
+	h.mu.Unlock()
+	if prev != nil {
+		node.write(context.Background(), l.to, prev)
+	}
+	time.AfterFunc(reorderFlush, func() {
+		h.mu.Lock()
+		if h.held[l] != f {
+			h.mu.Unlock()
+			return
+		}
+		delete(h.held, l)
+		h.mu.Unlock()
+		node.write(context.Background(), l.to, f)
+	})
+}
+
+// flushAfter writes f, then the frame held on the link, if any.
+func (h *harness) flushAfter(ctx context.Context, node *Node, l link, f *frame) error {
+	err := node.write(ctx, l.to, f)
+	h.mu.Lock()
+	prev := h.held[l]
+	delete(h.held, l)
+	h.mu.Unlock()
+	if prev != nil {
+		node.write(ctx, l.to, prev)
+	}
+	return err
+}
+
+// outcome is the result of a session for every signer.
+type outcome struct {
+	sigs map[uint8][]byte
+	errs map[uint8]error
+}
+
+// sign runs a session proposed by the first member of act, which gives up
+// after timeout, and collects the outcome of every signer.
+func (h *harness) sign(act uint8, msg []byte, timeout time.Duration) *outcome {
+	h.t.Helper()
+
+	type result struct {
+		party uint8
+		r     *Result
+	}
+	results := make(chan result, len(h.nodes))
+	for _, node := range h.nodes {
+		node.OnSession = func(r *Result) { results <- result{node.ID(), r} }
+		node.SessionTimeout = 2 * timeout
+	}
+
+	ids := members(act)
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	sig, _, err := h.nodes[ids[0]].Sign(ctx, act, msg, nil)
+
+	o := &outcome{
+		sigs: map[uint8][]byte{ids[0]: sig},
+		errs: map[uint8]error{ids[0]: err},
+	}
+	for range ids[1:] {
+		select {
+		case r := <-results:
+			o.sigs[r.party], o.errs[r.party] = r.r.Signature, r.r.Err
+		case <-time.After(4 * timeout):
+			// A signer that never got the proposal doesn't report
+			return o
+		}
+	}
+	return o
+}
+
+// requireSigned checks that every signer of act produced the same valid
+// signature of msg.
+func (h *harness) requireSigned(o *outcome, act uint8, msg []byte) {
+	h.t.Helper()
+	ids := members(act)
+	for _, id := range ids {
+		if err, ok := o.errs[id]; !ok || err != nil {
+			h.t.Fatalf("party %d: %v", id, err)
+		}
+		if string(o.sigs[id]) != string(o.sigs[ids[0]]) {
+			h.t.Fatalf("party %d got another signature", id)
+		}
+	}
+	if !thmldsa44.Verify(h.c.PK, msg, nil, o.sigs[ids[0]]) {
+		h.t.Fatal("invalid signature")
+	}
+}
+
+// requireAborted checks that the session failed for every party of
+// parties, either upon detecting the fault itself, with an error matching
+// want, or upon the abort of another party giving reason as the reason.
+func (h *harness) requireAborted(o *outcome, parties []uint8, want func(error) bool, reason string) {
+	h.t.Helper()
+	for _, id := range parties {
+		err, ok := o.errs[id]
+		if !ok || err == nil {
+			h.t.Fatalf("party %d: session didn't fail", id)
+		}
+		var abort *AbortError
+		if errors.As(err, &abort) {
+			if !strings.Contains(abort.Reason, reason) {
+				h.t.Fatalf("party %d: aborted for %q instead of %q", id, abort.Reason, reason)
+			}
+			continue
+		}
+		if !want(err) || !strings.Contains(err.Error(), reason) {
+			h.t.Fatalf("party %d: %v", id, err)
+		}
+	}
+}
+
+// isFault returns a matcher of the FaultError of the given party and round.
+func isFault(party uint8, round int, err error) func(error) bool {
+	return func(e error) bool {
+		var fault *FaultError
+		return errors.As(e, &fault) && fault.Party == party && fault.Round == round && errors.Is(e, err)
+	}
+}
+
+func roundFrame(round uint8) func(f *frame) bool {
+	return func(f *frame) bool {
+		return f.Type == frameRound && f.Round == round
+	}
+}
+
+func TestFaultsTolerated(t *testing.T) {
+	const act = 0b01011
+	for _, tc := range []struct {
+		name   string
+		faults *faults
+	}{
+		{"delays", &faults{Delay: 20 * time.Millisecond}},
+		{"duplicates", &faults{Duplicate: true}},
+		{"reordering", &faults{Reorder: true}},
+		{"reordered duplicates", &faults{Duplicate: true, Reorder: true}},
+		{"delayed proposal", &faults{Match: func(f *frame) bool { return f.Type == framePropose }, Delay: 100 * time.Millisecond}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			h := newHarness(t, 3, 5)
+			for _, id := range members(act) {
+				h.injectFrom(id, tc.faults)
+			}
+			msg := []byte(tc.name)
+			h.requireSigned(h.sign(act, msg, 10*time.Second), act, msg)
+		})
+	}
+}
+
+func TestFaultsAbort(t *testing.T) {
+	const act = 0b00111
+	timeout := time.Second
+	for _, tc := range []struct {
+		name    string
+		inject  func(h *harness)
+		culprit int // party not checked, as it is told about the abort
+		want    func(error) bool
+		reason  string
+	}{
+		{
+			name: "dropped round 2",
+			inject: func(h *harness) {
+				h.inject(1, 0, &faults{Match: roundFrame(2), Drop: true})
+			},
+			culprit: -1,
+			want:    func(err error) bool { return errors.Is(err, context.DeadlineExceeded) },
+			reason:  "waiting for party 1 in round 2",
+		},
+		{
+			name: "short round 1 message",
+			inject: func(h *harness) {
+				h.injectFrom(2, &faults{Match: roundFrame(1), Tamper: func(f *frame) []byte { return f.Payload[:10] }})
+			},
+			culprit: 2,
+			want:    isFault(2, 1, ErrBadMessage),
+			reason:  "party 2, round 1",
+		},
+		{
+			name: "oversized round 3 message",
+			inject: func(h *harness) {
+				h.injectFrom(1, &faults{Match: roundFrame(3), Tamper: func(f *frame) []byte { return append(f.Payload, 0) }})
+			},
+			culprit: 1,
+			want:    isFault(1, 3, ErrBadMessage),
+			reason:  "party 1, round 3",
+		},
+		{
+			name: "wrong commitment",
+			inject: func(h *harness) {
+				h.injectFrom(2, &faults{Match: roundFrame(2), Tamper: func(f *frame) []byte {
+					p := append([]byte(nil), f.Payload...)
+					p[0] ^= 1
+					return p
+				}})
+			},
+			culprit: 2,
+			want:    isFault(2, 2, thmldsa44.ErrWrongCommitment),
+			reason:  "party 2, round 2",
+		},
+		{
+			name: "wrong response",
+			inject: func(h *harness) {
+				h.injectFrom(1, &faults{Match: roundFrame(3), Tamper: func(f *frame) []byte {
+					p := append([]byte(nil), f.Payload...)
+					for i := range p {
+						p[i] ^= 0xff
+					}
+					return p
+				}})
+			},
+			culprit: 1,
+			// Responses cannot be checked one by one. The other signers
+			// fail every attempt, while party 1 combines its own response,
+			// signs and leaves them waiting for the next attempt.
+			want:   func(err error) bool { return errors.Is(err, context.DeadlineExceeded) },
+			reason: "waiting for party 1 in round 1",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			h := newHarness(t, 3, 5)
+			tc.inject(h)
+			o := h.sign(act, []byte(tc.name), timeout)
+			var honest []uint8
+			for _, id := range members(act) {
+				if int(id) != tc.culprit {
+					honest = append(honest, id)
+				}
+			}
+			h.requireAborted(o, honest, tc.want, tc.reason)
+		})
+	}
+}
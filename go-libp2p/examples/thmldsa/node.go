@@ -0,0 +1,603 @@
+// Package thmldsa runs threshold ML-DSA-44 signing sessions among a
+// committee of libp2p peers, each holding one key share.
+//
+// Any member can propose a session for a signer set of T members. The other
+// signers join it once their Approve policy accepts it, passing the proposal
+// on to each other, as messages of unproposed sessions or from parties
+// outside the signer set are dropped. Then all of them run the three rounds
+// of thmldsa44 over ProtocolID streams until one attempt yields a signature.
+// Every party combines the responses itself, so all of them end up with the
+// signature.
+package thmldsa
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/cloudflare/circl/sign/thmldsa/thmldsa44"
+	"github.com/libp2p/go-libp2p/core/crypto"
+	"github.com/libp2p/go-libp2p/core/host"
+	"github.com/libp2p/go-libp2p/core/network"
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/libp2p/go-libp2p/core/peerstore"
+	"github.com/libp2p/go-libp2p/core/record"
+)
+
+// Committee is the set of peers holding the shares of a threshold key.
+type Committee struct {
+	PK     *thmldsa44.PublicKey
+	Params *thmldsa44.ThresholdParams
+
+	// Peers[i] is the peer holding the key share of party i.
+	Peers []peer.AddrInfo
+}
+
+// PartyOf returns the party ID of peer p.
+func (c *Committee) PartyOf(p peer.ID) (uint8, bool) {
+	for i, info := range c.Peers {
+		if info.ID == p {
+			return uint8(i), true
+		}
+	}
+	return 0, false
+}
+
+// PubKey returns the combined public key of the committee as a libp2p key.
+func (c *Committee) PubKey() (crypto.PubKey, error) {
+	return crypto.UnmarshalMLDSA44PublicKey(c.PK.Bytes())
+}
+
+// PeerID returns the peer ID of the committee acting as a single peer, that
+// of its combined public key.
+func (c *Committee) PeerID() (peer.ID, error) {
+	pub, err := c.PubKey()
+	if err != nil {
+		return "", err
+	}
+	return peer.IDFromPublicKey(pub)
+}
+
+// Result is the outcome of a signing session.
+type Result struct {
+	Session   [32]byte
+	Act       uint8
+	Msg, Ctx  []byte
+	Signature []byte
+	Attempts  int
+	Err       error
+}
+
+type mailboxKey struct {
+	attempt uint32
+	round   uint8
+	from    uint8
+}
+
+type session struct {
+	id      [32]byte
+	act     uint8
+	expires time.Time
+
+	mu      sync.Mutex
+	started bool
+	done    bool
+	mailbox map[mailboxKey]chan []byte
+
+	aborted  chan struct{}
+	abortErr error
+}
+
+// slot returns the channel receiving the given message. Once the session is
+// over, messages go to a channel nobody reads.
+func (s *session) slot(k mailboxKey) chan []byte {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.mailbox == nil {
+		return make(chan []byte, 1)
+	}
+	ch, ok := s.mailbox[k]
+	if !ok {
+		ch = make(chan []byte, 1)
+		s.mailbox[k] = ch
+	}
+	return ch
+}
+
+// deliver stores a message, ignoring duplicates.
+func (s *session) deliver(k mailboxKey, msg []byte) {
+	ch := s.slot(k)
+	select {
+	case ch <- msg:
+	default:
+	}
+}
+
+func (s *session) abort(err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.abortErr == nil {
+		s.abortErr = err
+		close(s.aborted)
+	}
+}
+
+// Maximum number of proposed sessions for which messages are buffered
+// before the local node joined them
+const maxPendingSessions = 64
+
+// Node is the member of a committee running on a libp2p host.
+type Node struct {
+	host      host.Host
+	committee *Committee
+	sk        *thmldsa44.PrivateKey
+
+	// Approve decides whether to join a session proposed by party from, the
+	// member from which the proposal was first received. If nil, every
+	// session is joined. It may block, up to SessionTimeout.
+	Approve func(from, act uint8, msg, ctx []byte) bool
+
+	// OnSession, if not nil, is called with the outcome of the sessions
+	// joined upon a proposal.
+	OnSession func(*Result)
+
+	// SessionTimeout bounds the duration of the sessions joined upon a
+	// proposal, and how long messages of unknown sessions are kept.
+	SessionTimeout time.Duration
+
+	// MaxAttempts bounds the number of attempts of a session.
+	MaxAttempts int
+
+	mu       sync.Mutex
+	sessions map[[32]byte]*session
+	streams  map[uint8]*outStream
+
+	// sendHook, if not nil, sends the frames in place of write. Tests set it
+	// to inject faults on the links of the node.
+	sendHook func(ctx context.Context, to uint8, f *frame) error
+}
+
+type outStream struct {
+	mu sync.Mutex
+	s  network.Stream
+}
+
+// NewNode returns the node of party sk.Id of the committee on host h, and
+// registers its stream handler.
+func NewNode(h host.Host, c *Committee, sk *thmldsa44.PrivateKey) (*Node, error) {
+	if int(sk.Id) >= len(c.Peers) || c.Peers[sk.Id].ID != h.ID() {
+		return nil, fmt.Errorf("host is not party %d of the committee", sk.Id)
+	}
+	if len(c.Peers) != int(c.Params.N) {
+		return nil, fmt.Errorf("committee of %d peers instead of %d", len(c.Peers), c.Params.N)
+	}
+
+	n := &Node{
+		host:           h,
+		committee:      c,
+		sk:             sk,
+		SessionTimeout: time.Minute,
+		MaxAttempts:    570,
+		sessions:       make(map[[32]byte]*session),
+		streams:        make(map[uint8]*outStream),
+	}
+	for i, info := range c.Peers {
+		if uint8(i) != sk.Id {
+			h.Peerstore().AddAddrs(info.ID, info.Addrs, peerstore.PermanentAddrTTL)
+		}
+	}
+	h.SetStreamHandler(ProtocolID, n.handleStream)
+	return n, nil
+}
+
+// ID returns the party ID of the node.
+func (n *Node) ID() uint8 {
+	return n.sk.Id
+}
+
+// Connect connects to every other member of the committee.
+func (n *Node) Connect(ctx context.Context) error {
+	var errs []error
+	for i, info := range n.committee.Peers {
+		if uint8(i) == n.sk.Id {
+			continue
+		}
+		if err := n.host.Connect(ctx, info); err != nil {
+			errs = append(errs, fmt.Errorf("party %d: %w", i, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Close removes the stream handler and closes the outgoing streams.
+func (n *Node) Close() error {
+	n.host.RemoveStreamHandler(ProtocolID)
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	for _, out := range n.streams {
+		out.s.Close()
+	}
+	n.streams = make(map[uint8]*outStream)
+	return nil
+}
+
+// session returns the session with the given ID, creating it for the signer
+// set act if create is set, which is only done for checked proposals. It
+// returns nil for sessions that are over, or when too many sessions are
+// pending.
+func (n *Node) session(id [32]byte, act uint8, create bool) *session {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	now := time.Now()
+	if s, ok := n.sessions[id]; ok {
+		if s.done {
+			return nil
+		}
+		return s
+	}
+	if !create {
+		return nil
+	}
+
+	// Forget expired sessions
+	for sid, s := range n.sessions {
+		if now.After(s.expires) {
+			delete(n.sessions, sid)
+		}
+	}
+	if len(n.sessions) >= maxPendingSessions {
+		return nil
+	}
+
+	s := &session{
+		id:      id,
+		act:     act,
+		expires: now.Add(n.SessionTimeout),
+		mailbox: make(map[mailboxKey]chan []byte),
+		aborted: make(chan struct{}),
+	}
+	n.sessions[id] = s
+	return s
+}
+
+// finish marks a session as over, dropping its buffered messages.
+func (n *Node) finish(s *session) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	s.done = true
+	s.expires = time.Now().Add(n.SessionTimeout)
+
+	s.mu.Lock()
+	s.mailbox = nil
+	s.mu.Unlock()
+}
+
+// send sends f to party to.
+func (n *Node) send(ctx context.Context, to uint8, f *frame) error {
+	if n.sendHook != nil {
+		return n.sendHook(ctx, to, f)
+	}
+	return n.write(ctx, to, f)
+}
+
+// write writes f to party to, opening a stream if needed.
+func (n *Node) write(ctx context.Context, to uint8, f *frame) error {
+	n.mu.Lock()
+	out, ok := n.streams[to]
+	if !ok {
+		out = &outStream{}
+		n.streams[to] = out
+	}
+	n.mu.Unlock()
+
+	out.mu.Lock()
+	defer out.mu.Unlock()
+	for retry := 0; ; retry++ {
+		if out.s == nil {
+			s, err := n.host.NewStream(ctx, n.committee.Peers[to].ID, ProtocolID)
+			if err != nil {
+				return fmt.Errorf("party %d: %w", to, err)
+			}
+			out.s = s
+		}
+		err := writeFrame(out.s, f)
+		if err == nil {
+			return nil
+		}
+
+		// The stream may have been reset by the other side: open a new one
+		out.s.Reset()
+		out.s = nil
+		if retry > 0 {
+			return fmt.Errorf("party %d: %w", to, err)
+		}
+	}
+}
+
+// broadcast sends f to every member of act but the local node.
+func (n *Node) broadcast(ctx context.Context, act uint8, f *frame) error {
+	var errs []error
+	for _, to := range members(act) {
+		if to != n.sk.Id {
+			if err := n.send(ctx, to, f); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (n *Node) handleStream(s network.Stream) {
+	defer s.Close()
+
+	from, ok := n.committee.PartyOf(s.Conn().RemotePeer())
+	if !ok {
+		s.Reset()
+		return
+	}
+
+	for {
+		f, err := readFrame(s)
+		if err != nil {
+			return
+		}
+		if f.From != from {
+			// Messages are authenticated by the peer ID of the stream
+			log.Printf("party %d sent a message as party %d", from, f.From)
+			s.Reset()
+			return
+		}
+
+		switch f.Type {
+		case framePropose:
+			// The session is created before reading the next frame, which
+			// may belong to it. Approve may take a while, for instance
+			// waiting for an operator: the messages of the session are
+			// buffered meanwhile.
+			if sess, p := n.handleProposal(from, f); sess != nil {
+				go n.join(from, sess, p)
+			}
+
+		case frameRound:
+			if f.Round < 1 || f.Round > 3 {
+				continue
+			}
+			if sess := n.session(f.Session, 0, false); sess != nil && sess.act&(1<<from) != 0 {
+				sess.deliver(mailboxKey{f.Attempt, f.Round, from}, f.Payload)
+			}
+
+		case frameAbort:
+			if sess := n.session(f.Session, 0, false); sess != nil && sess.act&(1<<from) != 0 {
+				sess.abort(&AbortError{from, string(f.Payload)})
+			}
+		}
+	}
+}
+
+// handleProposal checks the proposal in f and returns its session, or nil
+// if the proposal is invalid or its session was already proposed.
+func (n *Node) handleProposal(from uint8, f *frame) (*session, *proposal) {
+	p := new(proposal)
+	if p.unmarshal(f.Payload) != nil || p.id(n.committee.PK) != f.Session {
+		return nil, nil
+	}
+	if p.Act&(1<<from) == 0 || n.checkSignerSet(p.Act) != nil {
+		return nil, nil
+	}
+
+	sess := n.session(f.Session, p.Act, true)
+	if sess == nil {
+		return nil, nil
+	}
+	sess.mu.Lock()
+	started := sess.started
+	sess.started = true
+	sess.mu.Unlock()
+	if started {
+		return nil, nil
+	}
+	return sess, p
+}
+
+// join runs the session proposed by party from once Approve accepts it.
+func (n *Node) join(from uint8, sess *session, p *proposal) {
+	if n.Approve != nil && !n.Approve(from, p.Act, p.Msg, p.Ctx) {
+		n.finish(sess)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), n.SessionTimeout)
+	defer cancel()
+
+	// Frames of unknown sessions are dropped, so the proposal is passed on
+	// ahead of the messages of the local node to the signers which may not
+	// have received it yet.
+	f := &frame{Type: framePropose, Session: sess.id, From: n.sk.Id, Payload: p.marshal()}
+	_ = n.broadcast(ctx, p.Act&^(1<<from), f)
+
+	sig, attempts, err := n.run(ctx, sess, p)
+	if n.OnSession != nil {
+		n.OnSession(&Result{sess.id, p.Act, p.Msg, p.Ctx, sig, attempts, err})
+	}
+}
+
+// members returns the IDs of the parties of act in increasing order.
+func members(act uint8) []uint8 {
+	var ids []uint8
+	for i := uint8(0); i < 8; i++ {
+		if act&(1<<i) != 0 {
+			ids = append(ids, i)
+		}
+	}
+	return ids
+}
+
+func (n *Node) checkSignerSet(act uint8) error {
+	ids := members(act)
+	if len(ids) != int(n.committee.Params.T) || ids[len(ids)-1] >= n.committee.Params.N || act&(1<<n.sk.Id) == 0 {
+		return ErrSignerSet
+	}
+	return nil
+}
+
+// Sign proposes a session to the other members of the signer set act, a
+// bitmask of T party IDs including the local node, and runs it. It returns
+// the signature of msg under context mctx and the number of attempts.
+func (n *Node) Sign(ctx context.Context, act uint8, msg, mctx []byte) ([]byte, int, error) {
+	if err := n.checkSignerSet(act); err != nil {
+		return nil, 0, err
+	}
+	if len(mctx) > 255 {
+		return nil, 0, errors.New("context longer than 255 bytes")
+	}
+
+	p := &proposal{Act: act, Ctx: mctx, Msg: msg}
+	if _, err := rand.Read(p.Nonce[:]); err != nil {
+		return nil, 0, err
+	}
+	id := p.id(n.committee.PK)
+	sess := n.session(id, act, true)
+	if sess == nil {
+		return nil, 0, errors.New("too many pending sessions")
+	}
+	sess.mu.Lock()
+	sess.started = true
+	sess.mu.Unlock()
+
+	f := &frame{Type: framePropose, Session: id, From: n.sk.Id, Payload: p.marshal()}
+	if err := n.broadcast(ctx, act, f); err != nil {
+		n.finish(sess)
+		return nil, 0, err
+	}
+	return n.run(ctx, sess, p)
+}
+
+// Signer returns a ThresholdSigner running sessions with the signer set act
+// under ctx. With crypto.NewThresholdMLDSA44PrivateKey, it lets the committee
+// sign as a single peer identity, that of its combined public key.
+func (n *Node) Signer(ctx context.Context, act uint8) crypto.ThresholdSigner {
+	return crypto.ThresholdSignerFunc(func(msg []byte) ([]byte, error) {
+		sig, _, err := n.Sign(ctx, act, msg, nil)
+		return sig, err
+	})
+}
+
+// SealRecord runs a session with the signer set act over the signing payload
+// of rec, and returns rec sealed in an Envelope with the threshold signature.
+// Consumers verify it with the combined public key of the committee, which no
+// single member can sign with.
+func (n *Node) SealRecord(ctx context.Context, act uint8, rec record.Record) (*record.Envelope, error) {
+	unsigned, err := record.SigningPayload(rec)
+	if err != nil {
+		return nil, err
+	}
+	sig, _, err := n.Sign(ctx, act, unsigned, nil)
+	if err != nil {
+		return nil, err
+	}
+	pub, err := n.committee.PubKey()
+	if err != nil {
+		return nil, err
+	}
+	return record.SealWithSignature(rec, pub, sig)
+}
+
+// exchange broadcasts msg for the round and returns the messages of every
+// member of act in member order.
+func (n *Node) exchange(ctx context.Context, sess *session, act uint8, attempt uint32, round uint8, msg []byte, size int) ([][]byte, error) {
+	f := &frame{Type: frameRound, Session: sess.id, Attempt: attempt, Round: round, From: n.sk.Id, Payload: msg}
+	if err := n.broadcast(ctx, act, f); err != nil {
+		return nil, err
+	}
+
+	ids := members(act)
+	msgs := make([][]byte, len(ids))
+	for j, from := range ids {
+		if from == n.sk.Id {
+			msgs[j] = msg
+			continue
+		}
+
+		select {
+		case m := <-sess.slot(mailboxKey{attempt, round, from}):
+			if len(m) != size {
+				return nil, &FaultError{from, int(round), fmt.Errorf("%w: %d bytes instead of %d", ErrBadMessage, len(m), size)}
+			}
+			msgs[j] = m
+		case <-sess.aborted:
+			return nil, sess.abortErr
+		case <-ctx.Done():
+			return nil, fmt.Errorf("waiting for party %d in round %d: %w", from, round, ctx.Err())
+		}
+	}
+	return msgs, nil
+}
+
+// run runs the session until a signature is produced. On failure, the
+// other signers are told to abort.
+func (n *Node) run(ctx context.Context, sess *session, p *proposal) ([]byte, int, error) {
+	defer n.finish(sess)
+
+	sig, attempts, err := n.attempts(ctx, sess, p)
+	if err != nil {
+		var abort *AbortError
+		if !errors.As(err, &abort) {
+			f := &frame{Type: frameAbort, Session: sess.id, From: n.sk.Id, Payload: []byte(err.Error())}
+			actx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			_ = n.broadcast(actx, p.Act, f)
+			cancel()
+		}
+		return nil, attempts, err
+	}
+	return sig, attempts, nil
+}
+
+func (n *Node) attempts(ctx context.Context, sess *session, p *proposal) ([]byte, int, error) {
+	params := n.committee.Params
+	ids := members(p.Act)
+	sig := make([]byte, thmldsa44.SignatureSize)
+
+	for attempt := uint32(0); attempt < uint32(n.MaxAttempts); attempt++ {
+		msg1, st1, err := thmldsa44.Round1(n.sk, params)
+		if err != nil {
+			return nil, int(attempt), err
+		}
+		msgs1, err := n.exchange(ctx, sess, p.Act, attempt, 1, msg1, len(msg1))
+		if err != nil {
+			return nil, int(attempt), err
+		}
+
+		msg2, st2, err := thmldsa44.Round2(n.sk, p.Act, p.Msg, p.Ctx, msgs1, &st1, params)
+		if err != nil {
+			return nil, int(attempt), err
+		}
+		msgs2, err := n.exchange(ctx, sess, p.Act, attempt, 2, msg2, params.CommitmentSize())
+		if err != nil {
+			return nil, int(attempt), err
+		}
+		for j, id := range ids {
+			if !thmldsa44.CheckCommitment(n.committee.PK, id, msgs1[j], msgs2[j]) {
+				return nil, int(attempt), &FaultError{id, 2, thmldsa44.ErrWrongCommitment}
+			}
+		}
+
+		msg3, err := thmldsa44.Round3(n.sk, msgs2, &st1, &st2, params)
+		if err != nil {
+			return nil, int(attempt), err
+		}
+		msgs3, err := n.exchange(ctx, sess, p.Act, attempt, 3, msg3, params.ResponseSize())
+		if err != nil {
+			return nil, int(attempt), err
+		}
+
+		if thmldsa44.Combine(n.committee.PK, p.Msg, p.Ctx, msgs2, msgs3, sig, params) {
+			return sig, int(attempt) + 1, nil
+		}
+	}
+	return nil, n.MaxAttempts, ErrTooManyAttempts
+}
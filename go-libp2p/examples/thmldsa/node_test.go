@@ -0,0 +1,302 @@
+package thmldsa
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"log"
+	"math/bits"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cloudflare/circl/sign/thmldsa/thmldsa44"
+	"github.com/libp2p/go-libp2p/core/crypto"
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/libp2p/go-libp2p/core/record"
+	mocknet "github.com/libp2p/go-libp2p/p2p/net/mock"
+)
+
+// newCommittee returns the nodes of a T-of-N committee on a mock network.
+func newCommittee(t *testing.T, threshold, n uint8) ([]*Node, *Committee) {
+	t.Helper()
+
+	params, err := thmldsa44.GetThresholdParams(threshold, n)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var seed [32]byte
+	binary.LittleEndian.PutUint64(seed[:], uint64(n))
+	pk, sks := thmldsa44.NewThresholdKeysFromSeed(&seed, params)
+
+	mn, err := mocknet.FullMeshLinked(int(n))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { mn.Close() })
+
+	c := &Committee{PK: pk, Params: params}
+	for _, h := range mn.Hosts() {
+		c.Peers = append(c.Peers, peer.AddrInfo{ID: h.ID(), Addrs: h.Addrs()})
+	}
+
+	nodes := make([]*Node, n)
+	for i, h := range mn.Hosts() {
+		nodes[i], err = NewNode(h, c, &sks[i])
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	return nodes, c
+}
+
+func TestSignSubsets(t *testing.T) {
+	nodes, c := newCommittee(t, 3, 5)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	if err := nodes[0].Connect(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	results := make(chan *Result, len(nodes))
+	for _, n := range nodes {
+		n.OnSession = func(r *Result) { results <- r }
+	}
+
+	for _, act := range []uint8{0b00111, 0b10101, 0b11010} {
+		msg := []byte{act}
+		proposer := nodes[members(act)[0]]
+		sig, _, err := proposer.Sign(ctx, act, msg, []byte("test"))
+		if err != nil {
+			t.Fatalf("signer set %05b: %v", act, err)
+		}
+		if !thmldsa44.Verify(c.PK, msg, []byte("test"), sig) {
+			t.Fatalf("signer set %05b: invalid signature", act)
+		}
+
+		// The other signers combine the same signature
+		for i := 0; i < int(c.Params.T)-1; i++ {
+			r := <-results
+			if r.Err != nil {
+				t.Fatalf("signer set %05b: %v", act, r.Err)
+			}
+			if string(r.Signature) != string(sig) || r.Act != act {
+				t.Fatalf("signer set %05b: other signers got another result", act)
+			}
+		}
+	}
+}
+
+func TestSignRejected(t *testing.T) {
+	nodes, _ := newCommittee(t, 2, 3)
+	nodes[1].Approve = func(from, act uint8, msg, ctx []byte) bool {
+		return false
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if _, _, err := nodes[0].Sign(ctx, 0b011, []byte("msg"), nil); err == nil {
+		t.Fatal("signed without the approval of party 1")
+	}
+
+	if _, _, err := nodes[0].Sign(ctx, 0b110, []byte("msg"), nil); err != ErrSignerSet {
+		t.Fatalf("got %v instead of ErrSignerSet", err)
+	}
+}
+
+func TestCommitteeIdentity(t *testing.T) {
+	nodes, c := newCommittee(t, 2, 3)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	pub, err := c.PubKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	priv, err := crypto.NewThresholdMLDSA44PrivateKey(pub, nodes[1].Signer(ctx, 0b110))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	id, err := peer.IDFromPrivateKey(priv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	msg := []byte("signed by the committee")
+	sig, err := priv.Sign(msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ok, err := pub.Verify(msg, sig); err != nil || !ok {
+		t.Fatal("invalid signature")
+	}
+	if !id.MatchesPublicKey(pub) {
+		t.Fatal("peer ID doesn't match the committee key")
+	}
+}
+
+func TestSealPeerRecord(t *testing.T) {
+	nodes, c := newCommittee(t, 2, 3)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	id, err := c.PeerID()
+	if err != nil {
+		t.Fatal(err)
+	}
+	rec := peer.NewPeerRecord()
+	rec.PeerID = id
+	rec.Addrs = nodes[0].host.Addrs()
+
+	env, err := nodes[2].SealRecord(ctx, 0b101, rec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := env.Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pub, err := c.PubKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, r, err := record.ConsumeEnvelopeSignedBy(data, peer.PeerRecordEnvelopeDomain, pub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.(*peer.PeerRecord).PeerID != id {
+		t.Fatal("peer record of another peer")
+	}
+
+	// A record sealed by a single member is rejected
+	forged, err := record.Seal(rec, nodes[0].host.Peerstore().PrivKey(nodes[0].host.ID()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err = forged.Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := record.ConsumeEnvelopeSignedBy(data, peer.PeerRecordEnvelopeDomain, pub); err != record.ErrUnexpectedSigner {
+		t.Fatalf("got %v instead of ErrUnexpectedSigner", err)
+	}
+}
+
+func TestDiscoveredPeers(t *testing.T) {
+	nodes, c := newCommittee(t, 3, 5)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	d := (*discoveryNotifee)(nodes[0])
+	if act := nodes[0].Reachable(); act != 0b00001 {
+		t.Fatalf("reachable parties %05b before discovery", act)
+	}
+
+	// Peers outside the committee are ignored
+	outsider, _, err := crypto.GenerateEd25519Key(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	id, err := peer.IDFromPrivateKey(outsider)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var logged bytes.Buffer
+	log.SetOutput(&logged)
+	d.HandlePeerFound(peer.AddrInfo{ID: id, Addrs: c.Peers[1].Addrs})
+	if act := nodes[0].Reachable(); act != 0b00001 {
+		t.Fatalf("reachable parties %05b after finding an outsider", act)
+	}
+	if !strings.Contains(logged.String(), "not a member") {
+		t.Fatal("outsider not reported")
+	}
+
+	// nor is the node itself, found from its own advertisement
+	logged.Reset()
+	d.HandlePeerFound(c.Peers[0])
+	log.SetOutput(os.Stderr)
+	if logged.Len() != 0 {
+		t.Fatalf("node found itself: %s", logged.String())
+	}
+
+	go func() {
+		d.HandlePeerFound(c.Peers[3])
+		d.HandlePeerFound(c.Peers[4])
+	}()
+	act, err := nodes[0].WaitReachable(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if act&0b00001 == 0 || bits.OnesCount8(act) < 3 {
+		t.Fatalf("reachable parties %05b", act)
+	}
+
+	// The reachable parties can sign
+	msg := []byte("found on the local network")
+	act = 0b11001
+	sig, _, err := nodes[0].Sign(ctx, act, msg, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !thmldsa44.Verify(c.PK, msg, nil, sig) {
+		t.Fatal("invalid signature")
+	}
+}
+
+func TestOutsiderFrames(t *testing.T) {
+	nodes, c := newCommittee(t, 2, 3)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	// Party 2 sends round and abort frames to party 0 for a session nobody
+	// proposed, which must not create it
+	unknown := [32]byte{1}
+	for _, f := range []*frame{
+		{Type: frameRound, Session: unknown, Round: 1, From: 2, Payload: []byte("round")},
+		{Type: frameAbort, Session: unknown, From: 2, Payload: []byte("abort")},
+	} {
+		if err := nodes[2].write(ctx, 0, f); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Then, outside of the signer set 0b011, aborts the session at party 1
+	// and sends it its own round 1 message
+	results := make(chan *Result, 1)
+	nodes[1].OnSession = func(r *Result) { results <- r }
+	nodes[0].sendHook = func(ctx context.Context, to uint8, f *frame) error {
+		if f.Type == frameRound && f.Round == 1 && f.Attempt == 0 {
+			time.Sleep(50 * time.Millisecond)
+			for _, g := range []*frame{
+				{Type: frameRound, Session: f.Session, Round: 1, From: 2, Payload: f.Payload},
+				{Type: frameAbort, Session: f.Session, From: 2, Payload: []byte("abort")},
+			} {
+				if err := nodes[2].write(ctx, to, g); err != nil {
+					return err
+				}
+			}
+		}
+		return nodes[0].write(ctx, to, f)
+	}
+	msg := []byte("message")
+	sig, _, err := nodes[0].Sign(ctx, 0b011, msg, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !thmldsa44.Verify(c.PK, msg, nil, sig) {
+		t.Fatal("invalid signature")
+	}
+	if r := <-results; r.Err != nil {
+		t.Fatalf("party 1: %v", r.Err)
+	}
+
+	nodes[0].mu.Lock()
+	defer nodes[0].mu.Unlock()
+	if _, ok := nodes[0].sessions[unknown]; ok || len(nodes[0].sessions) != 1 {
+		t.Fatalf("%d sessions instead of the signed one", len(nodes[0].sessions))
+	}
+}
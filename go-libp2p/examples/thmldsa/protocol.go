@@ -0,0 +1,165 @@
+package thmldsa
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/cloudflare/circl/sign/thmldsa/thmldsa44"
+	"github.com/libp2p/go-libp2p/core/protocol"
+)
+
+// ProtocolID is the protocol of the streams carrying signing sessions.
+const ProtocolID = protocol.ID("/thmldsa/sign/1.0.0")
+
+// MaxPayloadSize bounds the size of the payload of a frame.
+const MaxPayloadSize = 1 << 20
+
+type frameType uint8
+
+const (
+	// Proposes a session: the payload holds the nonce, the signer set, the
+	// context and the message.
+	framePropose frameType = iota + 1
+
+	// Message of one round of one attempt of a session
+	frameRound
+
+	// Aborts a session: the payload holds the reason.
+	frameAbort
+)
+
+// Every frame starts with its type, session, attempt, round, sender and
+// payload length, followed by the payload.
+const frameHeaderSize = 1 + 32 + 4 + 1 + 1 + 4
+
+type frame struct {
+	Type    frameType
+	Session [32]byte
+	Attempt uint32
+	Round   uint8
+	From    uint8
+	Payload []byte
+}
+
+func writeFrame(w io.Writer, f *frame) error {
+	var hdr [frameHeaderSize]byte
+	hdr[0] = byte(f.Type)
+	copy(hdr[1:33], f.Session[:])
+	binary.BigEndian.PutUint32(hdr[33:], f.Attempt)
+	hdr[37] = f.Round
+	hdr[38] = f.From
+	binary.BigEndian.PutUint32(hdr[39:], uint32(len(f.Payload)))
+	if _, err := w.Write(hdr[:]); err != nil {
+		return err
+	}
+	_, err := w.Write(f.Payload)
+	return err
+}
+
+func readFrame(r io.Reader) (*frame, error) {
+	var hdr [frameHeaderSize]byte
+	if _, err := io.ReadFull(r, hdr[:]); err != nil {
+		return nil, err
+	}
+	size := binary.BigEndian.Uint32(hdr[39:])
+	if size > MaxPayloadSize {
+		return nil, fmt.Errorf("%w: payload of %d bytes", ErrBadMessage, size)
+	}
+
+	f := &frame{
+		Type:    frameType(hdr[0]),
+		Attempt: binary.BigEndian.Uint32(hdr[33:]),
+		Round:   hdr[37],
+		From:    hdr[38],
+		Payload: make([]byte, size),
+	}
+	copy(f.Session[:], hdr[1:33])
+	if _, err := io.ReadFull(r, f.Payload); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// proposal is the payload of a framePropose.
+type proposal struct {
+	Nonce [16]byte
+	Act   uint8
+	Ctx   []byte
+	Msg   []byte
+}
+
+func (p *proposal) marshal() []byte {
+	buf := make([]byte, 0, 16+2+len(p.Ctx)+len(p.Msg))
+	buf = append(buf, p.Nonce[:]...)
+	buf = append(buf, p.Act, byte(len(p.Ctx)))
+	buf = append(buf, p.Ctx...)
+	return append(buf, p.Msg...)
+}
+
+func (p *proposal) unmarshal(buf []byte) error {
+	if len(buf) < 18 || len(buf) < 18+int(buf[17]) {
+		return ErrBadMessage
+	}
+	copy(p.Nonce[:], buf[:16])
+	p.Act = buf[16]
+	p.Ctx = buf[18 : 18+int(buf[17])]
+	p.Msg = buf[18+int(buf[17]):]
+	return nil
+}
+
+// id returns the identifier of the session, which binds the public key of
+// the committee, the signer set, the context and the message.
+func (p *proposal) id(pk *thmldsa44.PublicKey) [32]byte {
+	h := sha256.New()
+	h.Write([]byte(ProtocolID))
+	h.Write(pk.Bytes())
+	h.Write(p.marshal())
+	var id [32]byte
+	h.Sum(id[:0])
+	return id
+}
+
+var (
+	// ErrBadMessage is returned when a peer sends a malformed message.
+	ErrBadMessage = errors.New("malformed message")
+
+	// ErrNotMember is returned when a peer is not a member of the committee.
+	ErrNotMember = errors.New("peer is not a member of the committee")
+
+	// ErrSignerSet is returned when a signer set does not hold exactly T
+	// members of the committee, including the local node.
+	ErrSignerSet = errors.New("signer set must hold exactly T members including this node")
+
+	// ErrTooManyAttempts is returned when no signature was produced within
+	// the maximum number of attempts.
+	ErrTooManyAttempts = errors.New("too many signing attempts")
+)
+
+// FaultError is returned when a party of the session misbehaved. The session
+// is then aborted for every party.
+type FaultError struct {
+	Party uint8
+	Round int
+	Err   error
+}
+
+func (e *FaultError) Error() string {
+	return fmt.Sprintf("party %d, round %d: %v", e.Party, e.Round, e.Err)
+}
+
+func (e *FaultError) Unwrap() error {
+	return e.Err
+}
+
+// AbortError is returned when another party aborted the session.
+type AbortError struct {
+	Party  uint8
+	Reason string
+}
+
+func (e *AbortError) Error() string {
+	return fmt.Sprintf("session aborted by party %d: %s", e.Party, e.Reason)
+}
@@ -13,6 +13,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/cloudflare/circl/sign/mldsa/mldsa44"
 	logging "github.com/ipfs/go-log/v2"
 	"github.com/libp2p/go-libp2p/core/crypto"
 	"github.com/libp2p/go-libp2p/core/peer"
@@ -52,6 +53,27 @@ func TestMutualAuth(t *testing.T) {
 				return clientKey
 			},
 		},
+		{
+			name: "MLDSA87",
+			clientKeyGen: func(t *testing.T) crypto.PrivKey {
+				t.Helper()
+				clientKey, _, err := crypto.GenerateMLDSAKey(crypto.MLDSA87, rand.Reader)
+				require.NoError(t, err)
+				return clientKey
+			},
+		},
+		{
+			name: "threshold MLDSA44",
+			clientKeyGen: func(t *testing.T) crypto.PrivKey {
+				t.Helper()
+				// A plain ML-DSA-44 key stands in for the committee's signer
+				_, sk, err := mldsa44.GenerateKey(rand.Reader)
+				require.NoError(t, err)
+				clientKey, err := crypto.ThresholdMLDSA44KeyFromSigner(sk)
+				require.NoError(t, err)
+				return clientKey
+			},
+		},
 	}
 
 	type serverTestCase struct {
@@ -286,3 +308,40 @@ func TestConcurrentAuth(t *testing.T) {
 	}
 	wg.Wait()
 }
+
+func TestMLDSAServer(t *testing.T) {
+	_, sk, err := mldsa44.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	serverKey, err := crypto.ThresholdMLDSA44KeyFromSigner(sk)
+	require.NoError(t, err)
+	expectedServerID, err := peer.IDFromPrivateKey(serverKey)
+	require.NoError(t, err)
+
+	clientKey, _, err := crypto.GenerateMLDSAKey(crypto.MLDSA65, rand.Reader)
+	require.NoError(t, err)
+	expectedClientID, err := peer.IDFromPrivateKey(clientKey)
+	require.NoError(t, err)
+
+	auth := ServerPeerIDAuth{
+		PrivKey: serverKey,
+		ValidHostnameFn: func(s string) bool {
+			return s == "example.com"
+		},
+		TokenTTL: time.Hour,
+		NoTLS:    true,
+		Next: func(peer peer.ID, w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, expectedClientID, peer)
+		},
+	}
+	ts := httptest.NewServer(&auth)
+	t.Cleanup(ts.Close)
+
+	clientAuth := ClientPeerIDAuth{PrivKey: clientKey}
+	req, err := http.NewRequest("POST", ts.URL, nil)
+	require.NoError(t, err)
+	req.Host = "example.com"
+	serverID, resp, err := clientAuth.AuthenticatedDo(ts.Client(), req)
+	require.NoError(t, err)
+	require.Equal(t, expectedServerID, serverID)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+}
@@ -19,7 +19,10 @@ import (
 
 const PeerIDAuthScheme = "libp2p-PeerID"
 const challengeLen = 32
-const maxHeaderSize = 2048
+
+// maxHeaderSize bounds the size of a header value. It leaves room for the
+// public keys and signatures of ML-DSA-87, the largest key type.
+const maxHeaderSize = 16 << 10
 
 var peerIDAuthSchemeBytes = []byte(PeerIDAuthScheme)
 
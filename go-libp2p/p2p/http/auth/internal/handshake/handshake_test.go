@@ -15,16 +15,28 @@ import (
 	"time"
 
 	"github.com/libp2p/go-libp2p/core/crypto"
+	pb "github.com/libp2p/go-libp2p/core/crypto/pb"
 	"github.com/libp2p/go-libp2p/core/peer"
 	"github.com/stretchr/testify/require"
 )
 
 func TestHandshake(t *testing.T) {
-	for _, clientInitiated := range []bool{true, false} {
-		t.Run(fmt.Sprintf("clientInitiated=%t", clientInitiated), func(t *testing.T) {
+	for _, tc := range []struct {
+		clientInitiated bool
+		keyType         int
+	}{
+		{true, crypto.Ed25519},
+		{false, crypto.Ed25519},
+		{true, crypto.MLDSA44},
+		{false, crypto.MLDSA44},
+		{true, crypto.MLDSA87},
+		{false, crypto.MLDSA87},
+	} {
+		clientInitiated := tc.clientInitiated
+		t.Run(fmt.Sprintf("clientInitiated=%t/%s", clientInitiated, pb.KeyType(tc.keyType)), func(t *testing.T) {
 			hostname := "example.com"
-			serverPriv, _, _ := crypto.GenerateEd25519Key(rand.Reader)
-			clientPriv, _, _ := crypto.GenerateEd25519Key(rand.Reader)
+			serverPriv, _, _ := crypto.GenerateKeyPairWithReader(tc.keyType, 0, rand.Reader)
+			clientPriv, _, _ := crypto.GenerateKeyPairWithReader(tc.keyType, 0, rand.Reader)
 
 			serverHandshake := PeerIDAuthHandshakeServer{
 				Hostname: hostname,
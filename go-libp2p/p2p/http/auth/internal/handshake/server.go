@@ -280,10 +280,19 @@ func (h *PeerIDAuthHandshakeServer) addOpaqueParam() error {
 	if err != nil {
 		return err
 	}
-	h.hb.writeParamB64(h.buf[len(opaqueVal):], "opaque", opaqueVal)
+	h.hb.writeParamB64(h.scratchAfter(opaqueVal), "opaque", opaqueVal)
 	return nil
 }
 
+// scratchAfter returns the part of h.buf following b, or nil if b outgrew
+// h.buf, as it does with the large public keys of ML-DSA.
+func (h *PeerIDAuthHandshakeServer) scratchAfter(b []byte) []byte {
+	if len(b) > len(h.buf) {
+		return nil
+	}
+	return h.buf[len(b):]
+}
+
 func (h *PeerIDAuthHandshakeServer) addServerSigParam(clientPublicKeyBytes []byte) error {
 	if len(h.p.challengeServer) < challengeLen {
 		return errors.New("challenge too short")
@@ -305,7 +314,7 @@ func (h *PeerIDAuthHandshakeServer) addBearerParam() error {
 	if err != nil {
 		return err
 	}
-	h.hb.writeParamB64(h.buf[len(bearerToken):], "bearer", bearerToken)
+	h.hb.writeParamB64(h.scratchAfter(bearerToken), "bearer", bearerToken)
 	return nil
 }
 
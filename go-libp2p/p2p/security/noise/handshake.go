@@ -16,6 +16,7 @@ import (
 
 	"github.com/flynn/noise"
 	pool "github.com/libp2p/go-buffer-pool"
+	"golang.org/x/crypto/chacha20poly1305"
 	"google.golang.org/protobuf/proto"
 )
 
@@ -23,6 +24,20 @@ import (
 // our libp2p identity key.
 const payloadSigPrefix = "noise-libp2p-static-key:"
 
+// transcriptSigPrefix is prepended to the Noise handshake hash before signing
+// it with an ML-DSA libp2p identity key.
+//
+// The signature of the static key alone can be replayed by anyone able to
+// break X25519 and recover the static private key from its public key. Peers
+// with an ML-DSA identity key also sign a handshake hash covering the
+// ephemeral keys of both peers, which binds their identity to the session,
+// so that the session is authenticated with post-quantum signatures only.
+// The initiator signs the hash before the last handshake message, which
+// already covers both ephemeral keys. The responder's ephemeral key is only
+// sent in the message carrying its payload, so it signs the final handshake
+// hash instead, in a first transport message following the handshake.
+const transcriptSigPrefix = "noise-libp2p-transcript:"
+
 // All noise session share a fixed cipher suite
 var cipherSuite = noise.NewCipherSuite(noise.DH25519, noise.CipherChaChaPoly, noise.HashSHA256)
 
@@ -79,7 +94,8 @@ func (s *secureSession) runHandshake(ctx context.Context) (err error) {
 		if err != nil {
 			return fmt.Errorf("error reading handshake message: %w", err)
 		}
-		rcvdEd, err := s.handleRemoteHandshakePayload(plaintext, hs.PeerStatic())
+		// the transcript signature of the responder follows the handshake
+		rcvdEd, err := s.handleRemoteHandshakePayload(plaintext, hs.PeerStatic(), nil)
 		if err != nil {
 			return err
 		}
@@ -95,13 +111,20 @@ func (s *secureSession) runHandshake(ctx context.Context) (err error) {
 		if s.initiatorEarlyDataHandler != nil {
 			ed = s.initiatorEarlyDataHandler.Send(ctx, s.insecureConn, s.remoteID)
 		}
-		payload, err := s.generateHandshakePayload(kp, ed)
+		payload, err := s.generateHandshakePayload(kp, ed, handshakeHash(hs))
 		if err != nil {
 			return err
 		}
 		if err := s.sendHandshakeMessage(hs, payload, hbuf); err != nil {
 			return fmt.Errorf("error sending handshake message: %w", err)
 		}
+
+		// stage 3 //
+		if crypto.IsMLDSAKeyType(s.remoteKey.Type()) {
+			if err := s.readTranscriptSig(handshakeHash(hs)); err != nil {
+				return err
+			}
+		}
 		return nil
 	} else {
 		// stage 0 //
@@ -116,7 +139,9 @@ func (s *secureSession) runHandshake(ctx context.Context) (err error) {
 		if s.responderEarlyDataHandler != nil {
 			ed = s.responderEarlyDataHandler.Send(ctx, s.insecureConn, s.remoteID)
 		}
-		payload, err := s.generateHandshakePayload(kp, ed)
+		// the hash does not cover our ephemeral key yet, so the transcript
+		// signature is sent after the handshake
+		payload, err := s.generateHandshakePayload(kp, ed, nil)
 		if err != nil {
 			return err
 		}
@@ -125,11 +150,12 @@ func (s *secureSession) runHandshake(ctx context.Context) (err error) {
 		}
 
 		// stage 2 //
+		transcript := handshakeHash(hs)
 		plaintext, err := s.readHandshakeMessage(hs)
 		if err != nil {
 			return fmt.Errorf("error reading handshake message: %w", err)
 		}
-		rcvdEd, err := s.handleRemoteHandshakePayload(plaintext, hs.PeerStatic())
+		rcvdEd, err := s.handleRemoteHandshakePayload(plaintext, hs.PeerStatic(), transcript)
 		if err != nil {
 			return err
 		}
@@ -138,6 +164,13 @@ func (s *secureSession) runHandshake(ctx context.Context) (err error) {
 				return err
 			}
 		}
+
+		// stage 3 //
+		if crypto.IsMLDSAKeyType(s.localKey.Type()) {
+			if err := s.sendTranscriptSig(handshakeHash(hs)); err != nil {
+				return fmt.Errorf("error sending transcript signature: %w", err)
+			}
+		}
 		return nil
 	}
 }
@@ -214,9 +247,16 @@ func (s *secureSession) readHandshakeMessage(hs *noise.HandshakeState) ([]byte,
 	return msg, nil
 }
 
+// handshakeHash returns a copy of the current handshake hash, which covers
+// every handshake message processed so far.
+func handshakeHash(hs *noise.HandshakeState) []byte {
+	return append([]byte(nil), hs.ChannelBinding()...)
+}
+
 // generateHandshakePayload creates a libp2p handshake payload with a
-// signature of our static noise key.
-func (s *secureSession) generateHandshakePayload(localStatic noise.DHKey, ext *pb.NoiseExtensions) ([]byte, error) {
+// signature of our static noise key. With an ML-DSA identity key and a
+// non-nil transcript, it also signs the transcript.
+func (s *secureSession) generateHandshakePayload(localStatic noise.DHKey, ext *pb.NoiseExtensions, transcript []byte) ([]byte, error) {
 	// obtain the public key from the handshake session, so we can sign it with
 	// our libp2p secret key.
 	localKeyRaw, err := crypto.MarshalPublicKey(s.LocalPublicKey())
@@ -231,11 +271,20 @@ func (s *secureSession) generateHandshakePayload(localStatic noise.DHKey, ext *p
 		return nil, fmt.Errorf("error sigining handshake payload: %w", err)
 	}
 
+	var transcriptSig []byte
+	if transcript != nil && crypto.IsMLDSAKeyType(s.localKey.Type()) {
+		transcriptSig, err = s.signTranscript(transcript)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	// create payload
 	payloadEnc, err := proto.Marshal(&pb.NoiseHandshakePayload{
-		IdentityKey: localKeyRaw,
-		IdentitySig: signedPayload,
-		Extensions:  ext,
+		IdentityKey:   localKeyRaw,
+		IdentitySig:   signedPayload,
+		Extensions:    ext,
+		TranscriptSig: transcriptSig,
 	})
 	if err != nil {
 		return nil, fmt.Errorf("error marshaling handshake payload: %w", err)
@@ -245,8 +294,10 @@ func (s *secureSession) generateHandshakePayload(localStatic noise.DHKey, ext *p
 
 // handleRemoteHandshakePayload unmarshals the handshake payload object sent
 // by the remote peer and validates the signature against the peer's static Noise key.
+// For ML-DSA identity keys and a non-nil transcript, it also validates the
+// signature of the transcript.
 // It returns the data attached to the payload.
-func (s *secureSession) handleRemoteHandshakePayload(payload []byte, remoteStatic []byte) (*pb.NoiseExtensions, error) {
+func (s *secureSession) handleRemoteHandshakePayload(payload []byte, remoteStatic []byte, transcript []byte) (*pb.NoiseExtensions, error) {
 	// unmarshal payload
 	nhp := new(pb.NoiseHandshakePayload)
 	err := proto.Unmarshal(payload, nhp)
@@ -279,8 +330,71 @@ func (s *secureSession) handleRemoteHandshakePayload(payload []byte, remoteStati
 		return nil, fmt.Errorf("handshake signature invalid")
 	}
 
+	if transcript != nil && crypto.IsMLDSAKeyType(remotePubKey.Type()) {
+		if err := verifyTranscript(remotePubKey, transcript, nhp.GetTranscriptSig()); err != nil {
+			return nil, err
+		}
+	}
+
 	// set remote peer key and id
 	s.remoteID = id
 	s.remoteKey = remotePubKey
 	return nhp.Extensions, nil
 }
+
+// signTranscript signs the handshake hash transcript with our libp2p
+// identity key.
+func (s *secureSession) signTranscript(transcript []byte) ([]byte, error) {
+	sig, err := s.localKey.Sign(append([]byte(transcriptSigPrefix), transcript...))
+	if err != nil {
+		return nil, fmt.Errorf("error signing handshake transcript: %w", err)
+	}
+	return sig, nil
+}
+
+// verifyTranscript checks the signature of the handshake hash transcript by
+// the remote peer.
+func verifyTranscript(remotePubKey crypto.PubKey, transcript []byte, sig []byte) error {
+	ok, err := remotePubKey.Verify(append([]byte(transcriptSigPrefix), transcript...), sig)
+	if err != nil {
+		return fmt.Errorf("error verifying transcript signature: %w", err)
+	} else if !ok {
+		return fmt.Errorf("handshake transcript signature invalid")
+	}
+	return nil
+}
+
+// sendTranscriptSig sends the signature of the final handshake hash
+// transcript in a transport message, once the handshake is complete.
+func (s *secureSession) sendTranscriptSig(transcript []byte) error {
+	sig, err := s.signTranscript(transcript)
+	if err != nil {
+		return err
+	}
+	buf := make([]byte, LengthPrefixLength, LengthPrefixLength+len(sig)+chacha20poly1305.Overhead)
+	b, err := s.encrypt(buf, sig)
+	if err != nil {
+		return err
+	}
+	binary.BigEndian.PutUint16(b, uint16(len(b)-LengthPrefixLength))
+	_, err = s.writeMsgInsecure(b)
+	return err
+}
+
+// readTranscriptSig reads the transport message sent by sendTranscriptSig
+// and checks the signature of the final handshake hash transcript.
+func (s *secureSession) readTranscriptSig(transcript []byte) error {
+	l, err := s.readNextInsecureMsgLen()
+	if err != nil {
+		return fmt.Errorf("error reading transcript signature: %w", err)
+	}
+	buf := make([]byte, l)
+	if err := s.readNextMsgInsecure(buf); err != nil {
+		return fmt.Errorf("error reading transcript signature: %w", err)
+	}
+	sig, err := s.decrypt(buf[:0], buf)
+	if err != nil {
+		return fmt.Errorf("error decrypting transcript signature: %w", err)
+	}
+	return verifyTranscript(s.remoteKey, transcript, sig)
+}
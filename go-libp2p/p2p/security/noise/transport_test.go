@@ -3,6 +3,7 @@ package noise
 import (
 	"bytes"
 	"context"
+	crand "crypto/rand"
 	"encoding/binary"
 	"errors"
 	"io"
@@ -11,7 +12,9 @@ import (
 	"testing"
 	"time"
 
+	"github.com/flynn/noise"
 	"golang.org/x/crypto/chacha20poly1305"
+	"google.golang.org/protobuf/proto"
 
 	"github.com/libp2p/go-libp2p/core/crypto"
 	"github.com/libp2p/go-libp2p/core/peer"
@@ -713,3 +716,68 @@ func TestHandshakeWithTransportEarlyData(t *testing.T) {
 		})
 	}
 }
+
+func TestMLDSAHandshake(t *testing.T) {
+	for _, typ := range []int{crypto.MLDSA44, crypto.MLDSA65, crypto.MLDSA87} {
+		for _, otherTyp := range []int{typ, crypto.Ed25519} {
+			initTransport := newTestTransport(t, typ, 0)
+			respTransport := newTestTransport(t, otherTyp, 0)
+
+			initConn, respConn := connect(t, initTransport, respTransport)
+			require.True(t, respConn.RemotePublicKey().Equals(initTransport.privateKey.GetPublic()))
+			require.True(t, initConn.RemotePublicKey().Equals(respTransport.privateKey.GetPublic()))
+
+			_, err := initConn.Write([]byte("foobar"))
+			require.NoError(t, err)
+			b := make([]byte, 6)
+			_, err = io.ReadFull(respConn, b)
+			require.NoError(t, err)
+			require.Equal(t, "foobar", string(b))
+
+			initConn.Close()
+			respConn.Close()
+		}
+	}
+}
+
+func TestMLDSATranscriptSignature(t *testing.T) {
+	priv, _, err := crypto.GenerateKeyPair(crypto.MLDSA44, 0)
+	require.NoError(t, err)
+	kp, err := noise.DH25519.GenerateKeypair(crand.Reader)
+	require.NoError(t, err)
+
+	local := &secureSession{localKey: priv}
+	transcript := bytes.Repeat([]byte{1}, 32)
+	payload, err := local.generateHandshakePayload(kp, nil, transcript)
+	require.NoError(t, err)
+
+	var nhp pb.NoiseHandshakePayload
+	require.NoError(t, proto.Unmarshal(payload, &nhp))
+	require.NotEmpty(t, nhp.TranscriptSig)
+
+	remote := &secureSession{}
+	_, err = remote.handleRemoteHandshakePayload(payload, kp.Public, transcript)
+	require.NoError(t, err)
+
+	// The payload cannot be replayed in another session
+	otherTranscript := bytes.Repeat([]byte{2}, 32)
+	_, err = remote.handleRemoteHandshakePayload(payload, kp.Public, otherTranscript)
+	require.ErrorContains(t, err, "transcript signature invalid")
+
+	// nor stripped of its transcript signature
+	nhp.TranscriptSig = nil
+	stripped, err := proto.Marshal(&nhp)
+	require.NoError(t, err)
+	_, err = remote.handleRemoteHandshakePayload(stripped, kp.Public, transcript)
+	require.ErrorContains(t, err, "transcript signature invalid")
+
+	// The responder signs the transcript after the handshake instead
+	payload, err = local.generateHandshakePayload(kp, nil, nil)
+	require.NoError(t, err)
+	require.NoError(t, proto.Unmarshal(payload, &nhp))
+	require.Empty(t, nhp.TranscriptSig)
+	sig, err := local.signTranscript(transcript)
+	require.NoError(t, err)
+	require.NoError(t, verifyTranscript(priv.GetPublic(), transcript, sig))
+	require.ErrorContains(t, verifyTranscript(priv.GetPublic(), otherTranscript, sig), "transcript signature invalid")
+}
@@ -26,6 +26,11 @@ const certValidityPeriod = 100 * 365 * 24 * time.Hour // ~100 years
 const certificatePrefix = "libp2p-tls-handshake:"
 const alpn string = "libp2p"
 
+// Peers with ML-DSA identity keys sign the keying material exported with
+// sessionExporterLabel, prefixed by sessionSignaturePrefix, after the handshake.
+const sessionExporterLabel = "EXPORTER-libp2p-identity"
+const sessionSignaturePrefix = "libp2p-tls-session:"
+
 var extensionID = getPrefixedExtensionID([]int{1, 1})
 var extensionCritical bool // so we can mark the extension critical in tests
 
@@ -3,11 +3,14 @@ package libp2ptls
 import (
 	"context"
 	"crypto/tls"
+	"encoding/binary"
 	"errors"
 	"fmt"
+	"io"
 	"net"
 	"os"
 	"runtime/debug"
+	"time"
 
 	"github.com/libp2p/go-libp2p/core/canonicallog"
 	ci "github.com/libp2p/go-libp2p/core/crypto"
@@ -146,9 +149,66 @@ func (t *Transport) handshake(ctx context.Context, tlsConn *tls.Conn, keyCh <-ch
 		return nil, errors.New("go-libp2p tls BUG: expected remote pub key to be set")
 	}
 
+	// The TLS handshake itself is authenticated by the ECDSA certificate key,
+	// which is only bound to the libp2p identity by the certificate extension.
+	// When both peers use ML-DSA identity keys, they additionally sign the
+	// keying material exported from the session, so that the connection is
+	// authenticated by post-quantum signatures end to end.
+	if ci.IsMLDSAKeyType(t.privKey.Type()) && ci.IsMLDSAKeyType(remotePubKey.Type()) {
+		if err := t.bindSession(ctx, tlsConn, remotePubKey); err != nil {
+			return nil, err
+		}
+	}
+
 	return t.setupConn(tlsConn, remotePubKey)
 }
 
+// bindSession exchanges signatures of the exported keying material of the
+// session with the identity keys of both peers.
+func (t *Transport) bindSession(ctx context.Context, tlsConn *tls.Conn, remotePubKey ci.PubKey) error {
+	state := tlsConn.ConnectionState()
+	ekm, err := state.ExportKeyingMaterial(sessionExporterLabel, nil, 32)
+	if err != nil {
+		return err
+	}
+	sig, err := t.privKey.Sign(append([]byte(sessionSignaturePrefix), ekm...))
+	if err != nil {
+		return err
+	}
+	if len(sig) > 0xffff {
+		return errors.New("session signature too large")
+	}
+
+	if deadline, ok := ctx.Deadline(); ok {
+		if err := tlsConn.SetDeadline(deadline); err == nil {
+			defer tlsConn.SetDeadline(time.Time{})
+		}
+	}
+
+	// Both peers write before reading, so the write must not block the read.
+	writeErr := make(chan error, 1)
+	go func() {
+		msg := make([]byte, 2+len(sig))
+		binary.BigEndian.PutUint16(msg, uint16(len(sig)))
+		copy(msg[2:], sig)
+		_, err := tlsConn.Write(msg)
+		writeErr <- err
+	}()
+
+	var l [2]byte
+	if _, err := io.ReadFull(tlsConn, l[:]); err != nil {
+		return err
+	}
+	remoteSig := make([]byte, binary.BigEndian.Uint16(l[:]))
+	if _, err := io.ReadFull(tlsConn, remoteSig); err != nil {
+		return err
+	}
+	if ok, err := remotePubKey.Verify(append([]byte(sessionSignaturePrefix), ekm...), remoteSig); err != nil || !ok {
+		return errors.New("session signature invalid")
+	}
+	return <-writeErr
+}
+
 func (t *Transport) setupConn(tlsConn *tls.Conn, remotePubKey ci.PubKey) (sec.SecureConn, error) {
 	remotePeerID, err := peer.IDFromPublicKey(remotePubKey)
 	if err != nil {
@@ -12,6 +12,7 @@ import (
 	"crypto/x509/pkix"
 	"encoding/asn1"
 	"fmt"
+	"io"
 	"math/big"
 	mrand "math/rand"
 	"net"
@@ -175,6 +176,92 @@ func TestHandshakeSucceeds(t *testing.T) {
 	})
 }
 
+func TestMLDSAHandshake(t *testing.T) {
+	newKey := func(t *testing.T, typ int) (peer.ID, ic.PrivKey) {
+		priv, _, err := ic.GenerateKeyPairWithReader(typ, 0, rand.Reader)
+		require.NoError(t, err)
+		id, err := peer.IDFromPrivateKey(priv)
+		require.NoError(t, err)
+		return id, priv
+	}
+
+	for _, tc := range []struct {
+		name           string
+		client, server int
+	}{
+		{"MLDSA44", ic.MLDSA44, ic.MLDSA44},
+		{"MLDSA65", ic.MLDSA65, ic.MLDSA65},
+		{"MLDSA87", ic.MLDSA87, ic.MLDSA87},
+		{"MLDSA44 and MLDSA87", ic.MLDSA44, ic.MLDSA87},
+		{"MLDSA65 and Ed25519", ic.MLDSA65, ic.Ed25519},
+		{"Ed25519 and MLDSA44", ic.Ed25519, ic.MLDSA44},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			clientID, clientKey := newKey(t, tc.client)
+			serverID, serverKey := newKey(t, tc.server)
+			clientTransport, err := New(ID, clientKey, nil)
+			require.NoError(t, err)
+			serverTransport, err := New(ID, serverKey, nil)
+			require.NoError(t, err)
+
+			clientInsecureConn, serverInsecureConn := connect(t)
+			serverConnChan := make(chan sec.SecureConn, 1)
+			go func() {
+				serverConn, err := serverTransport.SecureInbound(context.Background(), serverInsecureConn, "")
+				assert.NoError(t, err)
+				serverConnChan <- serverConn
+			}()
+
+			clientConn, err := clientTransport.SecureOutbound(context.Background(), clientInsecureConn, serverID)
+			require.NoError(t, err)
+			defer clientConn.Close()
+			serverConn := <-serverConnChan
+			require.NotNil(t, serverConn)
+			defer serverConn.Close()
+
+			require.Equal(t, serverID, clientConn.RemotePeer())
+			require.Equal(t, clientID, serverConn.RemotePeer())
+
+			// The session signatures don't leak into the application data
+			_, err = clientConn.Write([]byte("foobar"))
+			require.NoError(t, err)
+			b := make([]byte, 6)
+			_, err = io.ReadFull(serverConn, b)
+			require.NoError(t, err)
+			require.Equal(t, "foobar", string(b))
+		})
+	}
+}
+
+func TestMLDSASessionSignatureInvalid(t *testing.T) {
+	clientKey, _, err := ic.GenerateMLDSAKey(ic.MLDSA44, rand.Reader)
+	require.NoError(t, err)
+	serverKey, _, err := ic.GenerateMLDSAKey(ic.MLDSA44, rand.Reader)
+	require.NoError(t, err)
+	otherKey, _, err := ic.GenerateMLDSAKey(ic.MLDSA44, rand.Reader)
+	require.NoError(t, err)
+	serverID, err := peer.IDFromPrivateKey(serverKey)
+	require.NoError(t, err)
+
+	clientTransport, err := New(ID, clientKey, nil)
+	require.NoError(t, err)
+	serverTransport, err := New(ID, serverKey, nil)
+	require.NoError(t, err)
+	// The certificate carries the server's key, but the session is signed
+	// with another one.
+	serverTransport.privKey = otherKey
+
+	clientInsecureConn, serverInsecureConn := connect(t)
+	go func() {
+		if conn, err := serverTransport.SecureInbound(context.Background(), serverInsecureConn, ""); err == nil {
+			conn.Close()
+		}
+	}()
+
+	_, err = clientTransport.SecureOutbound(context.Background(), clientInsecureConn, serverID)
+	require.ErrorContains(t, err, "session signature invalid")
+}
+
 type testcase struct {
 	clientProtos   []protocol.ID
 	serverProtos   []protocol.ID
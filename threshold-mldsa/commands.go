@@ -0,0 +1,403 @@
+package main
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"threshold-mldsa/sign"
+	"time"
+
+	"github.com/cloudflare/circl/sign/thmldsa/thmldsa44"
+)
+
+var commands = map[string]func(args []string) error{
+	"keygen":   keygen,
+	"identity": identity,
+	"nonce":    nonce,
+	"sign":     signCmd,
+	"combine":  combine,
+	"verify":   verify,
+	"inspect":  inspect,
+}
+
+func usage() {
+	fmt.Fprint(os.Stderr, `Usage:
+  threshold-mldsa keygen   -t T -n N -out DIR
+  threshold-mldsa identity -party ID -out FILE
+  threshold-mldsa nonce
+  threshold-mldsa sign     -key FILE -pk FILE -signers IDS -session NONCE -msg MSG -transport TR
+                           [-identity FILE -peers FILE] [-out FILE]
+  threshold-mldsa combine  -pk FILE -signers IDS -session NONCE -msg MSG -transport dir:PATH [-out FILE]
+  threshold-mldsa verify   -pk FILE -msg MSG -sig FILE
+  threshold-mldsa inspect  [-commitments FILE] FILE...
+  threshold-mldsa type=d|r iter= t= n=
+
+Run "threshold-mldsa COMMAND -h" for the options of a command.
+`)
+}
+
+// messageFlags are the flags selecting the message to sign or verify.
+type messageFlags struct {
+	msg, msgFile, ctx string
+}
+
+func (m *messageFlags) register(fs *flag.FlagSet) {
+	fs.StringVar(&m.msg, "msg", "", "message")
+	fs.StringVar(&m.msgFile, "msg-file", "", "file holding the message, instead of -msg")
+	fs.StringVar(&m.ctx, "ctx", "", "context string, of at most 255 bytes")
+}
+
+func (m *messageFlags) message() ([]byte, []byte, error) {
+	if len(m.ctx) > 255 {
+		return nil, nil, errors.New("context longer than 255 bytes")
+	}
+	if m.msgFile != "" {
+		msg, err := os.ReadFile(m.msgFile)
+		return msg, []byte(m.ctx), err
+	}
+	return []byte(m.msg), []byte(m.ctx), nil
+}
+
+// sessionFlags are the flags shared by the signers and the combiner.
+type sessionFlags struct {
+	messageFlags
+	pk          string
+	signers     string
+	nonce       string
+	transport   string
+	out         string
+	timeout     time.Duration
+	maxAttempts int
+}
+
+func (s *sessionFlags) register(fs *flag.FlagSet) {
+	s.messageFlags.register(fs)
+	fs.StringVar(&s.pk, "pk", "public.pem", "public key file")
+	fs.StringVar(&s.signers, "signers", "", "comma-separated IDs of the T signers")
+	fs.StringVar(&s.nonce, "session", "", "nonce of the session, drawn by the coordinator with the nonce command")
+	fs.StringVar(&s.transport, "transport", "", "dir:PATH for a shared directory, or tcp:ADDR0,...,ADDRn-1 with the address of every party")
+	fs.StringVar(&s.out, "out", "signature.bin", "signature file")
+	fs.DurationVar(&s.timeout, "timeout", 5*time.Minute, "time limit of the session")
+	fs.IntVar(&s.maxAttempts, "max-attempts", 570, "maximum number of signing attempts")
+}
+
+func (s *sessionFlags) session() (*sign.Session, error) {
+	pk, params, err := sign.ReadPublicKey(s.pk)
+	if err != nil {
+		return nil, err
+	}
+	msg, ctx, err := s.message()
+	if err != nil {
+		return nil, err
+	}
+
+	act := uint8(0)
+	for _, f := range strings.Split(s.signers, ",") {
+		id, err := strconv.ParseUint(strings.TrimSpace(f), 10, 8)
+		if err != nil || id >= uint64(params.N) {
+			return nil, fmt.Errorf("invalid signer %q", f)
+		}
+		act |= 1 << id
+	}
+
+	session := &sign.Session{
+		PK:          pk,
+		Params:      params,
+		Act:         act,
+		Msg:         msg,
+		Ctx:         ctx,
+		MaxAttempts: s.maxAttempts,
+	}
+	nonce, err := hex.DecodeString(s.nonce)
+	if err != nil || len(nonce) != sign.NonceSize {
+		return nil, fmt.Errorf("-session must be the %d-byte hex nonce of the session", sign.NonceSize)
+	}
+	copy(session.Nonce[:], nonce)
+	return session, nil
+}
+
+// openTransport returns the transport described by spec for party id. The
+// tcp transport authenticates the parties with their identity keys.
+func openTransport(spec string, id uint8, session *sign.Session, identity ed25519.PrivateKey, peers []ed25519.PublicKey) (sign.Transport, error) {
+	kind, arg, _ := strings.Cut(spec, ":")
+	switch kind {
+	case "dir":
+		sid := session.ID()
+		return sign.NewDirTransport(filepath.Join(arg, hex.EncodeToString(sid[:8])))
+	case "tcp":
+		addrs := strings.Split(arg, ",")
+		if len(addrs) != int(session.Params.N) {
+			return nil, fmt.Errorf("expected %d addresses, got %d", session.Params.N, len(addrs))
+		}
+		for i := range addrs {
+			if session.Act&(1<<i) == 0 {
+				addrs[i] = ""
+			}
+		}
+		if identity == nil {
+			return nil, errors.New("the tcp transport needs -identity and -peers")
+		}
+		return sign.NewTCPTransport(id, addrs, session.ID(), identity, peers)
+	default:
+		return nil, fmt.Errorf("unknown transport %q", spec)
+	}
+}
+
+func keygen(args []string) error {
+	fs := flag.NewFlagSet("keygen", flag.ExitOnError)
+	t := fs.Uint("t", 2, "threshold")
+	n := fs.Uint("n", 3, "number of parties")
+	out := fs.String("out", ".", "output directory")
+	_ = fs.Parse(args)
+
+	if *t > 255 || *n > 255 {
+		return errors.New("invalid parameters")
+	}
+	params, err := thmldsa44.GetThresholdParams(uint8(*t), uint8(*n))
+	if err != nil {
+		return err
+	}
+	pk, sks, err := thmldsa44.GenerateThresholdKey(nil, params)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(*out, 0o755); err != nil {
+		return err
+	}
+	if err := sign.WritePublicKey(filepath.Join(*out, "public.pem"), pk, params); err != nil {
+		return err
+	}
+	cmts := thmldsa44.CommitShares(sks, params)
+	if err := sign.WriteShareCommitments(filepath.Join(*out, "commitments.pem"), cmts, params); err != nil {
+		return err
+	}
+	for i := range sks {
+		path := filepath.Join(*out, fmt.Sprintf("party-%d.pem", i))
+		if err := sign.WritePrivateKey(path, &sks[i], params); err != nil {
+			return err
+		}
+	}
+
+	fmt.Printf("Generated %d-of-%d key %s in %s\n", *t, *n, sign.Fingerprint(pk.Tr[:]), *out)
+	return nil
+}
+
+func identity(args []string) error {
+	fs := flag.NewFlagSet("identity", flag.ExitOnError)
+	party := fs.Uint("party", 0, "ID of the party")
+	out := fs.String("out", "identity.pem", "identity key file")
+	_ = fs.Parse(args)
+
+	if *party > 255 {
+		return errors.New("invalid party")
+	}
+	pub, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return err
+	}
+	if err := sign.WriteIdentity(*out, uint8(*party), key); err != nil {
+		return err
+	}
+
+	// The public key goes on line party of the peers file of every party
+	fmt.Printf("%x\n", pub)
+	return nil
+}
+
+func nonce(args []string) error {
+	fs := flag.NewFlagSet("nonce", flag.ExitOnError)
+	_ = fs.Parse(args)
+
+	var n [sign.NonceSize]byte
+	if _, err := rand.Read(n[:]); err != nil {
+		return err
+	}
+	fmt.Printf("%x\n", n)
+	return nil
+}
+
+func signCmd(args []string) error {
+	var sf sessionFlags
+	fs := flag.NewFlagSet("sign", flag.ExitOnError)
+	key := fs.String("key", "", "private key share file")
+	cmtsFile := fs.String("commitments", "", "dealer's share commitments file, to check the key share against")
+	idFile := fs.String("identity", "", "identity key file, for the tcp transport")
+	peersFile := fs.String("peers", "", "public identity keys of the parties, for the tcp transport")
+	sf.register(fs)
+	_ = fs.Parse(args)
+
+	sk, params, err := sign.ReadPrivateKey(*key)
+	if err != nil {
+		return err
+	}
+	session, err := sf.session()
+	if err != nil {
+		return err
+	}
+	if params.T != session.Params.T || params.N != session.Params.N || sk.Tr != *session.PK.Tr {
+		return errors.New("key share does not match the public key")
+	}
+	if *cmtsFile != "" {
+		kf, err := sign.ReadKeyFile(*cmtsFile)
+		if err != nil {
+			return err
+		}
+		if kf.Commitments == nil {
+			return fmt.Errorf("%s is not a share commitments file", *cmtsFile)
+		}
+		if err := thmldsa44.VerifyShares(sk, kf.Commitments, params); err != nil {
+			return err
+		}
+	}
+
+	var idKey ed25519.PrivateKey
+	var peers []ed25519.PublicKey
+	if *idFile != "" || *peersFile != "" {
+		var party uint8
+		if idKey, party, err = sign.ReadIdentity(*idFile); err != nil {
+			return err
+		}
+		if party != sk.Id {
+			return fmt.Errorf("identity key of party %d, not %d", party, sk.Id)
+		}
+		if peers, err = sign.ReadPeers(*peersFile); err != nil {
+			return err
+		}
+	}
+
+	tr, err := openTransport(sf.transport, sk.Id, session, idKey, peers)
+	if err != nil {
+		return err
+	}
+	defer tr.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), sf.timeout)
+	defer cancel()
+	sig, attempts, err := sign.RunParty(ctx, sk, session, tr)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Signed after %d attempts\n", attempts)
+	return os.WriteFile(sf.out, sig, 0o644)
+}
+
+func combine(args []string) error {
+	var sf sessionFlags
+	fs := flag.NewFlagSet("combine", flag.ExitOnError)
+	sf.register(fs)
+	_ = fs.Parse(args)
+
+	if !strings.HasPrefix(sf.transport, "dir:") {
+		return errors.New("the combiner only reads the transcript of a dir: transport")
+	}
+	session, err := sf.session()
+	if err != nil {
+		return err
+	}
+	tr, err := openTransport(sf.transport, 0, session, nil, nil)
+	if err != nil {
+		return err
+	}
+	defer tr.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), sf.timeout)
+	defer cancel()
+	sig, attempts, err := sign.CombineSession(ctx, session, tr)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Combined after %d attempts\n", attempts)
+	return os.WriteFile(sf.out, sig, 0o644)
+}
+
+func verify(args []string) error {
+	var mf messageFlags
+	fs := flag.NewFlagSet("verify", flag.ExitOnError)
+	pkFile := fs.String("pk", "public.pem", "public key file")
+	sigFile := fs.String("sig", "signature.bin", "signature file")
+	mf.register(fs)
+	_ = fs.Parse(args)
+
+	pk, _, err := sign.ReadPublicKey(*pkFile)
+	if err != nil {
+		return err
+	}
+	msg, ctx, err := mf.message()
+	if err != nil {
+		return err
+	}
+	sig, err := os.ReadFile(*sigFile)
+	if err != nil {
+		return err
+	}
+	if !thmldsa44.Verify(pk, msg, ctx, sig) {
+		return errors.New("invalid signature")
+	}
+	fmt.Println("Signature OK")
+	return nil
+}
+
+func inspect(args []string) error {
+	fs := flag.NewFlagSet("inspect", flag.ExitOnError)
+	cmtsFile := fs.String("commitments", "", "dealer's share commitments file, to check key shares against")
+	_ = fs.Parse(args)
+
+	var cmts *thmldsa44.ShareCommitments
+	if *cmtsFile != "" {
+		kf, err := sign.ReadKeyFile(*cmtsFile)
+		if err != nil {
+			return err
+		}
+		cmts = kf.Commitments
+		if cmts == nil {
+			return fmt.Errorf("%s is not a share commitments file", *cmtsFile)
+		}
+	}
+
+	for _, path := range fs.Args() {
+		kf, err := sign.ReadKeyFile(path)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("%s:\n", path)
+		fmt.Printf("  Type:        %s\n", kf.Type)
+		fmt.Printf("  Threshold:   %d of %d\n", kf.T, kf.N)
+
+		switch {
+		case kf.PK != nil:
+			fmt.Printf("  Fingerprint: %s\n", sign.Fingerprint(kf.PK.Tr[:]))
+			fmt.Printf("  Size:        %d bytes\n", thmldsa44.PublicKeySize)
+
+		case kf.SK != nil:
+			fmt.Printf("  Fingerprint: %s\n", sign.Fingerprint(kf.SK.Tr[:]))
+			fmt.Printf("  Party:       %d\n", kf.Party)
+			fmt.Printf("  Shares:     ")
+			for _, u := range kf.SK.ShareIndices() {
+				fmt.Printf(" %0*b", kf.N, u)
+			}
+			fmt.Println()
+			if cmts != nil {
+				if err := thmldsa44.VerifyShares(kf.SK, cmts, kf.Params); err != nil {
+					fmt.Printf("  Commitments: MISMATCH (%v)\n", err)
+				} else {
+					fmt.Println("  Commitments: OK")
+				}
+			}
+
+		case kf.Commitments != nil:
+			digest := kf.Commitments.Digest()
+			fmt.Printf("  Digest:      %x\n", digest[:16])
+			fmt.Printf("  Shares:      %d\n", len(*kf.Commitments))
+		}
+	}
+	return nil
+}
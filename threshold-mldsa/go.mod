@@ -6,7 +6,10 @@ toolchain go1.24.0
 
 replace github.com/cloudflare/circl => ../circl-main
 
+replace bench => ../bench
+
 require (
+	bench v0.0.0
 	github.com/cloudflare/circl v1.6.0
 	github.com/montanaflynn/stats v0.7.1
 )
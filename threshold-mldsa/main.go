@@ -1,26 +1,42 @@
 package main
 
 import (
+	"bench"
 	"fmt"
 	"os"
 	"strconv"
 	"strings"
 	"threshold-mldsa/sign"
+	"time"
 )
 
 func main() {
 	if len(os.Args) < 2 {
-		fmt.Println("Usage: go run main.go type= iter= t= n=")
+		usage()
 		os.Exit(1)
 	}
 
-	if len(os.Args) > 5 {
-		fmt.Println("Only four args are allowed")
+	if cmd, ok := commands[os.Args[1]]; ok {
+		if err := cmd(os.Args[2:]); err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %v\n", os.Args[1], err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	benchmark(os.Args[1:])
+}
+
+// Runs the in-memory benchmarks: go run . type= iter= t= n= [format=]
+// The simulator (type=c) also takes latency=, bw=, loss= and rto=.
+func benchmark(argv []string) {
+	if len(argv) > 8 {
+		fmt.Println("Only eight args are allowed")
 		os.Exit(1)
 	}
 
 	args := make(map[string]string)
-	for _, arg := range os.Args[1:] {
+	for _, arg := range argv {
 		parts := strings.SplitN(arg, "=", 2)
 		if len(parts) != 2 {
 			fmt.Printf("Invalid argument format: %s\n", arg)
@@ -41,7 +57,11 @@ func main() {
 		os.Exit(1)
 	}
 
+	// A sweep runs every t for n up to the given one
 	t, ok := args["t"]
+	if !ok && partyID == "s" {
+		t, ok = "2", true
+	}
 	if !ok {
 		fmt.Println("Missing t parameter.")
 		os.Exit(1)
@@ -68,11 +88,68 @@ func main() {
 		os.Exit(1)
 	}
 
+	format, err := bench.ParseFormat(args["format"])
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+
 	sign.K = parties
 	sign.Threshold = threshold
 
+	if partyID == "s" || format != "text" {
+		err = bench.Report(os.Stdout, format, func(each func(*bench.Result)) error {
+			switch partyID {
+			case "s":
+				return sign.SweepThresholdDilithium(parties, iters, each)
+			case "d":
+				res, err := sign.BenchThresholdDilithium(threshold, parties, iters)
+				if err == nil {
+					each(res)
+				}
+				return err
+			}
+			return fmt.Errorf("format=%s is only supported with type=d or type=s", format)
+		})
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
 	if partyID == "d" {
 		sign.LocalThresholdDilithiumRun(iters)
 		return
 	}
+
+	if partyID == "r" {
+		sign.LocalRobustThresholdDilithiumRun(iters)
+		return
+	}
+
+	if partyID == "c" {
+		var link sign.LinkConfig
+		var rto time.Duration
+		if v, ok := args["latency"]; ok {
+			link.Latency, err = time.ParseDuration(v)
+		}
+		if v, ok := args["bw"]; ok && err == nil {
+			link.Bandwidth, err = strconv.ParseFloat(v, 64)
+		}
+		if v, ok := args["loss"]; ok && err == nil {
+			link.Loss, err = strconv.ParseFloat(v, 64)
+		}
+		if v, ok := args["rto"]; ok && err == nil {
+			rto, err = time.ParseDuration(v)
+		} else if err == nil {
+			rto = 4*link.Latency + 10*time.Millisecond
+		}
+		if err != nil || link.Loss < 0 || link.Loss >= 1 || (link.Loss > 0 && rto <= 0) {
+			fmt.Println("Error: invalid link parameters.")
+			os.Exit(1)
+		}
+		sign.LocalSimulatedRun(iters, link, rto)
+		return
+	}
 }
@@ -0,0 +1,123 @@
+package sign
+
+import (
+	"bench"
+	"encoding/binary"
+	"errors"
+	"time"
+
+	"github.com/cloudflare/circl/sign/thmldsa/thmldsa44"
+)
+
+// BenchThresholdDilithium runs the scheme runs times for T out of N parties
+// and returns the measurements, without printing anything.
+func BenchThresholdDilithium(t, n, runs int) (*bench.Result, error) {
+	var (
+		seed [32]byte
+		msg  = []byte("message")
+		ctx  [8]byte
+		sig  [thmldsa44.SignatureSize]byte
+	)
+
+	params, err := thmldsa44.GetThresholdParams(uint8(t), uint8(n))
+	if err != nil {
+		return nil, err
+	}
+
+	res := &bench.Result{Scheme: "threshold-mldsa-44", T: t, N: n, Runs: runs, SignatureBytes: thmldsa44.SignatureSize}
+	var keygen, combine, signDur, verify []time.Duration
+	var rounds [3][]time.Duration
+	attempts := 0
+
+	act := uint8((1 << t) - 1)
+	for i := 0; i < runs; i++ {
+		binary.LittleEndian.PutUint64(seed[:], uint64(i))
+		start := time.Now()
+		pk, sks := thmldsa44.NewThresholdKeysFromSeed(&seed, params)
+		keygen = append(keygen, time.Since(start))
+
+		signStart := time.Now()
+		ok := false
+		for attempt := 1; attempt <= 570 && !ok; attempt++ {
+			strd1s := make([]thmldsa44.StRound1, t)
+			msgs1 := make([][]byte, t)
+			for j := range msgs1 {
+				start = time.Now()
+				msgs1[j], strd1s[j], err = thmldsa44.Round1(&sks[j], params)
+				rounds[0] = append(rounds[0], time.Since(start))
+				if err != nil {
+					return nil, err
+				}
+			}
+
+			strd2s := make([]thmldsa44.StRound2, t)
+			msgs2 := make([][]byte, t)
+			for j := range msgs2 {
+				start = time.Now()
+				msgs2[j], strd2s[j], err = thmldsa44.Round2(&sks[j], act, msg, ctx[:], msgs1, &strd1s[j], params)
+				rounds[1] = append(rounds[1], time.Since(start))
+				if err != nil {
+					return nil, err
+				}
+			}
+
+			msgs3 := make([][]byte, t)
+			for j := range msgs3 {
+				start = time.Now()
+				msgs3[j], err = thmldsa44.Round3(&sks[j], msgs2, &strd1s[j], &strd2s[j], params)
+				rounds[2] = append(rounds[2], time.Since(start))
+				if err != nil {
+					return nil, err
+				}
+			}
+
+			start = time.Now()
+			ok = thmldsa44.Combine(pk, msg, ctx[:], msgs2, msgs3, sig[:], params)
+			combine = append(combine, time.Since(start))
+
+			res.RoundBytes = [3]int{len(msgs1[0]), len(msgs2[0]), len(msgs3[0])}
+			attempts++
+			if attempt > res.AttemptsMax {
+				res.AttemptsMax = attempt
+			}
+		}
+		signDur = append(signDur, time.Since(signStart))
+		if !ok {
+			return nil, ErrTooManyAttempts
+		}
+
+		start = time.Now()
+		if !thmldsa44.Verify(pk, msg, ctx[:], sig[:]) {
+			return nil, errors.New("verification failed")
+		}
+		verify = append(verify, time.Since(start))
+	}
+
+	res.AttemptsMean = float64(attempts) / float64(runs)
+	res.KeyGen = bench.Summarize(keygen)
+	for r := range rounds {
+		res.Rounds[r] = bench.Summarize(rounds[r])
+	}
+	res.Combine = bench.Summarize(combine)
+	res.Sign = bench.Summarize(signDur)
+	res.Verify = bench.Summarize(verify)
+	return res, nil
+}
+
+// SweepThresholdDilithium benchmarks every (T, N) supported by the
+// parameter table with N at most maxN.
+func SweepThresholdDilithium(maxN, runs int, each func(*bench.Result)) error {
+	for n := 2; n <= maxN; n++ {
+		for t := 2; t <= n; t++ {
+			if _, err := thmldsa44.GetThresholdParams(uint8(t), uint8(n)); err != nil {
+				continue
+			}
+			res, err := BenchThresholdDilithium(t, n, runs)
+			if err != nil {
+				return err
+			}
+			each(res)
+		}
+	}
+	return nil
+}
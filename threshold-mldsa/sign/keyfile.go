@@ -0,0 +1,231 @@
+package sign
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/ed25519"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+
+	"github.com/cloudflare/circl/sign/thmldsa/thmldsa44"
+)
+
+// PEM block types of the key files.
+const (
+	PublicKeyPEMType        = "THRESHOLD ML-DSA-44 PUBLIC KEY"
+	PrivateKeyPEMType       = "THRESHOLD ML-DSA-44 PRIVATE KEY SHARE"
+	ShareCommitmentsPEMType = "THRESHOLD ML-DSA-44 SHARE COMMITMENTS"
+	IdentityPEMType         = "THRESHOLD ML-DSA-44 IDENTITY KEY"
+)
+
+// ErrKeyFile is returned when a key file is malformed.
+var ErrKeyFile = errors.New("invalid key file")
+
+// KeyFile is the content of a public key, private key share or share
+// commitments file. The threshold parameters are stored as PEM headers.
+type KeyFile struct {
+	Type   string
+	T, N   uint8
+	Party  uint8 // Only for private key shares
+	Params *thmldsa44.ThresholdParams
+
+	PK          *thmldsa44.PublicKey
+	SK          *thmldsa44.PrivateKey
+	Commitments *thmldsa44.ShareCommitments
+}
+
+// Fingerprint returns a short identifier of the public key, shared by the
+// public key file and the private key shares of the same key.
+func Fingerprint(tr []byte) string {
+	return hex.EncodeToString(tr[:16])
+}
+
+func writePEM(path string, perm os.FileMode, typ string, headers map[string]string, data []byte) error {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, perm)
+	if err != nil {
+		return err
+	}
+	if err := pem.Encode(f, &pem.Block{Type: typ, Headers: headers, Bytes: data}); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+func paramsHeaders(params *thmldsa44.ThresholdParams) map[string]string {
+	return map[string]string{
+		"T": strconv.Itoa(int(params.T)),
+		"N": strconv.Itoa(int(params.N)),
+	}
+}
+
+// WritePublicKey writes pk to a new file at path.
+func WritePublicKey(path string, pk *thmldsa44.PublicKey, params *thmldsa44.ThresholdParams) error {
+	return writePEM(path, 0o644, PublicKeyPEMType, paramsHeaders(params), pk.Bytes())
+}
+
+// WritePrivateKey writes the key share sk to a new file at path, only
+// readable by its owner.
+func WritePrivateKey(path string, sk *thmldsa44.PrivateKey, params *thmldsa44.ThresholdParams) error {
+	buf := make([]byte, params.PrivateKeySize())
+	sk.Pack(buf)
+	headers := paramsHeaders(params)
+	headers["Party"] = strconv.Itoa(int(sk.Id))
+	err := writePEM(path, 0o600, PrivateKeyPEMType, headers, buf)
+	for i := range buf {
+		buf[i] = 0
+	}
+	return err
+}
+
+// WriteShareCommitments writes the dealer's commitments to a new file at
+// path, so that every party can check its share with VerifyShares.
+func WriteShareCommitments(path string, cmts *thmldsa44.ShareCommitments, params *thmldsa44.ThresholdParams) error {
+	return writePEM(path, 0o644, ShareCommitmentsPEMType, paramsHeaders(params), cmts.Bytes())
+}
+
+func headerUint8(headers map[string]string, name string) (uint8, error) {
+	v, err := strconv.ParseUint(headers[name], 10, 8)
+	if err != nil {
+		return 0, fmt.Errorf("%w: header %s: %v", ErrKeyFile, name, err)
+	}
+	return uint8(v), nil
+}
+
+// ReadKeyFile reads a public key, private key share or share commitments
+// file.
+func ReadKeyFile(path string) (*KeyFile, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("%w: no PEM block in %s", ErrKeyFile, path)
+	}
+
+	kf := &KeyFile{Type: block.Type}
+	if kf.T, err = headerUint8(block.Headers, "T"); err != nil {
+		return nil, err
+	}
+	if kf.N, err = headerUint8(block.Headers, "N"); err != nil {
+		return nil, err
+	}
+	if kf.Params, err = thmldsa44.GetThresholdParams(kf.T, kf.N); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrKeyFile, err)
+	}
+
+	switch block.Type {
+	case PublicKeyPEMType:
+		kf.PK = new(thmldsa44.PublicKey)
+		if err := kf.PK.UnmarshalBinary(block.Bytes); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrKeyFile, err)
+		}
+
+	case PrivateKeyPEMType:
+		if kf.Party, err = headerUint8(block.Headers, "Party"); err != nil {
+			return nil, err
+		}
+		if len(block.Bytes) != kf.Params.PrivateKeySize() {
+			return nil, fmt.Errorf("%w: private key share of %d bytes instead of %d",
+				ErrKeyFile, len(block.Bytes), kf.Params.PrivateKeySize())
+		}
+		kf.SK = new(thmldsa44.PrivateKey)
+		kf.SK.Unpack(block.Bytes)
+		if kf.SK.Id != kf.Party || kf.Party >= kf.N {
+			return nil, fmt.Errorf("%w: wrong party %d", ErrKeyFile, kf.SK.Id)
+		}
+
+	case ShareCommitmentsPEMType:
+		kf.Commitments = new(thmldsa44.ShareCommitments)
+		if err := kf.Commitments.UnmarshalBinary(block.Bytes); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrKeyFile, err)
+		}
+
+	default:
+		return nil, fmt.Errorf("%w: unknown type %q", ErrKeyFile, block.Type)
+	}
+	return kf, nil
+}
+
+// ReadPublicKey reads the public key file at path.
+func ReadPublicKey(path string) (*thmldsa44.PublicKey, *thmldsa44.ThresholdParams, error) {
+	kf, err := ReadKeyFile(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	if kf.PK == nil {
+		return nil, nil, fmt.Errorf("%w: %s is not a public key", ErrKeyFile, path)
+	}
+	return kf.PK, kf.Params, nil
+}
+
+// ReadPrivateKey reads the private key share file at path.
+func ReadPrivateKey(path string) (*thmldsa44.PrivateKey, *thmldsa44.ThresholdParams, error) {
+	kf, err := ReadKeyFile(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	if kf.SK == nil {
+		return nil, nil, fmt.Errorf("%w: %s is not a private key share", ErrKeyFile, path)
+	}
+	return kf.SK, kf.Params, nil
+}
+
+// WriteIdentity writes the Ed25519 identity key of party to a new file at
+// path, only readable by its owner. Every party generates its own, and only
+// hands out the public key for the peers file of the others.
+func WriteIdentity(path string, party uint8, key ed25519.PrivateKey) error {
+	headers := map[string]string{"Party": strconv.Itoa(int(party))}
+	return writePEM(path, 0o600, IdentityPEMType, headers, key.Seed())
+}
+
+// ReadIdentity reads the identity key file at path, and returns the key and
+// the party it belongs to.
+func ReadIdentity(path string) (ed25519.PrivateKey, uint8, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, 0, err
+	}
+	block, _ := pem.Decode(data)
+	if block == nil || block.Type != IdentityPEMType {
+		return nil, 0, fmt.Errorf("%w: %s is not an identity key", ErrKeyFile, path)
+	}
+	party, err := headerUint8(block.Headers, "Party")
+	if err != nil {
+		return nil, 0, err
+	}
+	if len(block.Bytes) != ed25519.SeedSize {
+		return nil, 0, fmt.Errorf("%w: identity key of %d bytes instead of %d",
+			ErrKeyFile, len(block.Bytes), ed25519.SeedSize)
+	}
+	return ed25519.NewKeyFromSeed(block.Bytes), party, nil
+}
+
+// ReadPeers reads the public identity keys of the parties from the file at
+// path, holding the hex-encoded key of party i on its i-th line. Empty lines
+// and lines starting with # are skipped.
+func ReadPeers(path string) ([]ed25519.PublicKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var peers []ed25519.PublicKey
+	s := bufio.NewScanner(bytes.NewReader(data))
+	for s.Scan() {
+		line := bytes.TrimSpace(s.Bytes())
+		if len(line) == 0 || line[0] == '#' {
+			continue
+		}
+		key, err := hex.DecodeString(string(line))
+		if err != nil || len(key) != ed25519.PublicKeySize {
+			return nil, fmt.Errorf("%w: identity key %d of %s", ErrKeyFile, len(peers), path)
+		}
+		peers = append(peers, key)
+	}
+	return peers, s.Err()
+}
@@ -1,6 +1,7 @@
 package sign
 
 import (
+	"context"
 	"encoding/binary"
 	"fmt"
 	"log"
@@ -28,6 +29,7 @@ func LocalThresholdDilithiumRun(iter int) {
 	avgSignRound3 := make([]time.Duration, Threshold)
 	avgCombineDur := time.Duration(0)
 	avgPartyRoundBytes := make([][3]int, Threshold)
+	var rejections thmldsa44.Stats
 
 	for i := uint64(0); i < uint64(iter); i++ {
 		log.Println("START OF RUN:", i)
@@ -110,8 +112,9 @@ func LocalThresholdDilithiumRun(iter int) {
 			}
 
 			start = time.Now()
-			ok := thmldsa44.Combine(pk, msg[:], ctx[:], msgs2, msgs3, sig[:], params)
+			ok, reasons := thmldsa44.CombineWithReport(pk, msg[:], ctx[:], msgs2, msgs3, sig[:], params)
 			totalCombineDur += time.Since(start)
+			rejections.ObserveAttempt(reasons)
 			combineAttemptCount++
 			if !ok {
 				continue
@@ -159,6 +162,7 @@ func LocalThresholdDilithiumRun(iter int) {
 		fmt.Printf("[TIME] VERIFICATION %s for %d out of %d parties \n", time.Since(start), Threshold, K)
 	}
 	fmt.Printf("\n=== AVERAGED STATS OVER %d RUNS ===\n", iter)
+	fmt.Printf("Rejections: %s\n", &rejections)
 	for p := 0; p < Threshold; p++ {
 		fmt.Printf("Party %d - Avg Round 1 Commitment Time: %s\n", p, avgSignRound1[p]/time.Duration(iter))
 		fmt.Printf("Party %d - Avg Round 2 Reveal Time: %s\n", p, avgSignRound2[p]/time.Duration(iter))
@@ -192,3 +196,44 @@ func printAveragedStats(phaseName string, totalDurations map[int]float64, x int)
 	fmt.Printf("  Median: %.3f ms\n", median)
 	fmt.Printf("  Standard Deviation: %.3f ms\n", stddev)
 }
+
+// Test threshold dilithium with the robust coordinator, which picks signer
+// sets among all K parties
+func LocalRobustThresholdDilithiumRun(iter int) {
+	var seed [32]byte
+
+	params, err := thmldsa44.GetThresholdParams(uint8(Threshold), uint8(K))
+	if err != nil {
+		panic("Error: failed to get threshold parameters.")
+	}
+
+	avgSignDur := time.Duration(0)
+	var rejections thmldsa44.Stats
+	for i := uint64(0); i < uint64(iter); i++ {
+		log.Println("START OF RUN:", i)
+
+		binary.LittleEndian.PutUint64(seed[:], i)
+		pk, sks := thmldsa44.NewThresholdKeysFromSeed(&seed, params)
+		coordinator := NewLocalCoordinator(pk, sks, params)
+		coordinator.Observer = &rejections
+
+		start := time.Now()
+		sig, err := coordinator.Sign(context.Background(), []byte("message"), nil)
+		signDur := time.Since(start)
+		avgSignDur += signDur
+		for _, f := range coordinator.Dropped {
+			fmt.Printf("Dropped %s\n", f)
+		}
+		if err != nil {
+			fmt.Printf("Error: signing failed: %v\n", err)
+			continue
+		}
+		fmt.Printf("[TIME] SIGNING %s for %d out of %d setting \n", signDur, Threshold, K)
+
+		if !thmldsa44.Verify(pk, []byte("message"), nil, sig) {
+			fmt.Println("Error: verification failed.")
+		}
+	}
+	fmt.Printf("Avg Signing Time per Run: %s\n", avgSignDur/time.Duration(iter))
+	fmt.Printf("Rejections: %s\n", &rejections)
+}
@@ -0,0 +1,162 @@
+package sign
+
+import (
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+
+	"github.com/cloudflare/circl/sign/thmldsa/thmldsa44"
+)
+
+// Session describes a signing session, on which all signers and the
+// combiner must agree.
+type Session struct {
+	PK     *thmldsa44.PublicKey
+	Params *thmldsa44.ThresholdParams
+
+	// Act is the signer set as a bitmask of T parties.
+	Act uint8
+
+	Msg, Ctx []byte
+
+	// Nonce is drawn afresh by the coordinator of every session, so that
+	// signing the same message twice does not give the same session ID.
+	Nonce [NonceSize]byte
+
+	// MaxAttempts bounds the number of attempts before giving up.
+	MaxAttempts int
+}
+
+// NonceSize is the size of the nonce of a session.
+const NonceSize = 16
+
+// ErrSignerSet is returned when the signer set of a session does not hold
+// exactly T parties.
+var ErrSignerSet = errors.New("signer set must hold exactly T parties")
+
+func (s *Session) check() error {
+	ids := members(s.Act)
+	if len(ids) != int(s.Params.T) || ids[len(ids)-1] >= s.Params.N {
+		return ErrSignerSet
+	}
+	return nil
+}
+
+// ID returns the identifier of the session, derived from the public key,
+// the nonce, the signer set, the context and the message.
+func (s *Session) ID() [32]byte {
+	h := sha256.New()
+	h.Write(s.PK.Bytes())
+	h.Write(s.Nonce[:])
+	h.Write([]byte{s.Act, byte(len(s.Ctx))})
+	h.Write(s.Ctx)
+	h.Write(s.Msg)
+	var id [32]byte
+	h.Sum(id[:0])
+	return id
+}
+
+// exchange broadcasts msg as party id and returns the messages of every
+// member of act for the round, in member order.
+func exchange(ctx context.Context, tr Transport, act uint8, attempt, round int, id uint8, msg []byte, size int) ([][]byte, error) {
+	if err := tr.Broadcast(ctx, attempt, round, id, msg); err != nil {
+		return nil, err
+	}
+	return receiveAll(ctx, tr, act, attempt, round, id, msg, size)
+}
+
+func receiveAll(ctx context.Context, tr Transport, act uint8, attempt, round int, id uint8, msg []byte, size int) ([][]byte, error) {
+	ids := members(act)
+	msgs := make([][]byte, len(ids))
+	for j, from := range ids {
+		if from == id && msg != nil {
+			msgs[j] = msg
+			continue
+		}
+		m, err := tr.Receive(ctx, attempt, round, from)
+		if err != nil {
+			return nil, fmt.Errorf("attempt %d, round %d, party %d: %w", attempt, round, from, err)
+		}
+		if len(m) != size {
+			return nil, Fault{from, round, fmt.Errorf("message of %d bytes instead of %d", len(m), size)}
+		}
+		msgs[j] = m
+	}
+	return msgs, nil
+}
+
+// RunParty runs the session as party sk over tr until a signature is
+// produced, which it returns together with the number of attempts.
+//
+// Every party combines the responses itself to learn whether to carry on
+// with another attempt, so all of them end up with the signature.
+func RunParty(ctx context.Context, sk *thmldsa44.PrivateKey, s *Session, tr Transport) ([]byte, int, error) {
+	if err := s.check(); err != nil {
+		return nil, 0, err
+	}
+	if s.Act&(1<<sk.Id) == 0 {
+		return nil, 0, fmt.Errorf("party %d is not in the signer set", sk.Id)
+	}
+
+	sig := make([]byte, thmldsa44.SignatureSize)
+	for attempt := 0; attempt < s.MaxAttempts; attempt++ {
+		msg1, st1, err := thmldsa44.Round1(sk, s.Params)
+		if err != nil {
+			return nil, attempt, err
+		}
+		msgs1, err := exchange(ctx, tr, s.Act, attempt, 1, sk.Id, msg1, len(msg1))
+		if err != nil {
+			return nil, attempt, err
+		}
+
+		msg2, st2, err := thmldsa44.Round2(sk, s.Act, s.Msg, s.Ctx, msgs1, &st1, s.Params)
+		if err != nil {
+			return nil, attempt, err
+		}
+		msgs2, err := exchange(ctx, tr, s.Act, attempt, 2, sk.Id, msg2, s.Params.CommitmentSize())
+		if err != nil {
+			return nil, attempt, err
+		}
+
+		msg3, err := thmldsa44.Round3(sk, msgs2, &st1, &st2, s.Params)
+		if err != nil {
+			return nil, attempt, err
+		}
+		msgs3, err := exchange(ctx, tr, s.Act, attempt, 3, sk.Id, msg3, s.Params.ResponseSize())
+		if err != nil {
+			return nil, attempt, err
+		}
+
+		if thmldsa44.Combine(s.PK, s.Msg, s.Ctx, msgs2, msgs3, sig, s.Params) {
+			return sig, attempt + 1, nil
+		}
+	}
+	return nil, s.MaxAttempts, ErrTooManyAttempts
+}
+
+// CombineSession waits for the messages of the signers on tr and combines
+// them, attempt after attempt, until a signature is produced. It returns the
+// signature and the number of attempts.
+func CombineSession(ctx context.Context, s *Session, tr Transport) ([]byte, int, error) {
+	if err := s.check(); err != nil {
+		return nil, 0, err
+	}
+
+	sig := make([]byte, thmldsa44.SignatureSize)
+	for attempt := 0; attempt < s.MaxAttempts; attempt++ {
+		msgs2, err := receiveAll(ctx, tr, s.Act, attempt, 2, 0, nil, s.Params.CommitmentSize())
+		if err != nil {
+			return nil, attempt, err
+		}
+		msgs3, err := receiveAll(ctx, tr, s.Act, attempt, 3, 0, nil, s.Params.ResponseSize())
+		if err != nil {
+			return nil, attempt, err
+		}
+
+		if thmldsa44.Combine(s.PK, s.Msg, s.Ctx, msgs2, msgs3, sig, s.Params) {
+			return sig, attempt + 1, nil
+		}
+	}
+	return nil, s.MaxAttempts, ErrTooManyAttempts
+}
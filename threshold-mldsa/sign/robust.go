@@ -0,0 +1,324 @@
+package sign
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/cloudflare/circl/sign/thmldsa/thmldsa44"
+)
+
+var (
+	// ErrNotEnoughSigners is returned when fewer than T parties are left
+	// after dropping the faulty ones.
+	ErrNotEnoughSigners = errors.New("fewer than T honest parties left")
+
+	// ErrTooManyAttempts is returned when no signature was produced within
+	// the maximum number of attempts.
+	ErrTooManyAttempts = errors.New("too many signing attempts")
+
+	errTimeout = errors.New("timeout")
+)
+
+// Signer is one of the N parties as seen by the coordinator of a signing
+// session. Its methods run the corresponding round of thmldsa44 for the
+// current attempt, remote signers forwarding the call over the network.
+//
+// ctx is cancelled once the round times out or the session ends, and
+// implementations must then return promptly: the coordinator does not wait
+// for them, so a call ignoring ctx keeps its goroutine until it returns.
+type Signer interface {
+	Round1(ctx context.Context) ([]byte, error)
+	Round2(ctx context.Context, act uint8, msg, mctx []byte, msgs1 [][]byte) ([]byte, error)
+	Round3(ctx context.Context, msgs2 [][]byte) ([]byte, error)
+}
+
+// LocalSigner is a Signer running in-process with its own key share.
+type LocalSigner struct {
+	SK     *thmldsa44.PrivateKey
+	Params *thmldsa44.ThresholdParams
+
+	strd1 thmldsa44.StRound1
+	strd2 thmldsa44.StRound2
+}
+
+func (s *LocalSigner) Round1(ctx context.Context) ([]byte, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	msg1, strd1, err := thmldsa44.Round1(s.SK, s.Params)
+	s.strd1 = strd1
+	return msg1, err
+}
+
+func (s *LocalSigner) Round2(ctx context.Context, act uint8, msg, mctx []byte, msgs1 [][]byte) ([]byte, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	msg2, strd2, err := thmldsa44.Round2(s.SK, act, msg, mctx, msgs1, &s.strd1, s.Params)
+	s.strd2 = strd2
+	return msg2, err
+}
+
+func (s *LocalSigner) Round3(ctx context.Context, msgs2 [][]byte) ([]byte, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return thmldsa44.Round3(s.SK, msgs2, &s.strd1, &s.strd2, s.Params)
+}
+
+// Fault records a party dropped by the coordinator.
+type Fault struct {
+	Party uint8
+	Round int
+	Err   error
+}
+
+func (f Fault) Error() string {
+	return fmt.Sprintf("party %d, round %d: %v", f.Party, f.Round, f.Err)
+}
+
+// Coordinator drives robust signing sessions: parties that time out or send
+// malformed or inconsistent messages are dropped, and signing carries on
+// with another signer set of T parties among the remaining ones.
+type Coordinator struct {
+	PK     *thmldsa44.PublicKey
+	Params *thmldsa44.ThresholdParams
+
+	// Signers[i] is party i, or nil if it is known to be unavailable.
+	Signers []Signer
+
+	// PartialKeys, if not nil, are used to find the parties whose
+	// responses do not match their commitments when Combine fails.
+	// Without them, such parties are only avoided by changing the signer
+	// set after AttemptsPerSet failed attempts.
+	PartialKeys *thmldsa44.PartialKeys
+
+	// RoundTimeout bounds the time each party may take to answer a round.
+	RoundTimeout time.Duration
+
+	// AttemptsPerSet is the number of failed attempts after which the
+	// signer set is changed, as responses that do not combine may come
+	// from a malicious party.
+	AttemptsPerSet int
+
+	// MaxAttempts bounds the total number of attempts of a session.
+	MaxAttempts int
+
+	// Observer, if not nil, is notified of the outcome of every attempt
+	// that reached Combine.
+	Observer thmldsa44.Observer
+
+	// Dropped holds the parties dropped so far. They are never selected
+	// again by this coordinator.
+	Dropped []Fault
+}
+
+// NewCoordinator returns a coordinator with default timeouts and attempt
+// limits for the given parties.
+func NewCoordinator(pk *thmldsa44.PublicKey, params *thmldsa44.ThresholdParams, signers []Signer) *Coordinator {
+	return &Coordinator{
+		PK:             pk,
+		Params:         params,
+		Signers:        signers,
+		RoundTimeout:   10 * time.Second,
+		AttemptsPerSet: 64,
+		MaxAttempts:    570,
+	}
+}
+
+// NewLocalCoordinator returns a coordinator running all parties of sks
+// in-process.
+func NewLocalCoordinator(pk *thmldsa44.PublicKey, sks []thmldsa44.PrivateKey, params *thmldsa44.ThresholdParams) *Coordinator {
+	signers := make([]Signer, len(sks))
+	for i := range sks {
+		signers[i] = &LocalSigner{SK: &sks[i], Params: params}
+	}
+	c := NewCoordinator(pk, params, signers)
+	c.PartialKeys = thmldsa44.NewPartialKeys(sks, params)
+	return c
+}
+
+func (c *Coordinator) drop(party uint8, round int, err error) {
+	c.Dropped = append(c.Dropped, Fault{party, round, err})
+	c.Signers[party] = nil
+}
+
+// signerSets returns every set of T available parties as a bitmask.
+func (c *Coordinator) signerSets() []uint8 {
+	var sets []uint8
+	for act := 0; act < 1<<len(c.Signers); act++ {
+		count := 0
+		ok := true
+		for i := range c.Signers {
+			if act&(1<<i) == 0 {
+				continue
+			}
+			if c.Signers[i] == nil {
+				ok = false
+				break
+			}
+			count++
+		}
+		if ok && count == int(c.Params.T) {
+			sets = append(sets, uint8(act))
+		}
+	}
+	return sets
+}
+
+// members returns the IDs of the parties of act in increasing order.
+func members(act uint8) []uint8 {
+	var ids []uint8
+	for i := uint8(0); i < 8; i++ {
+		if act&(1<<i) != 0 {
+			ids = append(ids, i)
+		}
+	}
+	return ids
+}
+
+// runRound calls f for every member of act concurrently and collects their
+// messages in member order. Parties that fail, time out or answer with a
+// message of the wrong length are returned as faults. The context given to
+// f is cancelled as soon as the party times out.
+func (c *Coordinator) runRound(ctx context.Context, act uint8, round, size int, f func(context.Context, Signer) ([]byte, error)) ([][]byte, []Fault) {
+	type result struct {
+		msg []byte
+		err error
+	}
+
+	ids := members(act)
+	results := make([]chan result, len(ids))
+	for j, id := range ids {
+		results[j] = make(chan result, 1)
+		rctx, cancel := context.WithTimeout(ctx, c.RoundTimeout)
+		defer cancel()
+
+		done := make(chan result, 1)
+		go func(s Signer) {
+			msg, err := f(rctx, s)
+			done <- result{msg, err}
+		}(c.Signers[id])
+		go func(ch chan result) {
+			select {
+			case r := <-done:
+				ch <- r
+			case <-rctx.Done():
+				ch <- result{nil, errTimeout}
+			}
+			cancel()
+		}(results[j])
+	}
+
+	msgs := make([][]byte, len(ids))
+	var faults []Fault
+	for j, id := range ids {
+		r := <-results[j]
+		switch {
+		case r.err != nil:
+			faults = append(faults, Fault{id, round, r.err})
+		case len(r.msg) != size:
+			faults = append(faults, Fault{id, round, fmt.Errorf("message of %d bytes instead of %d", len(r.msg), size)})
+		default:
+			msgs[j] = r.msg
+		}
+	}
+	return msgs, faults
+}
+
+// attempt runs one signing attempt with the signer set act. It returns
+// whether a signature was written to sig, and the parties found faulty.
+func (c *Coordinator) attempt(ctx context.Context, act uint8, msg, mctx, sig []byte) (bool, []Fault) {
+	ids := members(act)
+
+	msgs1, faults := c.runRound(ctx, act, 1, thmldsa44.CommitmentHashSize, func(ctx context.Context, s Signer) ([]byte, error) {
+		return s.Round1(ctx)
+	})
+	if len(faults) > 0 {
+		return false, faults
+	}
+
+	msgs2, faults := c.runRound(ctx, act, 2, c.Params.CommitmentSize(), func(ctx context.Context, s Signer) ([]byte, error) {
+		return s.Round2(ctx, act, msg, mctx, msgs1)
+	})
+	for j, id := range ids {
+		if msgs2[j] != nil && !thmldsa44.CheckCommitment(c.PK, id, msgs1[j], msgs2[j]) {
+			faults = append(faults, Fault{id, 2, thmldsa44.ErrWrongCommitment})
+		}
+	}
+	if len(faults) > 0 {
+		return false, faults
+	}
+
+	msgs3, faults := c.runRound(ctx, act, 3, c.Params.ResponseSize(), func(ctx context.Context, s Signer) ([]byte, error) {
+		return s.Round3(ctx, msgs2)
+	})
+	if len(faults) > 0 {
+		return false, faults
+	}
+
+	ok, reasons := thmldsa44.CombineWithReport(c.PK, msg, mctx, msgs2, msgs3, sig, c.Params)
+	if c.Observer != nil {
+		c.Observer.ObserveAttempt(reasons)
+	}
+	if ok || c.PartialKeys == nil {
+		return ok, nil
+	}
+
+	// Honest parties only fail to combine by rejecting their own responses,
+	// so look for a response that does not match its commitment.
+	for j, id := range ids {
+		if err := thmldsa44.VerifyResponse(c.PK, c.PartialKeys, act, id, msg, mctx, msgs2, msgs3[j], c.Params); err != nil {
+			faults = append(faults, Fault{id, 3, err})
+		}
+	}
+	return false, faults
+}
+
+// Sign runs attempts until a signature of msg with context mctx is produced,
+// dropping the parties found faulty along the way.
+//
+// Returns ErrNotEnoughSigners once fewer than T parties are left, or
+// ErrTooManyAttempts after MaxAttempts attempts. The faults found are
+// available in c.Dropped in both cases.
+func (c *Coordinator) Sign(ctx context.Context, msg, mctx []byte) ([]byte, error) {
+	sig := make([]byte, thmldsa44.SignatureSize)
+	failures := make(map[uint8]int)
+
+	for attempts := 0; attempts < c.MaxAttempts; attempts++ {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		sets := c.signerSets()
+		if len(sets) == 0 {
+			return nil, ErrNotEnoughSigners
+		}
+
+		// Pick the set with the fewest failed attempts, rotating among sets
+		// once AttemptsPerSet attempts failed with each of them.
+		act := sets[0]
+		for _, s := range sets {
+			if failures[s]/c.AttemptsPerSet < failures[act]/c.AttemptsPerSet {
+				act = s
+			}
+		}
+
+		ok, faults := c.attempt(ctx, act, msg, mctx, sig)
+		if ok {
+			return sig, nil
+		}
+		if err := ctx.Err(); err != nil {
+			// Parties did not answer because the session was cancelled
+			return nil, err
+		}
+		for _, f := range faults {
+			c.drop(f.Party, f.Round, f.Err)
+		}
+		failures[act]++
+	}
+
+	return nil, ErrTooManyAttempts
+}
@@ -0,0 +1,128 @@
+package sign
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/cloudflare/circl/sign/thmldsa/thmldsa44"
+)
+
+// faultySigner is a LocalSigner replacing its message of one round.
+type faultySigner struct {
+	LocalSigner
+	round int
+	fault func(ctx context.Context, msg []byte) ([]byte, error)
+}
+
+func (s *faultySigner) apply(ctx context.Context, round int, msg []byte, err error) ([]byte, error) {
+	if err != nil || round != s.round {
+		return msg, err
+	}
+	return s.fault(ctx, msg)
+}
+
+func (s *faultySigner) Round1(ctx context.Context) ([]byte, error) {
+	msg, err := s.LocalSigner.Round1(ctx)
+	return s.apply(ctx, 1, msg, err)
+}
+
+func (s *faultySigner) Round2(ctx context.Context, act uint8, msg, mctx []byte, msgs1 [][]byte) ([]byte, error) {
+	msg2, err := s.LocalSigner.Round2(ctx, act, msg, mctx, msgs1)
+	return s.apply(ctx, 2, msg2, err)
+}
+
+func (s *faultySigner) Round3(ctx context.Context, msgs2 [][]byte) ([]byte, error) {
+	msg, err := s.LocalSigner.Round3(ctx, msgs2)
+	return s.apply(ctx, 3, msg, err)
+}
+
+// newTestCoordinator returns a local coordinator for a 2-out-of-3 key, with
+// party 1, which belongs to the first signer set, replaced by a faulty one.
+func newTestCoordinator(t *testing.T, round int, fault func(ctx context.Context, msg []byte) ([]byte, error)) (*Coordinator, *thmldsa44.PublicKey) {
+	t.Helper()
+	params, err := thmldsa44.GetThresholdParams(2, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var seed [thmldsa44.SeedSize]byte
+	binary.LittleEndian.PutUint64(seed[:], uint64(round))
+	pk, sks := thmldsa44.NewThresholdKeysFromSeed(&seed, params)
+
+	c := NewLocalCoordinator(pk, sks, params)
+	c.Signers[1] = &faultySigner{LocalSigner{SK: &sks[1], Params: params}, round, fault}
+	return c, pk
+}
+
+func checkDropped(t *testing.T, c *Coordinator, pk *thmldsa44.PublicKey, sig []byte, err error, round int, want error) {
+	t.Helper()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !thmldsa44.Verify(pk, []byte("message"), nil, sig) {
+		t.Fatal("invalid signature")
+	}
+	if len(c.Dropped) != 1 {
+		t.Fatalf("dropped %v instead of party 1", c.Dropped)
+	}
+	if f := c.Dropped[0]; f.Party != 1 || f.Round != round || (want != nil && !errors.Is(f.Err, want)) {
+		t.Fatalf("dropped %v instead of party 1 in round %d", f, round)
+	}
+}
+
+func TestCoordinatorTimeout(t *testing.T) {
+	returned := make(chan struct{})
+	c, pk := newTestCoordinator(t, 1, func(ctx context.Context, _ []byte) ([]byte, error) {
+		<-ctx.Done()
+		close(returned)
+		return nil, ctx.Err()
+	})
+	c.RoundTimeout = 100 * time.Millisecond
+
+	sig, err := c.Sign(context.Background(), []byte("message"), nil)
+	checkDropped(t, c, pk, sig, err, 1, errTimeout)
+
+	// The hung signer is told to give up
+	select {
+	case <-returned:
+	case <-time.After(time.Second):
+		t.Fatal("context of the timed out signer not cancelled")
+	}
+}
+
+func TestCoordinatorBadMessages(t *testing.T) {
+	for _, tc := range []struct {
+		name  string
+		round int
+		fault func(ctx context.Context, msg []byte) ([]byte, error)
+		want  error
+	}{
+		{"short round 1", 1, func(_ context.Context, msg []byte) ([]byte, error) {
+			return msg[:len(msg)-1], nil
+		}, nil},
+		{"round 2 not matching round 1", 2, func(_ context.Context, msg []byte) ([]byte, error) {
+			bad := append([]byte{}, msg...)
+			bad[0] ^= 1
+			return bad, nil
+		}, thmldsa44.ErrWrongCommitment},
+		{"round 3 of the right length", 3, func(_ context.Context, msg []byte) ([]byte, error) {
+			bad := make([]byte, len(msg))
+			_, err := rand.Read(bad)
+			return bad, err
+		}, thmldsa44.ErrWrongResponse},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			c, pk := newTestCoordinator(t, tc.round, tc.fault)
+
+			// Never change the signer set on failed attempts, so that the
+			// faulty party must be found from its messages
+			c.AttemptsPerSet = c.MaxAttempts
+
+			sig, err := c.Sign(context.Background(), []byte("message"), nil)
+			checkDropped(t, c, pk, sig, err, tc.round, tc.want)
+		})
+	}
+}
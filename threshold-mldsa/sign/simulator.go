@@ -0,0 +1,315 @@
+package sign
+
+import (
+	"bench"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"log"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/cloudflare/circl/sign/thmldsa/thmldsa44"
+)
+
+// LinkConfig models a directed link between two parties.
+type LinkConfig struct {
+	// Latency is the one-way propagation delay.
+	Latency time.Duration
+
+	// Bandwidth in bytes per second, or 0 for unlimited. Messages sent on
+	// the same link are serialized one after another.
+	Bandwidth float64
+
+	// Loss is the probability that a transmission is lost.
+	Loss float64
+}
+
+// transmitTime returns the time taken to put size bytes on the link.
+func (l LinkConfig) transmitTime(size int) time.Duration {
+	if l.Bandwidth <= 0 {
+		return 0
+	}
+	return time.Duration(float64(size) / l.Bandwidth * float64(time.Second))
+}
+
+// NetworkConfig describes the simulated network.
+type NetworkConfig struct {
+	// Default applies to every link not listed in Links.
+	Default LinkConfig
+
+	// Links overrides the configuration of the link from [0] to [1].
+	Links map[[2]uint8]LinkConfig
+
+	// RetransmitTimeout is the time after which a lost transmission is sent
+	// again. It must not be 0 if any link loses messages, as a message lost
+	// for good would leave its recipient waiting forever.
+	RetransmitTimeout time.Duration
+
+	// Seed of the source of message loss
+	Seed int64
+}
+
+// ErrNoRetransmit is returned for a network losing messages without
+// retransmitting them.
+var ErrNoRetransmit = errors.New("lossy links need a retransmission timeout")
+
+func (c *NetworkConfig) check() error {
+	links := []LinkConfig{c.Default}
+	for _, l := range c.Links {
+		links = append(links, l)
+	}
+	for _, l := range links {
+		if l.Loss < 0 || l.Loss >= 1 {
+			return fmt.Errorf("loss probability %v not in [0, 1)", l.Loss)
+		}
+		if l.Loss > 0 && c.RetransmitTimeout <= 0 {
+			return ErrNoRetransmit
+		}
+	}
+	return nil
+}
+
+func (c *NetworkConfig) link(from, to uint8) LinkConfig {
+	if l, ok := c.Links[[2]uint8{from, to}]; ok {
+		return l
+	}
+	return c.Default
+}
+
+// Network is a simulated network connecting in-process parties with
+// channels. Endpoint returns the Transport of each party.
+type Network struct {
+	cfg NetworkConfig
+
+	mu     sync.Mutex
+	rng    *rand.Rand
+	freeAt map[[2]uint8]time.Time // when each link is done sending
+	inbox  map[uint8]map[messageID]chan []byte
+	peers  []uint8
+
+	// Counters over the lifetime of the network
+	sent, lost, bytes int
+}
+
+// NewNetwork returns a simulated network between the given parties.
+func NewNetwork(peers []uint8, cfg NetworkConfig) (*Network, error) {
+	if err := cfg.check(); err != nil {
+		return nil, err
+	}
+	n := &Network{
+		cfg:    cfg,
+		rng:    rand.New(rand.NewSource(cfg.Seed)),
+		freeAt: make(map[[2]uint8]time.Time),
+		inbox:  make(map[uint8]map[messageID]chan []byte),
+		peers:  peers,
+	}
+	for _, p := range peers {
+		n.inbox[p] = make(map[messageID]chan []byte)
+	}
+	return n, nil
+}
+
+// slot returns the channel on which party to receives message id. The
+// caller must hold n.mu.
+func (n *Network) slot(to uint8, id messageID) chan []byte {
+	ch, ok := n.inbox[to][id]
+	if !ok {
+		ch = make(chan []byte, 1)
+		n.inbox[to][id] = ch
+	}
+	return ch
+}
+
+// send schedules the delivery of msg from party from to party to.
+func (n *Network) send(from, to uint8, id messageID, msg []byte) {
+	link := n.cfg.link(from, to)
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	// The message is put on the link once the previous ones are sent. Each
+	// lost transmission is sent again a retransmission timeout after it
+	// ended, taking the link again, and later messages wait for the last
+	// one as on an ordered stream.
+	now := time.Now()
+	start := n.freeAt[[2]uint8{from, to}]
+	if start.Before(now) {
+		start = now
+	}
+	n.sent++
+	n.bytes += len(msg)
+	for n.rng.Float64() < link.Loss {
+		n.lost++
+		n.bytes += len(msg)
+		start = start.Add(link.transmitTime(len(msg)) + n.cfg.RetransmitTimeout)
+	}
+	end := start.Add(link.transmitTime(len(msg)))
+	n.freeAt[[2]uint8{from, to}] = end
+	delay := end.Sub(now) + link.Latency
+
+	ch := n.slot(to, id)
+	time.AfterFunc(delay, func() {
+		ch <- msg
+	})
+}
+
+// Counters returns the number of messages sent, the number of lost
+// transmissions and the number of bytes sent so far, retransmissions
+// included.
+func (n *Network) Counters() (sent, lost, bytes int) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return n.sent, n.lost, n.bytes
+}
+
+// Endpoint returns the Transport of party id.
+func (n *Network) Endpoint(id uint8) Transport {
+	return &endpoint{n, id}
+}
+
+type endpoint struct {
+	net *Network
+	id  uint8
+}
+
+func (e *endpoint) Broadcast(ctx context.Context, attempt, round int, from uint8, msg []byte) error {
+	id := messageID{attempt, round, from}
+	for _, to := range e.net.peers {
+		if to != e.id {
+			e.net.send(e.id, to, id, msg)
+		}
+	}
+	return nil
+}
+
+func (e *endpoint) Receive(ctx context.Context, attempt, round int, from uint8) ([]byte, error) {
+	e.net.mu.Lock()
+	ch := e.net.slot(e.id, messageID{attempt, round, from})
+	e.net.mu.Unlock()
+
+	select {
+	case msg := <-ch:
+		ch <- msg // keep it for later calls
+		return msg, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func (e *endpoint) Close() error {
+	return nil
+}
+
+// SimResult holds the outcome of a simulated signing session.
+type SimResult struct {
+	// Latency is the wall-clock time until every party got the signature.
+	Latency time.Duration
+
+	Attempts int
+
+	// Messages sent, transmissions lost and bytes sent on the network
+	Messages, Lost, Bytes int
+}
+
+// Simulate runs a signing session with every party of s.Act in its own
+// goroutine, connected by a network simulated according to cfg.
+func Simulate(ctx context.Context, sks []thmldsa44.PrivateKey, s *Session, cfg NetworkConfig) (*SimResult, []byte, error) {
+	if err := s.check(); err != nil {
+		return nil, nil, err
+	}
+	ids := members(s.Act)
+	network, err := NewNetwork(ids, cfg)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	type result struct {
+		sig      []byte
+		attempts int
+		err      error
+	}
+	results := make(chan result, len(ids))
+
+	start := time.Now()
+	for _, id := range ids {
+		go func(sk *thmldsa44.PrivateKey) {
+			sig, attempts, err := RunParty(ctx, sk, s, network.Endpoint(sk.Id))
+			if err != nil {
+				err = fmt.Errorf("party %d: %w", sk.Id, err)
+			}
+			results <- result{sig, attempts, err}
+		}(&sks[id])
+	}
+
+	var sig []byte
+	res := &SimResult{}
+	var errs []error
+	for range ids {
+		r := <-results
+		if r.err != nil {
+			errs = append(errs, r.err)
+			continue
+		}
+		sig = r.sig
+		res.Attempts = r.attempts
+	}
+	res.Latency = time.Since(start)
+	res.Messages, res.Lost, res.Bytes = network.Counters()
+
+	if len(errs) > 0 {
+		return res, nil, errors.Join(errs...)
+	}
+	return res, sig, nil
+}
+
+// LocalSimulatedRun runs iter simulated signing sessions of Threshold out of
+// K parties over links configured as link, and prints the end-to-end
+// signing latency.
+func LocalSimulatedRun(iter int, link LinkConfig, rto time.Duration) {
+	var seed [32]byte
+
+	params, err := thmldsa44.GetThresholdParams(uint8(Threshold), uint8(K))
+	if err != nil {
+		panic("Error: failed to get threshold parameters.")
+	}
+
+	var latencies []time.Duration
+	attempts := 0
+	for i := 0; i < iter; i++ {
+		log.Println("START OF RUN:", i)
+		binary.LittleEndian.PutUint64(seed[:], uint64(i))
+		pk, sks := thmldsa44.NewThresholdKeysFromSeed(&seed, params)
+
+		s := &Session{
+			PK:          pk,
+			Params:      params,
+			Act:         uint8((1 << Threshold) - 1),
+			Msg:         []byte("message"),
+			MaxAttempts: 570,
+		}
+		cfg := NetworkConfig{Default: link, RetransmitTimeout: rto, Seed: int64(i)}
+		res, sig, err := Simulate(context.Background(), sks, s, cfg)
+		if err != nil {
+			fmt.Printf("Error: signing failed: %v\n", err)
+			continue
+		}
+		if !thmldsa44.Verify(pk, s.Msg, nil, sig) {
+			fmt.Println("Error: verification failed.")
+		}
+
+		fmt.Printf("[TIME] SIGNING %s in %d attempts, %d messages (%d lost), %d bytes for %d out of %d setting\n",
+			res.Latency, res.Attempts, res.Messages, res.Lost, res.Bytes, Threshold, K)
+		latencies = append(latencies, res.Latency)
+		attempts += res.Attempts
+	}
+
+	if len(latencies) == 0 {
+		return
+	}
+	p := bench.Summarize(latencies)
+	fmt.Printf("End-to-end signing latency over %d runs: mean %.3f ms, p50 %.3f ms, p90 %.3f ms, max %.3f ms, %.2f attempts\n",
+		len(latencies), p.Mean, p.P50, p.P90, p.Max, float64(attempts)/float64(len(latencies)))
+}
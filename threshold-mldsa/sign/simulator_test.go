@@ -0,0 +1,94 @@
+package sign
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/cloudflare/circl/sign/thmldsa/thmldsa44"
+)
+
+func TestNetworkConfig(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		cfg  NetworkConfig
+		want error
+	}{
+		{"lossless", NetworkConfig{}, nil},
+		{"lossy with retransmission", NetworkConfig{Default: LinkConfig{Loss: 0.1}, RetransmitTimeout: time.Millisecond}, nil},
+		{"lossy without retransmission", NetworkConfig{Default: LinkConfig{Loss: 0.1}}, ErrNoRetransmit},
+		{"lossy link without retransmission", NetworkConfig{Links: map[[2]uint8]LinkConfig{{0, 1}: {Loss: 0.1}}}, ErrNoRetransmit},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, err := NewNetwork([]uint8{0, 1}, tc.cfg); !errors.Is(err, tc.want) {
+				t.Fatalf("got %v instead of %v", err, tc.want)
+			}
+		})
+	}
+
+	if _, err := NewNetwork([]uint8{0, 1}, NetworkConfig{Default: LinkConfig{Loss: 1}, RetransmitTimeout: time.Millisecond}); err == nil {
+		t.Fatal("link losing every message accepted")
+	}
+}
+
+func TestRetransmitBandwidth(t *testing.T) {
+	// Every transmission of the message takes 100ms of the link
+	link := LinkConfig{Bandwidth: 1000, Loss: 0.5}
+	n, err := NewNetwork([]uint8{0, 1}, NetworkConfig{Default: link, RetransmitTimeout: time.Millisecond, Seed: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	msg := make([]byte, 100)
+
+	start := time.Now()
+	for i := 0; i < 10; i++ {
+		n.send(0, 1, messageID{0, 1, uint8(i)}, msg)
+	}
+	sent, lost, bytes := n.Counters()
+	if lost == 0 {
+		t.Fatal("no transmission lost")
+	}
+	if transmissions := sent + lost; bytes != transmissions*len(msg) {
+		t.Fatalf("%d bytes sent for %d transmissions", bytes, transmissions)
+	}
+	busy := n.freeAt[[2]uint8{0, 1}].Sub(start)
+	if want := time.Duration(sent+lost) * link.transmitTime(len(msg)); busy < want {
+		t.Fatalf("link busy for %s for %d transmissions instead of %s", busy, sent+lost, want)
+	}
+}
+
+func TestSimulateLossy(t *testing.T) {
+	params, err := thmldsa44.GetThresholdParams(2, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var seed [thmldsa44.SeedSize]byte
+	binary.LittleEndian.PutUint64(seed[:], 1)
+	pk, sks := thmldsa44.NewThresholdKeysFromSeed(&seed, params)
+
+	s := &Session{PK: pk, Params: params, Act: 5, Msg: []byte("message"), MaxAttempts: 570}
+	cfg := NetworkConfig{
+		Default:           LinkConfig{Latency: time.Millisecond, Loss: 0.3},
+		RetransmitTimeout: 5 * time.Millisecond,
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	res, sig, err := Simulate(ctx, sks, s, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !thmldsa44.Verify(pk, s.Msg, nil, sig) {
+		t.Fatal("invalid signature")
+	}
+	if res.Lost == 0 {
+		t.Fatal("no transmission lost")
+	}
+
+	cfg.RetransmitTimeout = 0
+	if _, _, err := Simulate(ctx, sks, s, cfg); !errors.Is(err, ErrNoRetransmit) {
+		t.Fatalf("got %v instead of %v", err, ErrNoRetransmit)
+	}
+}
@@ -0,0 +1,341 @@
+package sign
+
+import (
+	"bufio"
+	"context"
+	"crypto/ed25519"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// Transport carries the messages of one signing session between the
+// parties. Every message is identified by the attempt, the round and the
+// party that sent it.
+type Transport interface {
+	// Broadcast sends the message of party from for the given round of the
+	// attempt to every other party.
+	Broadcast(ctx context.Context, attempt, round int, from uint8, msg []byte) error
+
+	// Receive waits for the message of party from for the given round of
+	// the attempt.
+	Receive(ctx context.Context, attempt, round int, from uint8) ([]byte, error)
+
+	Close() error
+}
+
+// DirTransport is a Transport over a directory shared by all parties, for
+// instance on a network file system. Every message is written to its own
+// file, which also leaves a transcript of the session for the combiner.
+type DirTransport struct {
+	Dir string
+
+	// PollInterval is how often Receive checks for new messages.
+	PollInterval time.Duration
+}
+
+// ErrStaleTranscript is returned when a message of the session is already
+// in the directory, left by an earlier session with the same ID.
+var ErrStaleTranscript = errors.New("message already written: stale transcript")
+
+// NewDirTransport returns a transport over the directory dir, creating it
+// if needed.
+func NewDirTransport(dir string) (*DirTransport, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	return &DirTransport{Dir: dir, PollInterval: 50 * time.Millisecond}, nil
+}
+
+func (t *DirTransport) path(attempt, round int, from uint8) string {
+	return filepath.Join(t.Dir, fmt.Sprintf("a%04d-r%d-p%d", attempt, round, from))
+}
+
+func (t *DirTransport) Broadcast(ctx context.Context, attempt, round int, from uint8, msg []byte) error {
+	path := t.path(attempt, round, from)
+
+	// Write to a temporary file first, so that readers never see a
+	// partial message. Linking it fails rather than replace a message
+	// which the other parties may already have read.
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, msg, 0o644); err != nil {
+		return err
+	}
+	defer os.Remove(tmp)
+	if err := os.Link(tmp, path); err != nil {
+		if errors.Is(err, os.ErrExist) {
+			return fmt.Errorf("%s: %w", path, ErrStaleTranscript)
+		}
+		return err
+	}
+	return nil
+}
+
+func (t *DirTransport) Receive(ctx context.Context, attempt, round int, from uint8) ([]byte, error) {
+	path := t.path(attempt, round, from)
+	for {
+		msg, err := os.ReadFile(path)
+		if err == nil {
+			return msg, nil
+		}
+		if !errors.Is(err, os.ErrNotExist) {
+			return nil, err
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(t.PollInterval):
+		}
+	}
+}
+
+func (t *DirTransport) Close() error {
+	return nil
+}
+
+// Limits of TCPTransport: the size of a message, and the number of messages
+// of a party held until they are received
+const (
+	maxMessageSize     = 1 << 20
+	maxPendingMessages = 16
+)
+
+type messageID struct {
+	attempt int
+	round   int
+	from    uint8
+}
+
+// after reports whether m comes after o in the order the messages of a party
+// are sent.
+func (m messageID) after(o messageID) bool {
+	return m.attempt > o.attempt || m.attempt == o.attempt && m.round > o.round
+}
+
+// TCPTransport is a Transport over direct TCP connections between the
+// parties. Every party listens on its own address and dials the others.
+//
+// Every frame is signed with the identity key of its sender, and frames
+// which do not verify under the identity of the party they claim to come
+// from are dropped with their connection. Connections are not encrypted.
+type TCPTransport struct {
+	id       uint8
+	addrs    []string
+	session  [32]byte
+	identity ed25519.PrivateKey
+	peers    []ed25519.PublicKey
+
+	listener net.Listener
+
+	mu      sync.Mutex
+	conns   map[uint8]*bufio.Writer
+	raw     []net.Conn
+	msgs    map[messageID][]byte
+	pending map[uint8]int       // messages of each party not received yet
+	last    map[uint8]messageID // last message accepted from each party
+	notify  chan struct{}
+	closed  bool
+}
+
+// NewTCPTransport returns a transport for party id of the session, listening
+// on addrs[id] and sending to the other addresses of addrs, indexed by party.
+// The address of parties not taking part in the session is left empty.
+// Frames are signed with identity and checked against peers, the public
+// identity keys of every party.
+func NewTCPTransport(id uint8, addrs []string, session [32]byte, identity ed25519.PrivateKey, peers []ed25519.PublicKey) (*TCPTransport, error) {
+	if int(id) >= len(addrs) || addrs[id] == "" {
+		return nil, fmt.Errorf("no address for party %d", id)
+	}
+	if len(peers) != len(addrs) {
+		return nil, fmt.Errorf("expected %d identity keys, got %d", len(addrs), len(peers))
+	}
+	if len(identity) != ed25519.PrivateKeySize || !peers[id].Equal(identity.Public()) {
+		return nil, fmt.Errorf("identity key does not match the one of party %d", id)
+	}
+	l, err := net.Listen("tcp", addrs[id])
+	if err != nil {
+		return nil, err
+	}
+	t := &TCPTransport{
+		id:       id,
+		addrs:    addrs,
+		session:  session,
+		identity: identity,
+		peers:    peers,
+		listener: l,
+		conns:    make(map[uint8]*bufio.Writer),
+		msgs:     make(map[messageID][]byte),
+		pending:  make(map[uint8]int),
+		last:     make(map[uint8]messageID),
+		notify:   make(chan struct{}),
+	}
+	go t.accept()
+	return t, nil
+}
+
+func (t *TCPTransport) accept() {
+	for {
+		conn, err := t.listener.Accept()
+		if err != nil {
+			return
+		}
+		t.mu.Lock()
+		t.raw = append(t.raw, conn)
+		t.mu.Unlock()
+		go t.read(conn)
+	}
+}
+
+// Frame header: session, attempt, round, sender and payload length. The
+// payload is followed by the sender's signature of the header and payload.
+const frameHeaderSize = 32 + 4 + 1 + 1 + 4
+
+// read receives the frames of conn until it fails or a frame is rejected.
+// Frames of a party must be signed by it and come in the order they are
+// sent, which drops replays, and at most maxPendingMessages of them are
+// held until received.
+func (t *TCPTransport) read(conn net.Conn) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	frame := make([]byte, frameHeaderSize)
+	for {
+		if _, err := io.ReadFull(r, frame[:frameHeaderSize]); err != nil {
+			return
+		}
+		size := binary.BigEndian.Uint32(frame[38:])
+		from := frame[37]
+		if [32]byte(frame[:32]) != t.session || size > maxMessageSize ||
+			from == t.id || int(from) >= len(t.addrs) || t.addrs[from] == "" {
+			return
+		}
+		frame = append(frame[:frameHeaderSize], make([]byte, int(size)+ed25519.SignatureSize)...)
+		if _, err := io.ReadFull(r, frame[frameHeaderSize:]); err != nil {
+			return
+		}
+		signed := frame[:frameHeaderSize+int(size)]
+		if !ed25519.Verify(t.peers[from], signed, frame[len(signed):]) {
+			return
+		}
+
+		id := messageID{int(binary.BigEndian.Uint32(frame[32:])), int(frame[36]), from}
+		t.mu.Lock()
+		last, seen := t.last[from]
+		if (seen && !id.after(last)) || t.pending[from] >= maxPendingMessages {
+			t.mu.Unlock()
+			return
+		}
+		t.last[from] = id
+		t.pending[from]++
+		t.msgs[id] = append([]byte(nil), signed[frameHeaderSize:]...)
+		close(t.notify)
+		t.notify = make(chan struct{})
+		t.mu.Unlock()
+	}
+}
+
+// dial returns the connection to party i, retrying until it is up or ctx
+// is done, as the other parties may not have started yet.
+func (t *TCPTransport) dial(ctx context.Context, i uint8) (*bufio.Writer, error) {
+	t.mu.Lock()
+	w, ok := t.conns[i]
+	t.mu.Unlock()
+	if ok {
+		return w, nil
+	}
+
+	var d net.Dialer
+	for {
+		conn, err := d.DialContext(ctx, "tcp", t.addrs[i])
+		if err == nil {
+			w = bufio.NewWriter(conn)
+			t.mu.Lock()
+			t.conns[i] = w
+			t.raw = append(t.raw, conn)
+			t.mu.Unlock()
+			return w, nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("party %d: %w", i, err)
+		case <-time.After(100 * time.Millisecond):
+		}
+	}
+}
+
+func (t *TCPTransport) Broadcast(ctx context.Context, attempt, round int, from uint8, msg []byte) error {
+	var hdr [frameHeaderSize]byte
+	copy(hdr[:32], t.session[:])
+	binary.BigEndian.PutUint32(hdr[32:], uint32(attempt))
+	hdr[36] = byte(round)
+	hdr[37] = from
+	binary.BigEndian.PutUint32(hdr[38:], uint32(len(msg)))
+	sig := ed25519.Sign(t.identity, append(hdr[:], msg...))
+
+	for i := range t.addrs {
+		if uint8(i) == t.id || t.addrs[i] == "" {
+			continue
+		}
+		w, err := t.dial(ctx, uint8(i))
+		if err != nil {
+			return err
+		}
+		if _, err := w.Write(hdr[:]); err != nil {
+			return fmt.Errorf("party %d: %w", i, err)
+		}
+		if _, err := w.Write(msg); err != nil {
+			return fmt.Errorf("party %d: %w", i, err)
+		}
+		if _, err := w.Write(sig); err != nil {
+			return fmt.Errorf("party %d: %w", i, err)
+		}
+		if err := w.Flush(); err != nil {
+			return fmt.Errorf("party %d: %w", i, err)
+		}
+	}
+	return nil
+}
+
+// Receive hands out every message once, after which it is dropped.
+func (t *TCPTransport) Receive(ctx context.Context, attempt, round int, from uint8) ([]byte, error) {
+	id := messageID{attempt, round, from}
+	for {
+		t.mu.Lock()
+		msg, ok := t.msgs[id]
+		if ok {
+			delete(t.msgs, id)
+			t.pending[from]--
+		}
+		notify := t.notify
+		t.mu.Unlock()
+		if ok {
+			return msg, nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-notify:
+		}
+	}
+}
+
+func (t *TCPTransport) Close() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.closed {
+		return nil
+	}
+	t.closed = true
+	for _, conn := range t.raw {
+		conn.Close()
+	}
+	return t.listener.Close()
+}
@@ -0,0 +1,347 @@
+package cli
+
+import (
+	"bench"
+	"fmt"
+	"log"
+	"net"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"traccoon-sign/networking"
+	"traccoon-sign/sign"
+)
+
+// defaultKeysDir is the directory of the key files when keys= is not given.
+const defaultKeysDir = "keys"
+
+// Transport connects a party of the networked mode to the other parties of
+// the committee among parties, or to every other party if parties is nil.
+type Transport func(identity *networking.Identity, committee *networking.Committee, parties []int) (networking.Communicator, error)
+
+// Main runs the command line of the T-Raccoon programs with the arguments
+// argv, the networked parties connecting with the TCP transport or with
+// one of transports, chosen with transport=.
+func Main(argv []string, transports map[string]Transport) {
+	if len(argv) < 1 {
+		fmt.Println("Usage: go run . type= iter= t= n= [format=] [keys=] [committee=] [transport=] [params=] [dkg=] [id=] [signers=]")
+		os.Exit(1)
+	}
+
+	if len(argv) > 10 {
+		fmt.Println("Only ten args are allowed")
+		os.Exit(1)
+	}
+
+	args := make(map[string]string)
+	for _, arg := range argv {
+		parts := strings.SplitN(arg, "=", 2)
+		if len(parts) != 2 {
+			fmt.Printf("Invalid argument format: %s\n", arg)
+			os.Exit(1)
+		}
+		args[parts[0]] = parts[1]
+	}
+
+	partyIDStr, ok := args["type"]
+	if !ok {
+		fmt.Println("Missing type parameter.")
+		os.Exit(1)
+	}
+
+	// Each party generates its identity key once, before the keys are dealt
+	if partyIDStr == "identity" {
+		generateIdentity(args)
+		return
+	}
+
+	// Dealing keys runs once
+	iterStr, ok := args["iter"]
+	if !ok && partyIDStr == "g" {
+		iterStr, ok = "1", true
+	}
+	if !ok {
+		fmt.Println("Missing iter parameter.")
+		os.Exit(1)
+	}
+
+	// A sweep runs every t for n up to the given one
+	t, ok := args["t"]
+	if !ok && partyIDStr == "s" {
+		t, ok = "2", true
+	}
+	if !ok {
+		fmt.Println("Missing t parameter.")
+		os.Exit(1)
+	}
+
+	n, ok := args["n"]
+	if !ok {
+		fmt.Println("Missing n parameter.")
+		os.Exit(1)
+	}
+
+	var err error
+	var iters, threshold, parties int
+	iters, err = strconv.Atoi(iterStr)
+	threshold, err = strconv.Atoi(t)
+	parties, err = strconv.Atoi(n)
+	if err != nil {
+		fmt.Println("Error: Please enter a valid integer for params.")
+		os.Exit(1)
+	}
+
+	sign.K = parties
+	sign.Threshold = threshold
+
+	// The networked mode signs with the parameters of the dealt keys
+	if name, ok := args["params"]; ok {
+		sign.Parameters, err = sign.ParamsByName(name)
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
+	format, err := bench.ParseFormat(args["format"])
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	if partyIDStr == "s" || (partyIDStr == "d" && format != "text") {
+		runBenchmarks(partyIDStr == "s", threshold, parties, iters, format)
+		return
+	}
+
+	if partyIDStr == "d" {
+		sign.LocalRun(iters)
+		return
+	}
+
+	// Parties of the networked mode read their keys from the files written
+	// by the dealer, which are to be copied to each party beforehand
+	keysDir := args["keys"]
+	if keysDir == "" {
+		keysDir = defaultKeysDir
+	}
+	committeePath := args["committee"]
+	if committeePath == "" {
+		committeePath = filepath.Join(keysDir, "committee.json")
+	}
+
+	transport := args["transport"]
+	if transport == "" {
+		transport = "tcp"
+	}
+	connect, ok := transports[transport]
+	if transport == "tcp" {
+		connect, ok = connectTCP, true
+	}
+	if !ok {
+		fmt.Printf("Error: unknown transport %s, libp2p being run by the program of the libp2p module.\n", transport)
+		os.Exit(1)
+	}
+
+	if partyIDStr == "g" {
+		// The committee lists the identities generated by the parties
+		identities, err := networking.ReadPublicIdentities(keysDir, sign.K)
+		if err != nil {
+			fmt.Printf("Error: %v, each party first generating its identity with type=identity id=\n", err)
+			os.Exit(1)
+		}
+		pk, sks, err := sign.NewThresholdKeys(sign.Threshold, sign.K, sign.Parameters)
+		if err == nil {
+			err = sign.WriteKeys(keysDir, pk, sks)
+		}
+		if err == nil {
+			err = networking.WriteCommittee(committeePath, networking.LocalCommittee(identities))
+		}
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("Wrote the %s keys of %d parties to %s, and a committee running on loopback to %s\n", pk.Params.Name, sign.K, keysDir, committeePath)
+		return
+	}
+
+	partyID, err := strconv.Atoi(partyIDStr)
+	if err != nil {
+		fmt.Println("Error: Please enter a valid integer.")
+		os.Exit(1)
+	}
+
+	// With dkg=true, the parties generate the keys together instead of
+	// signing, and each writes the public key and its own private key
+	dkg := args["dkg"] == "true"
+
+	// The signers are the first t parties unless given with signers=, the
+	// key generation running with every party
+	var T []int
+	if !dkg {
+		T, err = parseSigners(args["signers"], sign.Threshold, sign.K)
+		if err == nil && !slices.Contains(T, partyID) {
+			err = fmt.Errorf("party %d is not among the signers %v", partyID, T)
+		}
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
+	var pk *sign.PublicKey
+	var sk *sign.PrivateKey
+	var genDuration time.Duration
+	if !dkg {
+		fmt.Println("Reading keys...")
+		start := time.Now()
+		pk, sk, err = sign.ReadKeys(keysDir, partyID)
+		if err != nil {
+			log.Fatalf("Failed to read keys: %v", err)
+		}
+		genDuration = time.Since(start)
+	}
+	committee, err := networking.ReadCommittee(committeePath)
+	if err != nil {
+		log.Fatalf("Failed to read committee: %v", err)
+	}
+	if committee.Size() != sign.K {
+		log.Fatalf("The committee has %d parties instead of %d", committee.Size(), sign.K)
+	}
+	identity, err := networking.ReadIdentity(keysDir, partyID, committee)
+	if err != nil {
+		log.Fatalf("Failed to read identity: %v", err)
+	}
+
+	fmt.Println("Establishing connections...")
+	comm, err := connect(identity, committee, T)
+	if err != nil {
+		log.Fatalf("Failed to connect over %s: %v", transport, err)
+	}
+	defer comm.Close()
+
+	if dkg {
+		d, err := sign.NewDKG(partyID, sign.Threshold, sign.K, sign.Parameters)
+		if err != nil {
+			log.Fatalf("Failed to start the key generation: %v", err)
+		}
+		start := time.Now()
+		pk, sk, err := sign.RunDKG(comm, d)
+		if err != nil {
+			log.Fatalf("Key generation failed: %v", err)
+		}
+		if err := sign.WriteKeys(keysDir, pk, []sign.PrivateKey{*sk}); err != nil {
+			log.Fatalf("Failed to write keys: %v", err)
+		}
+		fmt.Printf("Generated the %s keys of party %d in %s, written to %s\n", pk.Params.Name, partyID, time.Since(start), keysDir)
+		return
+	}
+
+	msg := []byte("Message")
+	fmt.Printf("Timestamp before signing: %s\n", time.Now().Format("15:04:05.000000"))
+	res, err := sign.RunParty(comm, sign.NewParty(partyID, pk), pk, sk, msg, nil, T, sign.K)
+	if err != nil {
+		log.Fatalf("Signing failed: %v", err)
+	}
+	fmt.Printf("Timestamp after signing: %s\n", time.Now().Format("15:04:05.000000"))
+	if partyID == res.Combiner {
+		fmt.Printf("Signature Verification Result: %v\n", res.Valid)
+		fmt.Printf("Signature size: %d bytes\n", len(res.Signature))
+	}
+
+	fmt.Println("Gen duration:", genDuration)
+	fmt.Println("Signature Round 1 duration:", res.Round1)
+	fmt.Println("Signature Round 2 duration:", res.Round2)
+	fmt.Println("Signature Round 3 duration:", res.Round3)
+	fmt.Println("Finalize duration:", res.Finalize)
+	fmt.Println("Verify duration:", res.Verify)
+	fmt.Println("Sign Round 1 sending/receiving duration:", res.Exchange1)
+	fmt.Println("Sign Round 2 sending/receiving duration:", res.Exchange2)
+	fmt.Println("Sign Round 3 sending/receiving duration:", res.Exchange3)
+	fmt.Println("Bytes sent:", res.BytesSent)
+}
+
+// generateIdentity generates the identity key of the party id= into keys=,
+// and prints its public key.
+func generateIdentity(args map[string]string) {
+	partyID, err := strconv.Atoi(args["id"])
+	if err != nil || partyID < 0 {
+		fmt.Println("Error: Please enter a valid party ID with id=.")
+		os.Exit(1)
+	}
+	keysDir := args["keys"]
+	if keysDir == "" {
+		keysDir = defaultKeysDir
+	}
+	pub, err := networking.GenerateIdentity(keysDir, partyID)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("Wrote the identity key of party %d to %s, give party-%d.identity.pub to the dealer: %x\n", partyID, keysDir, partyID, pub)
+}
+
+// parseSigners parses the comma-separated IDs of the t signers among n
+// parties, the first t parties if s is empty.
+func parseSigners(s string, t, n int) ([]int, error) {
+	var T []int
+	if s == "" {
+		for i := 0; i < t; i++ {
+			T = append(T, i)
+		}
+		return T, nil
+	}
+	for _, f := range strings.Split(s, ",") {
+		id, err := strconv.Atoi(strings.TrimSpace(f))
+		if err != nil || id < 0 || id >= n {
+			return nil, fmt.Errorf("invalid signer %q among %d parties", f, n)
+		}
+		if slices.Contains(T, id) {
+			return nil, fmt.Errorf("signer %d listed twice", id)
+		}
+		T = append(T, id)
+	}
+	if len(T) != t {
+		return nil, fmt.Errorf("%d signers instead of t = %d", len(T), t)
+	}
+	slices.Sort(T)
+	return T, nil
+}
+
+// connectTCP connects the party to the other parties of the committee among
+// parties over TCP.
+func connectTCP(identity *networking.Identity, committee *networking.Committee, parties []int) (networking.Communicator, error) {
+	comm := &networking.P2PComm{
+		Socks:    make(map[int]*net.Conn),
+		Rank:     identity.ID,
+		Identity: identity,
+	}
+	if err := networking.EstablishConnections(comm, committee, parties); err != nil {
+		comm.Close()
+		return nil, err
+	}
+	return comm, nil
+}
+
+// runBenchmarks runs the local benchmark for (threshold, parties), or for
+// every setting up to parties if sweep is set, and prints the results in the
+// given format.
+func runBenchmarks(sweep bool, threshold, parties, iters int, format string) {
+	err := bench.Report(os.Stdout, format, func(each func(*bench.Result)) error {
+		if sweep {
+			return sign.Sweep(parties, iters, sign.Parameters, each)
+		}
+		res, err := sign.Bench(threshold, parties, iters, sign.Parameters)
+		if err == nil {
+			each(res)
+		}
+		return err
+	})
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,33 @@
+package cli
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestParseSigners(t *testing.T) {
+	for _, tc := range []struct {
+		s    string
+		want []int
+	}{
+		{"", []int{0, 1}},
+		{"2,0", []int{0, 2}},
+		{" 1, 2", []int{1, 2}},
+		{"0", nil},
+		{"0,1,2", nil},
+		{"0,0", nil},
+		{"0,3", nil},
+		{"0,x", nil},
+	} {
+		T, err := parseSigners(tc.s, 2, 3)
+		if tc.want == nil {
+			if err == nil {
+				t.Errorf("signers %q accepted as %v", tc.s, T)
+			}
+			continue
+		}
+		if err != nil || !slices.Equal(T, tc.want) {
+			t.Errorf("signers %q: got %v, %v instead of %v", tc.s, T, err, tc.want)
+		}
+	}
+}
@@ -0,0 +1,403 @@
+// Package libp2p carries the messages of the T-Raccoon parties over
+// go-libp2p streams. It is a module of its own, so that the other
+// transports build without go-libp2p.
+package libp2p
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"time"
+	"traccoon-sign/networking"
+
+	"github.com/libp2p/go-libp2p"
+	"github.com/libp2p/go-libp2p/core/crypto"
+	"github.com/libp2p/go-libp2p/core/host"
+	"github.com/libp2p/go-libp2p/core/network"
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/libp2p/go-libp2p/core/peerstore"
+	"github.com/libp2p/go-libp2p/core/protocol"
+	"github.com/libp2p/go-libp2p/p2p/transport/tcp"
+	ma "github.com/multiformats/go-multiaddr"
+)
+
+// ProtocolID is the protocol of the streams carrying the messages of the
+// parties.
+const ProtocolID protocol.ID = "/traccoon/sign/1.0.0"
+
+const (
+	// maxMessageSize bounds the size of a message received over libp2p
+	maxMessageSize = 1 << 24
+
+	// frameHeaderSize is the size of the sequence number and the length
+	// preceding each message
+	frameHeaderSize = 12
+
+	// queueSize is the number of messages from a party that can be pending
+	// before its stream stops being read
+	queueSize = 64
+
+	// sendAttempts is the number of times Send tries to open a new stream to
+	// deliver a message before giving up, and retryDelay the time between
+	// them
+	sendAttempts = 3
+	retryDelay   = 500 * time.Millisecond
+
+	// closeTimeout bounds how long Close waits for a party to read the
+	// messages sent to it
+	closeTimeout = 10 * time.Second
+)
+
+// Comm is the Communicator over go-libp2p streams. Each party opens one
+// stream to every other party, on which it writes its messages to that party
+// as a sequence number and a length followed by the message. Streams from
+// peers outside of the committee are reset.
+//
+// When a stream fails, the messages written to it may not have arrived, so
+// the next one starts again from the first message, the receiver dropping
+// those it already has. Every message sent is therefore kept until Close.
+type Comm struct {
+	Rank int
+
+	host  host.Host
+	peers []peer.AddrInfo
+
+	// inbox[src] holds the messages received from party src
+	inbox []chan []byte
+	recv  []inStreams
+
+	mu      sync.Mutex
+	streams map[int]*outStream
+
+	closeOnce sync.Once
+	closed    chan struct{}
+}
+
+// inStreams holds the sequence number of the next message expected from a
+// party, over any of its streams.
+type inStreams struct {
+	mu   sync.Mutex
+	next uint64
+}
+
+type outStream struct {
+	mu     sync.Mutex
+	s      network.Stream
+	frames [][]byte
+}
+
+// NewComm returns the communicator of party rank on h, peers[i] being the
+// address of party i.
+func NewComm(h host.Host, rank int, peers []peer.AddrInfo) (*Comm, error) {
+	if rank < 0 || rank >= len(peers) {
+		return nil, fmt.Errorf("no party %d in a committee of %d", rank, len(peers))
+	}
+	if peers[rank].ID != h.ID() {
+		return nil, fmt.Errorf("host %s is not party %d", h.ID(), rank)
+	}
+	comm := &Comm{
+		Rank:    rank,
+		host:    h,
+		peers:   peers,
+		inbox:   make([]chan []byte, len(peers)),
+		recv:    make([]inStreams, len(peers)),
+		streams: make(map[int]*outStream),
+		closed:  make(chan struct{}),
+	}
+	for i, info := range peers {
+		comm.inbox[i] = make(chan []byte, queueSize)
+		if i != rank {
+			h.Peerstore().AddAddrs(info.ID, info.Addrs, peerstore.PermanentAddrTTL)
+		}
+	}
+	h.SetStreamHandler(ProtocolID, comm.handleStream)
+	return comm, nil
+}
+
+// Connect connects to the other parties among parties, or to every other
+// party if parties is nil.
+func (comm *Comm) Connect(ctx context.Context, parties []int) error {
+	if parties == nil {
+		for i := range comm.peers {
+			parties = append(parties, i)
+		}
+	}
+	var errs []error
+	for _, i := range parties {
+		if i == comm.Rank {
+			continue
+		}
+		if i < 0 || i >= len(comm.peers) {
+			errs = append(errs, fmt.Errorf("no party %d", i))
+			continue
+		}
+		if err := comm.host.Connect(ctx, comm.peers[i]); err != nil {
+			errs = append(errs, fmt.Errorf("party %d: %w", i, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (comm *Comm) party(id peer.ID) (int, bool) {
+	for i, info := range comm.peers {
+		if info.ID == id && i != comm.Rank {
+			return i, true
+		}
+	}
+	return 0, false
+}
+
+func (comm *Comm) handleStream(s network.Stream) {
+	src, ok := comm.party(s.Conn().RemotePeer())
+	if !ok {
+		s.Reset()
+		return
+	}
+	defer s.Close()
+
+	reader := bufio.NewReader(s)
+	for {
+		seq, msg, err := readFrame(reader)
+		if err == nil {
+			err = comm.deliver(src, seq, msg)
+		}
+		if err != nil {
+			if err != io.EOF {
+				s.Reset()
+			}
+			return
+		}
+	}
+}
+
+// deliver queues the message seq from src unless it was already received
+// on an earlier stream.
+func (comm *Comm) deliver(src int, seq uint64, msg []byte) error {
+	in := &comm.recv[src]
+	in.mu.Lock()
+	defer in.mu.Unlock()
+	switch {
+	case seq < in.next:
+		return nil
+	case seq > in.next:
+		return fmt.Errorf("message %d of party %d instead of %d", seq, src, in.next)
+	}
+	select {
+	case comm.inbox[src] <- msg:
+		in.next++
+		return nil
+	case <-comm.closed:
+		return networking.ErrClosed
+	}
+}
+
+func readFrame(r io.Reader) (uint64, []byte, error) {
+	var header [frameHeaderSize]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		return 0, nil, err
+	}
+	seq := binary.BigEndian.Uint64(header[:8])
+	size := binary.BigEndian.Uint32(header[8:])
+	if size > maxMessageSize {
+		return 0, nil, fmt.Errorf("message of %d bytes", size)
+	}
+	msg := make([]byte, size)
+	if _, err := io.ReadFull(r, msg); err != nil {
+		return 0, nil, err
+	}
+	return seq, msg, nil
+}
+
+// Send sends msg to party dst, opening a new stream if the current one
+// fails. If it still fails, msg is sent again, after the messages before it,
+// on the stream of the next message to dst.
+func (comm *Comm) Send(dst int, msg []byte) (int, error) {
+	if dst < 0 || dst >= len(comm.peers) || dst == comm.Rank {
+		return 0, fmt.Errorf("no party %d", dst)
+	}
+	select {
+	case <-comm.closed:
+		return 0, networking.ErrClosed
+	default:
+	}
+
+	comm.mu.Lock()
+	out, ok := comm.streams[dst]
+	if !ok {
+		out = &outStream{}
+		comm.streams[dst] = out
+	}
+	comm.mu.Unlock()
+
+	out.mu.Lock()
+	defer out.mu.Unlock()
+	frame := binary.BigEndian.AppendUint64(nil, uint64(len(out.frames)))
+	frame = binary.BigEndian.AppendUint32(frame, uint32(len(msg)))
+	frame = append(frame, msg...)
+	out.frames = append(out.frames, frame)
+
+	sent := 0
+	for attempt := 1; ; attempt++ {
+		n, err := comm.write(dst, out, frame)
+		sent += n
+		if err == nil {
+			return sent, nil
+		}
+		if attempt == sendAttempts {
+			return sent, fmt.Errorf("party %d: %w", dst, err)
+		}
+		select {
+		case <-time.After(retryDelay):
+		case <-comm.closed:
+			return sent, networking.ErrClosed
+		}
+	}
+}
+
+// write writes frame to the stream to dst, or every frame sent to dst on a
+// new stream if there is none or writing fails, and drops the stream if
+// that fails too.
+func (comm *Comm) write(dst int, out *outStream, frame []byte) (int, error) {
+	if out.s != nil {
+		if _, err := out.s.Write(frame); err == nil {
+			return len(frame), nil
+		}
+		out.s.Reset()
+		out.s = nil
+	}
+
+	s, err := comm.host.NewStream(context.Background(), comm.peers[dst].ID, ProtocolID)
+	if err != nil {
+		return 0, err
+	}
+	sent := 0
+	for _, f := range out.frames {
+		if _, err := s.Write(f); err != nil {
+			s.Reset()
+			return sent, err
+		}
+		sent += len(f)
+	}
+	out.s = s
+	return sent, nil
+}
+
+func (comm *Comm) Recv(src int) ([]byte, int, error) {
+	if src < 0 || src >= len(comm.peers) || src == comm.Rank {
+		return nil, 0, fmt.Errorf("no party %d", src)
+	}
+	select {
+	case msg := <-comm.inbox[src]:
+		return msg, len(msg) + frameHeaderSize, nil
+	case <-comm.closed:
+		return nil, 0, networking.ErrClosed
+	}
+}
+
+// Close removes the stream handler and closes the outgoing streams, waiting
+// for each party to read the messages sent to it, as they are lost if the
+// host stops first. The host is left open.
+func (comm *Comm) Close() error {
+	comm.closeOnce.Do(func() {
+		close(comm.closed)
+		comm.host.RemoveStreamHandler(ProtocolID)
+		comm.mu.Lock()
+		defer comm.mu.Unlock()
+		var wg sync.WaitGroup
+		for _, out := range comm.streams {
+			wg.Add(1)
+			go func(out *outStream) {
+				defer wg.Done()
+				out.mu.Lock()
+				defer out.mu.Unlock()
+				if out.s != nil {
+					closeStream(out.s)
+				}
+			}(out)
+		}
+		wg.Wait()
+	})
+	return nil
+}
+
+// closeStream closes s for writing and waits for the other side to close it
+// in turn once it has read everything.
+func closeStream(s network.Stream) {
+	if err := s.CloseWrite(); err != nil {
+		s.Reset()
+		return
+	}
+	s.SetReadDeadline(time.Now().Add(closeTimeout))
+	if _, err := io.Copy(io.Discard, s); err != nil {
+		s.Reset()
+		return
+	}
+	s.Close()
+}
+
+// Key returns the identity of the party as a libp2p key, so that its peer
+// ID follows from its identity in the committee.
+func Key(id *networking.Identity) (crypto.PrivKey, error) {
+	return crypto.UnmarshalEd25519PrivateKey(id.Key)
+}
+
+// AddrInfos returns the libp2p addresses of the parties of c, party i
+// listening on its Port.
+func AddrInfos(c *networking.Committee) ([]peer.AddrInfo, error) {
+	peers := c.Peers()
+	infos := make([]peer.AddrInfo, c.Size())
+	for i, m := range c.Members {
+		key, ok := peers[i]
+		if !ok {
+			return nil, fmt.Errorf("malformed identity of party %d", i)
+		}
+		pub, err := crypto.UnmarshalEd25519PublicKey(key)
+		if err != nil {
+			return nil, err
+		}
+		id, err := peer.IDFromPublicKey(pub)
+		if err != nil {
+			return nil, err
+		}
+		addr, err := listenAddr(m.Host, m.Port)
+		if err != nil {
+			return nil, fmt.Errorf("party %d: %w", i, err)
+		}
+		infos[i] = peer.AddrInfo{ID: id, Addrs: []ma.Multiaddr{addr}}
+	}
+	return infos, nil
+}
+
+func listenAddr(host string, port int) (ma.Multiaddr, error) {
+	proto := "dns"
+	if ip := net.ParseIP(host); ip != nil {
+		proto = "ip6"
+		if ip.To4() != nil {
+			proto = "ip4"
+		}
+	}
+	return ma.NewMultiaddr(fmt.Sprintf("/%s/%s/tcp/%d", proto, host, port))
+}
+
+// NewHost starts the libp2p host of a party, listening over TCP on the port
+// of the party in the committee.
+func NewHost(id *networking.Identity, committee *networking.Committee) (host.Host, error) {
+	key, err := Key(id)
+	if err != nil {
+		return nil, err
+	}
+	addr, err := ma.NewMultiaddr(fmt.Sprintf("/ip4/0.0.0.0/tcp/%d", committee.Members[id.ID].Port))
+	if err != nil {
+		return nil, err
+	}
+	return libp2p.New(
+		libp2p.Identity(key),
+		libp2p.ListenAddrs(addr),
+		libp2p.Transport(tcp.NewTCPTransport),
+	)
+}
@@ -0,0 +1,175 @@
+package libp2p
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+	"traccoon-sign/networking"
+
+	"github.com/libp2p/go-libp2p/core/peer"
+	mocknet "github.com/libp2p/go-libp2p/p2p/net/mock"
+)
+
+func newComms(t *testing.T, n int) ([]*Comm, mocknet.Mocknet) {
+	t.Helper()
+	mn, err := mocknet.FullMeshLinked(n)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { mn.Close() })
+
+	var peers []peer.AddrInfo
+	for _, h := range mn.Hosts() {
+		peers = append(peers, peer.AddrInfo{ID: h.ID(), Addrs: h.Addrs()})
+	}
+	comms := make([]*Comm, n)
+	for i, h := range mn.Hosts() {
+		if comms[i], err = NewComm(h, i, peers); err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { comms[i].Close() })
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	for _, comm := range comms {
+		if err := comm.Connect(ctx, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return comms, mn
+}
+
+func TestComm(t *testing.T) {
+	n := 3
+	comms, _ := newComms(t, n)
+
+	// Several messages to each party, received in order
+	var wg sync.WaitGroup
+	for i, comm := range comms {
+		for j := 0; j < n; j++ {
+			if j == i {
+				continue
+			}
+			wg.Add(1)
+			go func(comm *Comm, j int) {
+				defer wg.Done()
+				for k := 0; k < 3; k++ {
+					if _, err := comm.Send(j, []byte{byte(comm.Rank), byte(j), byte(k)}); err != nil {
+						t.Error(err)
+					}
+				}
+			}(comm, j)
+		}
+	}
+	for i, comm := range comms {
+		for j := 0; j < n; j++ {
+			if j == i {
+				continue
+			}
+			for k := 0; k < 3; k++ {
+				msg, _, err := comm.Recv(j)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !bytes.Equal(msg, []byte{byte(j), byte(i), byte(k)}) {
+					t.Fatalf("party %d got %v from %d", i, msg, j)
+				}
+			}
+		}
+	}
+	wg.Wait()
+
+	comms[0].Close()
+	if _, _, err := comms[0].Recv(1); !errors.Is(err, networking.ErrClosed) {
+		t.Fatalf("got %v", err)
+	}
+}
+
+func TestCommRejectsStrangers(t *testing.T) {
+	comms, mn := newComms(t, 2)
+	stranger, err := mn.GenPeer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := mn.LinkAll(); err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if _, err := mn.ConnectPeers(stranger.ID(), comms[0].host.ID()); err != nil {
+		t.Fatal(err)
+	}
+	s, err := stranger.NewStream(ctx, comms[0].host.ID(), ProtocolID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.Write([]byte{0, 0, 0, 1, 42})
+	if _, err := s.Read(make([]byte, 1)); err == nil {
+		t.Fatal("stream of a stranger accepted")
+	}
+
+	select {
+	case msg := <-comms[0].inbox[1]:
+		t.Fatalf("got %v", msg)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func TestCommitteeAddrInfos(t *testing.T) {
+	ids := networking.NewTestIdentities(2)
+	c := networking.LocalCommittee([]ed25519.PublicKey{ids[0].Peers[0], ids[1].Peers[1]})
+	c.Members[1].Host = "party1.example"
+	infos, err := AddrInfos(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, err := Key(ids[1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	id, err := peer.IDFromPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if infos[1].ID != id {
+		t.Fatalf("peer ID %s instead of %s", infos[1].ID, id)
+	}
+	if got := infos[0].Addrs[0].String(); got != "/ip4/127.0.0.1/tcp/6000" {
+		t.Fatalf("address %s", got)
+	}
+	if got := infos[1].Addrs[0].String(); got != "/dns/party1.example/tcp/6100" {
+		t.Fatalf("address %s", got)
+	}
+}
+
+func TestCommResendsAfterStreamFailure(t *testing.T) {
+	comms, _ := newComms(t, 2)
+	if _, err := comms[0].Send(1, []byte("a")); err != nil {
+		t.Fatal(err)
+	}
+
+	// The stream fails, the message written to it may or may not arrive
+	comms[0].streams[1].s.Reset()
+	if _, err := comms[0].Send(1, []byte("b")); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, want := range []string{"a", "b"} {
+		msg, _, err := comms[1].Recv(0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(msg) != want {
+			t.Fatalf("got %q instead of %q", msg, want)
+		}
+	}
+	select {
+	case msg := <-comms[1].inbox[0]:
+		t.Fatalf("got %q twice", msg)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
@@ -0,0 +1,48 @@
+// Command party runs the T-Raccoon program of the traccoon-sign module,
+// with the additional transport=libp2p connecting the parties over
+// go-libp2p, party i listening on its port in the committee.
+package main
+
+import (
+	"context"
+	"log"
+	"os"
+	"time"
+	"traccoon-sign/cli"
+	"traccoon-sign/libp2p"
+	"traccoon-sign/networking"
+)
+
+func main() {
+	cli.Main(os.Args[1:], map[string]cli.Transport{"libp2p": connect})
+}
+
+// connect starts the libp2p host of the party and connects it to the other
+// parties of the committee among parties.
+func connect(identity *networking.Identity, committee *networking.Committee, parties []int) (networking.Communicator, error) {
+	h, err := libp2p.NewHost(identity, committee)
+	if err != nil {
+		return nil, err
+	}
+	peers, err := libp2p.AddrInfos(committee)
+	if err != nil {
+		return nil, err
+	}
+	comm, err := libp2p.NewComm(h, identity.ID, peers)
+	if err != nil {
+		return nil, err
+	}
+
+	// The other parties may not be listening yet
+	for i := 0; ; i++ {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		err = comm.Connect(ctx, parties)
+		cancel()
+		if err == nil || i == 9 {
+			break
+		}
+		log.Printf("Failed to connect: %v, retrying...", err)
+		time.Sleep(time.Second)
+	}
+	return comm, err
+}
@@ -0,0 +1,119 @@
+package networking
+
+import (
+	"crypto/ed25519"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+// Member is the entry of a party in the committee file.
+type Member struct {
+	ID   int    `json:"id"`
+	Host string `json:"host"`
+
+	// Port is the first of the ports the party listens on: it waits for
+	// party j > ID on Port + j
+	Port int `json:"port"`
+
+	// Identity is the hex-encoded Ed25519 public key of the party
+	Identity string `json:"identity"`
+}
+
+// Committee is the address book of the parties, member i being party i.
+type Committee struct {
+	Members []Member `json:"members"`
+}
+
+// LocalCommittee returns a committee running on loopback, party i listening
+// on the ports from 6000 + 100*i.
+func LocalCommittee(identities []ed25519.PublicKey) *Committee {
+	c := &Committee{}
+	for i, key := range identities {
+		c.Members = append(c.Members, Member{
+			ID:       i,
+			Host:     "127.0.0.1",
+			Port:     6000 + 100*i,
+			Identity: hex.EncodeToString(key),
+		})
+	}
+	return c
+}
+
+// ReadCommittee reads and checks a committee file.
+func ReadCommittee(path string) (*Committee, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	c := &Committee{}
+	if err := json.Unmarshal(data, c); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if err := c.check(); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return c, nil
+}
+
+// WriteCommittee writes a committee file.
+func WriteCommittee(path string, c *Committee) error {
+	data, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0o644)
+}
+
+func (c *Committee) check() error {
+	n := len(c.Members)
+	for i, m := range c.Members {
+		if m.ID != i {
+			return fmt.Errorf("member %d has ID %d, members must be listed by ID from 0", i, m.ID)
+		}
+		if m.Host == "" {
+			return fmt.Errorf("no host for party %d", i)
+		}
+		if m.Port <= 0 || m.Port+n-1 > 65535 {
+			return fmt.Errorf("ports %d to %d of party %d out of range", m.Port, m.Port+n-1, i)
+		}
+		if _, err := c.identity(i); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *Committee) identity(partyID int) (ed25519.PublicKey, error) {
+	key, err := hex.DecodeString(c.Members[partyID].Identity)
+	if err != nil || len(key) != ed25519.PublicKeySize {
+		return nil, fmt.Errorf("malformed identity of party %d", partyID)
+	}
+	return key, nil
+}
+
+// Peers returns the identity keys of every party.
+func (c *Committee) Peers() map[int]ed25519.PublicKey {
+	peers := make(map[int]ed25519.PublicKey)
+	for i := range c.Members {
+		if key, err := c.identity(i); err == nil {
+			peers[i] = key
+		}
+	}
+	return peers
+}
+
+// Size returns the number of parties.
+func (c *Committee) Size() int {
+	return len(c.Members)
+}
+
+// port returns the port on which the lower of the two parties waits for the
+// other one.
+func (c *Committee) port(partyID, otherID int) int {
+	if partyID < otherID {
+		return c.Members[partyID].Port + otherID
+	}
+	return c.Members[otherID].Port + partyID
+}
@@ -0,0 +1,48 @@
+package networking
+
+import (
+	"crypto/ed25519"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestReadCommittee(t *testing.T) {
+	dir := t.TempDir()
+	ids := NewTestIdentities(3)
+	keys := []ed25519.PublicKey{ids[0].Peers[0], ids[1].Peers[1], ids[2].Peers[2]}
+
+	path := filepath.Join(dir, "committee.json")
+	if err := WriteCommittee(path, LocalCommittee(keys)); err != nil {
+		t.Fatal(err)
+	}
+	c, err := ReadCommittee(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Size() != 3 || c.port(2, 1) != 6102 || !c.Peers()[2].Equal(keys[2]) {
+		t.Fatalf("read %+v", c)
+	}
+
+	for _, tc := range []struct {
+		name   string
+		modify func(c *Committee)
+		want   string
+	}{
+		{"unordered", func(c *Committee) { c.Members[0].ID, c.Members[1].ID = 1, 0 }, "listed by ID"},
+		{"no host", func(c *Committee) { c.Members[1].Host = "" }, "no host"},
+		{"ports out of range", func(c *Committee) { c.Members[2].Port = 65534 }, "out of range"},
+		{"malformed identity", func(c *Committee) { c.Members[0].Identity = "00" }, "malformed identity"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			c := LocalCommittee(keys)
+			tc.modify(c)
+			if err := WriteCommittee(path, c); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := ReadCommittee(path); err == nil || !strings.Contains(err.Error(), tc.want) {
+				t.Fatalf("got %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,71 @@
+package networking
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// memQueueSize is the number of messages from one party to another that
+// can be pending before Send blocks.
+const memQueueSize = 64
+
+var ErrClosed = errors.New("communicator closed")
+
+// MemComm is the Communicator of a party among parties running in the same
+// process, as in tests.
+type MemComm struct {
+	Rank int
+
+	// queues[src][dst] carries the messages from src to dst
+	queues [][]chan []byte
+
+	closeOnce sync.Once
+	closed    chan struct{}
+}
+
+// NewMemNetwork returns the communicators of n parties connected to each
+// other.
+func NewMemNetwork(n int) []*MemComm {
+	queues := make([][]chan []byte, n)
+	for src := range queues {
+		queues[src] = make([]chan []byte, n)
+		for dst := range queues[src] {
+			queues[src][dst] = make(chan []byte, memQueueSize)
+		}
+	}
+	comms := make([]*MemComm, n)
+	for i := range comms {
+		comms[i] = &MemComm{Rank: i, queues: queues, closed: make(chan struct{})}
+	}
+	return comms
+}
+
+func (comm *MemComm) Send(dst int, msg []byte) (int, error) {
+	if dst < 0 || dst >= len(comm.queues) || dst == comm.Rank {
+		return 0, fmt.Errorf("no party %d", dst)
+	}
+	select {
+	case comm.queues[comm.Rank][dst] <- append([]byte(nil), msg...):
+		return len(msg), nil
+	case <-comm.closed:
+		return 0, ErrClosed
+	}
+}
+
+func (comm *MemComm) Recv(src int) ([]byte, int, error) {
+	if src < 0 || src >= len(comm.queues) || src == comm.Rank {
+		return nil, 0, fmt.Errorf("no party %d", src)
+	}
+	select {
+	case msg := <-comm.queues[src][comm.Rank]:
+		return msg, len(msg), nil
+	case <-comm.closed:
+		return nil, 0, ErrClosed
+	}
+}
+
+func (comm *MemComm) Close() error {
+	comm.closeOnce.Do(func() { close(comm.closed) })
+	return nil
+}
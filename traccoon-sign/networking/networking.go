@@ -3,6 +3,7 @@ package networking
 import (
 	"bufio"
 	"encoding/binary"
+	"errors"
 	"fmt"
 	"log"
 	"net"
@@ -13,16 +14,88 @@ import (
 	"github.com/tuneinsight/lattigo/v5/utils/structs"
 )
 
+// Communicator carries the messages between the parties of a protocol.
+// Messages from one party to another are received in the order they were
+// sent. Send and Recv may be called concurrently for different parties.
 type Communicator interface {
+	// Send sends msg to party dst and returns the number of bytes sent
 	Send(dst int, msg []byte) (int, error)
+
+	// Recv returns the next message from party src and the number of bytes
+	// received
 	Recv(src int) ([]byte, int, error)
+
 	Close() error
 }
 
+// P2PComm is the Communicator over TCP connections, set up by
+// EstablishConnections.
 type P2PComm struct {
 	Socks map[int]*net.Conn
 	Rank  int
 	mu    sync.Mutex // Added mutex for safe concurrent access
+
+	// Identity authenticates the connections to the other parties, which
+	// are then encrypted. They are in plaintext if it is nil.
+	Identity *Identity
+
+	peers map[int]*tcpPeer
+}
+
+// tcpPeer holds the buffers of the connection to a party, which persist
+// from one message to the next as a reader may already hold the beginning
+// of the next message.
+type tcpPeer struct {
+	rmu    sync.Mutex
+	reader *bufio.Reader
+	wmu    sync.Mutex
+	writer *bufio.Writer
+}
+
+func (comm *P2PComm) peer(key int) (*tcpPeer, error) {
+	comm.mu.Lock()
+	defer comm.mu.Unlock()
+	if p, ok := comm.peers[key]; ok {
+		return p, nil
+	}
+	sock := comm.Socks[key]
+	if sock == nil || *sock == nil {
+		return nil, fmt.Errorf("no connection to party %d", key)
+	}
+	if comm.peers == nil {
+		comm.peers = make(map[int]*tcpPeer)
+	}
+	p := &tcpPeer{reader: bufio.NewReader(*sock), writer: bufio.NewWriter(*sock)}
+	comm.peers[key] = p
+	return p, nil
+}
+
+func (comm *P2PComm) Send(dst int, msg []byte) (int, error) {
+	p, err := comm.peer(dst)
+	if err != nil {
+		return 0, err
+	}
+	p.wmu.Lock()
+	defer p.wmu.Unlock()
+	return comm.SendBytes(p.writer, dst, msg)
+}
+
+func (comm *P2PComm) Recv(src int) ([]byte, int, error) {
+	p, err := comm.peer(src)
+	if err != nil {
+		return nil, 0, err
+	}
+	p.rmu.Lock()
+	defer p.rmu.Unlock()
+	return comm.RecvBytes(p.reader, src)
+}
+
+// secure runs the handshake on conn if the communicator has an identity.
+func (comm *P2PComm) secure(conn net.Conn, peer int) (net.Conn, error) {
+	if comm.Identity == nil {
+		return conn, nil
+	}
+	return Handshake(conn, comm.Identity, peer)
 }
 
 func (comm *P2PComm) SetSock(key int, conn *net.Conn) {
@@ -64,7 +137,7 @@ func (comm *P2PComm) SendBytes(writer *bufio.Writer, dst int, msg []byte) (int,
 	return totalBytesSent, nil
 }
 
-func (comm *P2PComm) Recv(reader *bufio.Reader, src int) ([]byte, int, error) {
+func (comm *P2PComm) RecvBytes(reader *bufio.Reader, src int) ([]byte, int, error) {
 	lengthBuf := make([]byte, 4)
 	totalBytesRead := 0
 	for totalBytesRead < 4 {
@@ -338,33 +411,41 @@ func (comm *P2PComm) RecvBytesSliceMap(reader *bufio.Reader, src int) map[int][]
 	return data
 }
 
-func ListenTCP(comm *P2PComm, port string, src int) {
+// ListenTCP accepts the connection of party src on port, rejecting those
+// failing the handshake.
+func ListenTCP(comm *P2PComm, port string, src int) error {
 	l, err := net.Listen("tcp", "0.0.0.0:"+port)
 	if err != nil {
-		log.Fatal(err)
-		return
+		return err
 	}
 	defer l.Close()
 	for {
 		conn, err := l.Accept()
 		if err != nil {
-			log.Println(err)
+			return err
+		}
+		sconn, err := comm.secure(conn, src)
+		if err != nil {
+			log.Printf("Rejected connection from %s: %v", conn.RemoteAddr(), err)
+			conn.Close()
 			continue
 		}
 
-		comm.SetSock(src, &conn)
-		break
+		comm.SetSock(src, &sconn)
+		return nil
 	}
 }
 
-func DialTCP(comm *P2PComm, dst int, address string) {
+// DialTCP connects to party dst at address, retrying for a few seconds while
+// it does not listen yet.
+func DialTCP(comm *P2PComm, dst int, address string) error {
 	log.Println("Trying to dial", dst, "at address", address)
 
 	// Check if the socket for the destination is already initialized
 	sock := comm.GetSock(dst)
 	if sock != nil && *sock != nil {
 		log.Printf("Socket for destination %d is already initialized.", dst)
-		return
+		return nil
 	}
 
 	var conn net.Conn
@@ -380,42 +461,55 @@ func DialTCP(comm *P2PComm, dst int, address string) {
 	}
 
 	if err != nil {
-		log.Fatalf("Failed to dial TCP to %s after retries: %v", address, err)
-		return
+		return fmt.Errorf("dialing %s: %w", address, err)
+	}
+	sconn, err := comm.secure(conn, dst)
+	if err != nil {
+		conn.Close()
+		return fmt.Errorf("authenticating %s: %w", address, err)
 	}
 
-	comm.SetSock(dst, &conn)
+	comm.SetSock(dst, &sconn)
 	log.Println("Successfully connected to", address)
+	return nil
 }
 
-func EstablishConnections(wg *sync.WaitGroup, comm *P2PComm, partyID int, totalParties int) {
-	defer wg.Done()
-	var localWg sync.WaitGroup
-
-	// List of IP addresses of all parties
-	// ips := []string{"50.18.79.144", "34.226.247.5", "18.199.237.28", "34.244.57.20", "54.248.28.234", "47.129.58.89", "54.232.160.71", "3.107.98.51"}
-	ips := []string{"127.0.0.1", "127.0.0.1", "127.0.0.1", "127.0.0.1", "127.0.0.1", "127.0.0.1", "127.0.0.1", "127.0.0.1"}
-	for otherID := 0; otherID < totalParties; otherID++ {
+// EstablishConnections connects the party of comm to the other parties of
+// the committee among parties, or to every other party if parties is nil,
+// the lower of two parties listening for the higher one. It returns the
+// errors of the connections which failed.
+func EstablishConnections(comm *P2PComm, committee *Committee, parties []int) error {
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	var errs []error
+
+	if parties == nil {
+		for i := 0; i < committee.Size(); i++ {
+			parties = append(parties, i)
+		}
+	}
+	partyID := comm.Rank
+	for _, otherID := range parties {
 		if partyID != otherID {
-			localWg.Add(1)
+			wg.Add(1)
 			go func(otherID int) {
-				defer localWg.Done()
-				port := 6000 + calculatePortOffset(partyID, otherID)
-				address := fmt.Sprintf("%s:%d", ips[otherID], port)
+				defer wg.Done()
+				var err error
+				port := committee.port(partyID, otherID)
 				if partyID < otherID {
-					ListenTCP(comm, fmt.Sprintf("%d", port), otherID)
+					err = ListenTCP(comm, fmt.Sprintf("%d", port), otherID)
 				} else {
-					DialTCP(comm, otherID, address)
+					address := net.JoinHostPort(committee.Members[otherID].Host, fmt.Sprintf("%d", port))
+					err = DialTCP(comm, otherID, address)
+				}
+				if err != nil {
+					mu.Lock()
+					defer mu.Unlock()
+					errs = append(errs, fmt.Errorf("party %d: %w", otherID, err))
 				}
 			}(otherID)
 		}
 	}
-	localWg.Wait()
-}
-
-func calculatePortOffset(partyID, otherID int) int {
-	if partyID < otherID {
-		return partyID*100 + otherID
-	}
-	return otherID*100 + partyID
+	wg.Wait()
+	return errors.Join(errs...)
 }
@@ -0,0 +1,316 @@
+package networking
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdh"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/zeebo/blake3"
+)
+
+// Identity holds the static key authenticating a party to the others, and
+// the public keys of every party of the committee. Connections from or to
+// any other key are rejected.
+type Identity struct {
+	ID    int
+	Key   ed25519.PrivateKey
+	Peers map[int]ed25519.PublicKey
+}
+
+const (
+	handshakeTimeout = 10 * time.Second
+
+	// maxFrameSize bounds the plaintext of one frame of a SecureConn
+	maxFrameSize = 1 << 16
+
+	transcriptContext = "traccoon-sign secure channel v1 transcript"
+	signatureContext  = "traccoon-sign secure channel v1 signature"
+	lowToHighContext  = "traccoon-sign secure channel v1 lower to higher ID"
+	highToLowContext  = "traccoon-sign secure channel v1 higher to lower ID"
+)
+
+var ErrUnknownPeer = errors.New("unknown peer")
+
+// SecureConn is a connection to another party, authenticated by the static
+// keys of both parties and encrypted with AES-GCM. Each Write is sent as one
+// or more frames made of the length of the ciphertext followed by the
+// ciphertext, the nonce being the number of frames sent before.
+type SecureConn struct {
+	net.Conn
+	Peer int
+
+	wmu     sync.Mutex
+	send    cipher.AEAD
+	sendSeq uint64
+
+	rmu     sync.Mutex
+	recv    cipher.AEAD
+	recvSeq uint64
+	pending []byte
+}
+
+// Handshake authenticates the party at the other end of conn, which must be
+// the party peer, and returns the secure channel to it. Both parties send an
+// ephemeral X25519 key along with their ID, then sign the transcript of
+// these messages with their static key. The keys of both directions are
+// derived from the Diffie-Hellman secret and the transcript.
+func Handshake(conn net.Conn, id *Identity, peer int) (*SecureConn, error) {
+	peerKey, ok := id.Peers[peer]
+	if !ok || peer == id.ID {
+		return nil, fmt.Errorf("%w %d", ErrUnknownPeer, peer)
+	}
+	conn.SetDeadline(time.Now().Add(handshakeTimeout))
+	defer conn.SetDeadline(time.Time{})
+
+	ephemeral, err := ecdh.X25519().GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	hello := binary.BigEndian.AppendUint32(nil, uint32(id.ID))
+	hello = append(hello, ephemeral.PublicKey().Bytes()...)
+	peerHello, err := exchange(conn, hello, len(hello))
+	if err != nil {
+		return nil, err
+	}
+	if claimed := int(binary.BigEndian.Uint32(peerHello)); claimed != peer {
+		return nil, fmt.Errorf("%w: party %d instead of %d", ErrUnknownPeer, claimed, peer)
+	}
+	peerEphemeral, err := ecdh.X25519().NewPublicKey(peerHello[4:])
+	if err != nil {
+		return nil, err
+	}
+	secret, err := ephemeral.ECDH(peerEphemeral)
+	if err != nil {
+		return nil, err
+	}
+
+	// The transcript orders the messages by party ID so that both parties
+	// compute the same one
+	transcript := blake3.NewDeriveKey(transcriptContext)
+	if id.ID < peer {
+		transcript.Write(hello)
+		transcript.Write(peerHello)
+	} else {
+		transcript.Write(peerHello)
+		transcript.Write(hello)
+	}
+	th := transcript.Sum(nil)
+
+	sig := ed25519.Sign(id.Key, signedTranscript(th, id.ID))
+	peerSig, err := exchange(conn, sig, ed25519.SignatureSize)
+	if err != nil {
+		return nil, err
+	}
+	if !ed25519.Verify(peerKey, signedTranscript(th, peer), peerSig) {
+		return nil, fmt.Errorf("invalid handshake signature of party %d", peer)
+	}
+
+	material := append(secret, th...)
+	lowToHigh, err := newAEAD(lowToHighContext, material)
+	if err != nil {
+		return nil, err
+	}
+	highToLow, err := newAEAD(highToLowContext, material)
+	if err != nil {
+		return nil, err
+	}
+	sc := &SecureConn{Conn: conn, Peer: peer, send: lowToHigh, recv: highToLow}
+	if id.ID > peer {
+		sc.send, sc.recv = highToLow, lowToHigh
+	}
+	return sc, nil
+}
+
+func signedTranscript(th []byte, signer int) []byte {
+	msg := append([]byte(signatureContext), th...)
+	return binary.BigEndian.AppendUint32(msg, uint32(signer))
+}
+
+func newAEAD(context string, material []byte) (cipher.AEAD, error) {
+	key := make([]byte, 32)
+	blake3.DeriveKey(context, material, key)
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// exchange sends msg while reading a message of size bytes from the peer,
+// as both parties send first.
+func exchange(conn net.Conn, msg []byte, size int) ([]byte, error) {
+	errc := make(chan error, 1)
+	go func() {
+		_, err := conn.Write(msg)
+		errc <- err
+	}()
+	peerMsg := make([]byte, size)
+	if _, err := io.ReadFull(conn, peerMsg); err != nil {
+		return nil, err
+	}
+	if err := <-errc; err != nil {
+		return nil, err
+	}
+	return peerMsg, nil
+}
+
+func (sc *SecureConn) nonce(seq uint64) []byte {
+	nonce := make([]byte, sc.send.NonceSize())
+	binary.BigEndian.PutUint64(nonce[len(nonce)-8:], seq)
+	return nonce
+}
+
+func (sc *SecureConn) Write(p []byte) (int, error) {
+	sc.wmu.Lock()
+	defer sc.wmu.Unlock()
+
+	written := 0
+	for len(p) > 0 {
+		chunk := p
+		if len(chunk) > maxFrameSize {
+			chunk = chunk[:maxFrameSize]
+		}
+		header := binary.BigEndian.AppendUint32(nil, uint32(len(chunk)+sc.send.Overhead()))
+		frame := sc.send.Seal(header, sc.nonce(sc.sendSeq), chunk, header)
+		sc.sendSeq++
+		if _, err := sc.Conn.Write(frame); err != nil {
+			return written, err
+		}
+		written += len(chunk)
+		p = p[len(chunk):]
+	}
+	return written, nil
+}
+
+func (sc *SecureConn) Read(p []byte) (int, error) {
+	sc.rmu.Lock()
+	defer sc.rmu.Unlock()
+
+	if len(sc.pending) == 0 {
+		header := make([]byte, 4)
+		if _, err := io.ReadFull(sc.Conn, header); err != nil {
+			return 0, err
+		}
+		size := binary.BigEndian.Uint32(header)
+		if size < uint32(sc.recv.Overhead()) || size > maxFrameSize+uint32(sc.recv.Overhead()) {
+			return 0, fmt.Errorf("invalid frame size %d from party %d", size, sc.Peer)
+		}
+		frame := make([]byte, size)
+		if _, err := io.ReadFull(sc.Conn, frame); err != nil {
+			return 0, err
+		}
+		plaintext, err := sc.recv.Open(frame[:0], sc.nonce(sc.recvSeq), frame, header)
+		if err != nil {
+			return 0, fmt.Errorf("invalid frame from party %d: %w", sc.Peer, err)
+		}
+		sc.recvSeq++
+		sc.pending = plaintext
+	}
+	n := copy(p, sc.pending)
+	sc.pending = sc.pending[n:]
+	return n, nil
+}
+
+// NewTestIdentities returns the identities of a committee of n parties, for
+// running every party in one process.
+func NewTestIdentities(n int) []*Identity {
+	ids := make([]*Identity, n)
+	peers := make(map[int]ed25519.PublicKey)
+	for i := range ids {
+		pub, priv, err := ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			panic(err)
+		}
+		ids[i] = &Identity{ID: i, Key: priv, Peers: peers}
+		peers[i] = pub
+	}
+	return ids
+}
+
+func identityFile(partyID int) string {
+	return fmt.Sprintf("party-%d.identity", partyID)
+}
+
+func publicIdentityFile(partyID int) string {
+	return identityFile(partyID) + ".pub"
+}
+
+// GenerateIdentity generates the identity key of party partyID, which each
+// party runs for itself so that its private key never leaves it. It writes
+// the private key into dir, readable by its owner only, and the public key
+// next to it, to be handed to the dealer for the committee file. An existing
+// identity is never overwritten.
+func GenerateIdentity(dir string, partyID int) (ed25519.PublicKey, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, err
+	}
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.OpenFile(filepath.Join(dir, identityFile(partyID)), os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := f.WriteString(hex.EncodeToString(priv.Seed()) + "\n"); err != nil {
+		f.Close()
+		return nil, err
+	}
+	if err := f.Close(); err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(filepath.Join(dir, publicIdentityFile(partyID)), []byte(hex.EncodeToString(pub)+"\n"), 0o644); err != nil {
+		return nil, err
+	}
+	return pub, nil
+}
+
+// ReadPublicIdentities reads the public keys written by GenerateIdentity
+// for the n parties of a committee, copied into dir.
+func ReadPublicIdentities(dir string, n int) ([]ed25519.PublicKey, error) {
+	public := make([]ed25519.PublicKey, n)
+	for i := range public {
+		data, err := os.ReadFile(filepath.Join(dir, publicIdentityFile(i)))
+		if err != nil {
+			return nil, err
+		}
+		key, err := hex.DecodeString(strings.TrimSpace(string(data)))
+		if err != nil || len(key) != ed25519.PublicKeySize {
+			return nil, fmt.Errorf("%s: malformed key", publicIdentityFile(i))
+		}
+		public[i] = key
+	}
+	return public, nil
+}
+
+// ReadIdentity reads the private key of party partyID from dir, as written
+// by GenerateIdentity, and takes the keys of the other parties from the
+// committee.
+func ReadIdentity(dir string, partyID int, committee *Committee) (*Identity, error) {
+	data, err := os.ReadFile(filepath.Join(dir, identityFile(partyID)))
+	if err != nil {
+		return nil, err
+	}
+	seed, err := hex.DecodeString(strings.TrimSpace(string(data)))
+	if err != nil || len(seed) != ed25519.SeedSize {
+		return nil, fmt.Errorf("%s: malformed key", identityFile(partyID))
+	}
+	id := &Identity{ID: partyID, Key: ed25519.NewKeyFromSeed(seed), Peers: committee.Peers()}
+	if !id.Key.Public().(ed25519.PublicKey).Equal(id.Peers[partyID]) {
+		return nil, fmt.Errorf("%s does not match the identity of party %d in the committee", identityFile(partyID), partyID)
+	}
+	return id, nil
+}
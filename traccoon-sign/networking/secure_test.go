@@ -0,0 +1,251 @@
+package networking
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
+	"errors"
+	"io"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// handshakePair runs the handshake of party a with party b over a pipe, b
+// being expected by a as party expected.
+func handshakePair(a, b *Identity, expected int) (*SecureConn, *SecureConn, error, error) {
+	ca, cb := net.Pipe()
+	var sb *SecureConn
+	var errB error
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		sb, errB = Handshake(cb, b, a.ID)
+		if errB != nil {
+			cb.Close()
+		}
+	}()
+	sa, errA := Handshake(ca, a, expected)
+	if errA != nil {
+		ca.Close()
+	}
+	<-done
+	return sa, sb, errA, errB
+}
+
+func TestHandshake(t *testing.T) {
+	ids := NewTestIdentities(2)
+	sa, sb, errA, errB := handshakePair(ids[0], ids[1], 1)
+	if errA != nil || errB != nil {
+		t.Fatal(errA, errB)
+	}
+	if sa.Peer != 1 || sb.Peer != 0 {
+		t.Fatalf("peers %d and %d", sa.Peer, sb.Peer)
+	}
+
+	// Messages spanning several frames in both directions at once
+	msgs := [2][]byte{make([]byte, 3*maxFrameSize+5), make([]byte, 100)}
+	rand.Read(msgs[0])
+	rand.Read(msgs[1])
+	var wg sync.WaitGroup
+	for i, sc := range []*SecureConn{sa, sb} {
+		wg.Add(1)
+		go func(sc *SecureConn, msg []byte) {
+			defer wg.Done()
+			if _, err := sc.Write(msg); err != nil {
+				t.Error(err)
+			}
+		}(sc, msgs[i])
+	}
+	for i, sc := range []*SecureConn{sb, sa} {
+		got := make([]byte, len(msgs[i]))
+		if _, err := io.ReadFull(sc, got); err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(got, msgs[i]) {
+			t.Fatalf("message %d corrupted", i)
+		}
+	}
+	wg.Wait()
+}
+
+func TestHandshakeRejects(t *testing.T) {
+	ids := NewTestIdentities(3)
+
+	t.Run("unknown key", func(t *testing.T) {
+		// Party 1 of another committee
+		impostor := NewTestIdentities(2)[1]
+		impostor.Peers[0] = ids[0].Peers[0]
+		_, _, err, _ := handshakePair(ids[0], impostor, 1)
+		if err == nil || !strings.Contains(err.Error(), "invalid handshake signature") {
+			t.Fatalf("got %v", err)
+		}
+	})
+
+	t.Run("other party", func(t *testing.T) {
+		// Party 2 answers a connection meant for party 1
+		_, _, err, _ := handshakePair(ids[0], ids[2], 1)
+		if !errors.Is(err, ErrUnknownPeer) {
+			t.Fatalf("got %v", err)
+		}
+	})
+
+	t.Run("out of the committee", func(t *testing.T) {
+		_, _, err, _ := handshakePair(ids[0], ids[1], 5)
+		if !errors.Is(err, ErrUnknownPeer) {
+			t.Fatalf("got %v", err)
+		}
+	})
+}
+
+// flipConn flips the last bit written.
+type flipConn struct {
+	net.Conn
+}
+
+func (c flipConn) Write(p []byte) (int, error) {
+	q := bytes.Clone(p)
+	q[len(q)-1] ^= 1
+	return c.Conn.Write(q)
+}
+
+func TestTamperedFrame(t *testing.T) {
+	ids := NewTestIdentities(2)
+	sa, sb, errA, errB := handshakePair(ids[0], ids[1], 1)
+	if errA != nil || errB != nil {
+		t.Fatal(errA, errB)
+	}
+	sa.Conn = flipConn{sa.Conn}
+	go sa.Write([]byte("round 1"))
+	if _, err := sb.Read(make([]byte, 16)); err == nil || !strings.Contains(err.Error(), "invalid frame") {
+		t.Fatalf("got %v", err)
+	}
+}
+
+// TestSecureConnections runs a committee of three parties on loopback.
+func TestSecureConnections(t *testing.T) {
+	n := 3
+	ids := NewTestIdentities(n)
+	var keys []ed25519.PublicKey
+	for i := range ids {
+		keys = append(keys, ids[i].Peers[i])
+	}
+	committee := LocalCommittee(keys)
+	comms := make([]*P2PComm, n)
+	var wg sync.WaitGroup
+	for i := range comms {
+		comms[i] = &P2PComm{Socks: make(map[int]*net.Conn), Rank: i, Identity: ids[i]}
+		wg.Add(1)
+		go func(comm *P2PComm) {
+			defer wg.Done()
+			if err := EstablishConnections(comm, committee, nil); err != nil {
+				t.Error(err)
+			}
+		}(comms[i])
+	}
+	wg.Wait()
+	if t.Failed() {
+		t.FailNow()
+	}
+	defer func() {
+		for _, comm := range comms {
+			comm.Close()
+		}
+	}()
+
+	for i, comm := range comms {
+		for j := 0; j < n; j++ {
+			if j == i {
+				continue
+			}
+			if _, ok := (*comm.GetSock(j)).(*SecureConn); !ok {
+				t.Fatalf("connection of party %d to %d is not secure", i, j)
+			}
+			wg.Add(1)
+			go func(comm *P2PComm, j int) {
+				defer wg.Done()
+				msg := []byte{byte(comm.Rank), byte(j)}
+				if _, err := comm.Send(j, msg); err != nil {
+					t.Error(err)
+				}
+			}(comm, j)
+		}
+	}
+	for i, comm := range comms {
+		for j := 0; j < n; j++ {
+			if j == i {
+				continue
+			}
+			msg, _, err := comm.Recv(j)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(msg, []byte{byte(j), byte(i)}) {
+				t.Fatalf("party %d got %v from %d", i, msg, j)
+			}
+		}
+	}
+	wg.Wait()
+}
+
+func TestGenerateIdentity(t *testing.T) {
+	dir := t.TempDir()
+	var public []ed25519.PublicKey
+	for i := 0; i < 2; i++ {
+		pub, err := GenerateIdentity(dir, i)
+		if err != nil {
+			t.Fatal(err)
+		}
+		public = append(public, pub)
+	}
+	if _, err := GenerateIdentity(dir, 0); err == nil {
+		t.Fatal("existing identity overwritten")
+	}
+
+	read, err := ReadPublicIdentities(dir, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range public {
+		if !read[i].Equal(public[i]) {
+			t.Fatalf("public key of party %d changed", i)
+		}
+	}
+
+	committee := LocalCommittee(read)
+	id, err := ReadIdentity(dir, 1, committee)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !id.Key.Public().(ed25519.PublicKey).Equal(public[1]) {
+		t.Fatal("private key does not match the public key")
+	}
+	if _, err := ReadIdentity(dir, 1, LocalCommittee([]ed25519.PublicKey{public[1], public[0]})); err == nil {
+		t.Fatal("identity of another party accepted")
+	}
+	if _, err := ReadPublicIdentities(dir, 3); err == nil {
+		t.Fatal("missing identity accepted")
+	}
+}
+
+// TestEstablishConnectionsError checks that a party failing to listen for
+// another gets an error rather than a communicator without the connection.
+func TestEstablishConnectionsError(t *testing.T) {
+	ids := NewTestIdentities(2)
+	committee := LocalCommittee([]ed25519.PublicKey{ids[0].Peers[0], ids[1].Peers[1]})
+
+	// Party 0 listens for party 1 on a port already taken
+	l, err := net.Listen("tcp", "0.0.0.0:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	committee.Members[0].Port = l.Addr().(*net.TCPAddr).Port - 1
+
+	comm := &P2PComm{Socks: make(map[int]*net.Conn), Rank: 0, Identity: ids[0]}
+	err = EstablishConnections(comm, committee, nil)
+	if err == nil || !strings.Contains(err.Error(), "party 1") {
+		t.Fatalf("got %v", err)
+	}
+}
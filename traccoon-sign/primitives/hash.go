@@ -13,9 +13,34 @@ import (
 
 const keySize = 32
 
+// Contexts of the hashes, each hash deriving its key from its own context so
+// that the outputs of one are unrelated to those of another
+const (
+	messageContext    = "traccoon-sign v1 message"
+	commitmentContext = "traccoon-sign v1 commitment"
+	precomputeContext = "traccoon-sign v1 precomputation"
+	challengeContext  = "traccoon-sign v1 challenge"
+	seedContext       = "traccoon-sign v1 seed commitment"
+	combineContext    = "traccoon-sign v1 seed combination"
+	deriveContext     = "traccoon-sign v1 seed derivation"
+	transcriptContext = "traccoon-sign v1 key generation transcript"
+)
+
+// Hashes a message signed under the encoded verification key vk for the
+// application identified by ctx, of at most 255 bytes, into mu
+func HashMessage(vk, ctx, msg []byte) []byte {
+	hasher := blake3.NewDeriveKey(messageContext)
+	hasher.Write(vk)
+	hasher.Write([]byte{byte(len(ctx))})
+	hasher.Write(ctx)
+	hasher.Write(msg)
+	hashOutput := hasher.Sum(nil)
+	return hashOutput[:keySize]
+}
+
 // Hashes a commitment
 func HashCommitment(A structs.Matrix[ring.Poly], b structs.Vector[ring.Poly], w structs.Vector[ring.Poly], partyID int) []byte {
-	hasher := blake3.New()
+	hasher := blake3.NewDeriveKey(commitmentContext)
 	buf := new(bytes.Buffer)
 
 	if _, err := A.WriteTo(buf); err != nil {
@@ -39,7 +64,7 @@ func HashCommitment(A structs.Matrix[ring.Poly], b structs.Vector[ring.Poly], w
 
 // Hashes precomputable values
 func Hash(A structs.Matrix[ring.Poly], b structs.Vector[ring.Poly], D map[int]structs.Vector[ring.Poly], sid int, T []int) []byte {
-	hasher := blake3.New()
+	hasher := blake3.NewDeriveKey(precomputeContext)
 	buf := new(bytes.Buffer)
 
 	if _, err := A.WriteTo(buf); err != nil {
@@ -64,9 +89,9 @@ func Hash(A structs.Matrix[ring.Poly], b structs.Vector[ring.Poly], D map[int]st
 	return hashOutput[:keySize]
 }
 
-// Hashes to low norm ring elements
-func LowNormHash(r *ring.Ring, A structs.Matrix[ring.Poly], b structs.Vector[ring.Poly], h structs.Vector[ring.Poly], mu string, kappa int) ring.Poly {
-	hasher := blake3.New()
+// Hashes to low norm ring elements, mu being the output of HashMessage
+func LowNormHash(r *ring.Ring, A structs.Matrix[ring.Poly], b structs.Vector[ring.Poly], h structs.Vector[ring.Poly], mu []byte, kappa int) ring.Poly {
+	hasher := blake3.NewDeriveKey(challengeContext)
 	buf := new(bytes.Buffer)
 
 	if _, err := A.WriteTo(buf); err != nil {
@@ -81,7 +106,7 @@ func LowNormHash(r *ring.Ring, A structs.Matrix[ring.Poly], b structs.Vector[rin
 		log.Fatalf("Error writing vector h: %v\n", err)
 	}
 
-	binary.Write(buf, binary.BigEndian, []byte(mu))
+	buf.Write(mu)
 
 	hasher.Write(buf.Bytes())
 	hashOutput := hasher.Sum(nil)
@@ -97,4 +122,62 @@ func LowNormHash(r *ring.Ring, A structs.Matrix[ring.Poly], b structs.Vector[rin
 	r.MForm(c, c)
 
 	return c
-}
\ No newline at end of file
+}
+// Hashes a seed contribution to the public matrix
+func HashSeed(seed []byte, partyID int) []byte {
+	hasher := blake3.NewDeriveKey(seedContext)
+	buf := new(bytes.Buffer)
+
+	buf.Write(seed)
+	binary.Write(buf, binary.BigEndian, int64(partyID))
+
+	hasher.Write(buf.Bytes())
+	hashOutput := hasher.Sum(nil)
+	return hashOutput[:keySize]
+}
+
+// Combines the seed contributions of the parties, in the order of their IDs,
+// into the key of the PRNG sampling the public matrix
+func CombineSeeds(seeds [][]byte) []byte {
+	hasher := blake3.NewDeriveKey(combineContext)
+	buf := new(bytes.Buffer)
+
+	for _, seed := range seeds {
+		buf.Write(seed)
+	}
+
+	hasher.Write(buf.Bytes())
+	hashOutput := hasher.Sum(nil)
+	return hashOutput[:keySize]
+}
+
+// Derives the key of the PRNG used for purpose from a master seed, so that
+// one seed drives several independent samplers
+func DeriveSeed(seed []byte, purpose string) []byte {
+	hasher := blake3.NewDeriveKey(deriveContext)
+	hasher.Write([]byte{byte(len(purpose))})
+	hasher.Write([]byte(purpose))
+	hasher.Write(seed)
+	hashOutput := hasher.Sum(nil)
+	return hashOutput[:keySize]
+}
+
+// Hashes the messages broadcast in the rounds of the key generation, each
+// round holding the message of every party in the order of their IDs, so that
+// the parties can check that they all received the same messages
+func HashTranscript(rounds ...[][]byte) []byte {
+	hasher := blake3.NewDeriveKey(transcriptContext)
+	buf := new(bytes.Buffer)
+
+	for _, msgs := range rounds {
+		binary.Write(buf, binary.BigEndian, uint32(len(msgs)))
+		for _, msg := range msgs {
+			binary.Write(buf, binary.BigEndian, uint32(len(msg)))
+			buf.Write(msg)
+		}
+	}
+
+	hasher.Write(buf.Bytes())
+	hashOutput := hasher.Sum(nil)
+	return hashOutput[:keySize]
+}
@@ -0,0 +1,132 @@
+package sign
+
+import (
+	"bench"
+	"errors"
+	"time"
+
+	"github.com/tuneinsight/lattigo/v5/ring"
+	"github.com/tuneinsight/lattigo/v5/utils/structs"
+)
+
+// maxBenchAttempts bounds the attempts of the benchmark to produce one
+// signature.
+const maxBenchAttempts = 100
+
+// Bench runs the scheme runs times for T out of N parties with the
+// parameters params and returns the measurements, without printing anything.
+func Bench(t, n, runs int, params *Params) (*bench.Result, error) {
+	msg := []byte("Message")
+	res := &bench.Result{Scheme: "t-raccoon", Params: params.Name, T: t, N: n, Runs: runs}
+	var keygen, combine, signDur, verifyDur []time.Duration
+	var rounds [3][]time.Duration
+	attempts := 0
+
+	T := make([]int, t)
+	for i := range T {
+		T[i] = i
+	}
+	for run := 0; run < runs; run++ {
+		start := time.Now()
+		pk, sks, err := NewThresholdKeys(t, n, params)
+		if err != nil {
+			return nil, err
+		}
+		keygen = append(keygen, time.Since(start))
+
+		parties := make([]*Party, n)
+		for i := range parties {
+			parties[i] = NewParty(i, pk)
+		}
+
+		signStart := time.Now()
+		mu, err := Mu(pk, msg, nil)
+		if err != nil {
+			return nil, err
+		}
+		// The combined signature may fail to verify even with honest
+		// parties, in which case they sign again
+		var c ring.Poly
+		var sig, Delta structs.Vector[ring.Poly]
+		attempt := 1
+		for ; ; attempt++ {
+			msgs1 := make(map[int][]byte)
+			strd1 := make(map[int]StRound1)
+			for _, partyID := range T {
+				start = time.Now()
+				msgs1[partyID], strd1[partyID] = parties[partyID].SignRound1(pk)
+				rounds[0] = append(rounds[0], time.Since(start))
+			}
+
+			msgs2 := make(map[int][]byte)
+			strd2 := make(map[int]StRound2)
+			for _, partyID := range T {
+				start = time.Now()
+				msgs2[partyID], strd2[partyID] = parties[partyID].SignRound2(pk, msgs1, strd1[partyID], mu, T)
+				rounds[1] = append(rounds[1], time.Since(start))
+			}
+
+			msgs3 := make(map[int][]byte)
+			for _, partyID := range T {
+				start = time.Now()
+				msgs3[partyID], err = parties[partyID].SignRound3(pk, &sks[partyID], msgs2, strd2[partyID], mu, T, n)
+				rounds[2] = append(rounds[2], time.Since(start))
+				if err != nil {
+					return nil, err
+				}
+			}
+
+			start = time.Now()
+			c, sig, Delta, err = parties[0].SignFinalize(pk, msgs2, msgs3, mu, T, n)
+			combine = append(combine, time.Since(start))
+			res.RoundBytes = [3]int{len(msgs1[0]), len(msgs2[0]), len(msgs3[0])}
+			if err == nil {
+				break
+			}
+			if !errors.Is(err, ErrInvalidSignature) || attempt == maxBenchAttempts {
+				return nil, err
+			}
+		}
+		signDur = append(signDur, time.Since(signStart))
+		attempts += attempt
+		if attempt > res.AttemptsMax {
+			res.AttemptsMax = attempt
+		}
+
+		encoded, err := (&Signature{Params: params, C: c, Z: sig, Delta: Delta}).MarshalBinary()
+		if err != nil {
+			return nil, err
+		}
+		res.SignatureBytes = len(encoded)
+
+		start = time.Now()
+		if !verify(pk, sig, mu, c, Delta) {
+			return nil, errors.New("verification failed")
+		}
+		verifyDur = append(verifyDur, time.Since(start))
+	}
+
+	res.AttemptsMean = float64(attempts) / float64(runs)
+	res.KeyGen = bench.Summarize(keygen)
+	for r := range rounds {
+		res.Rounds[r] = bench.Summarize(rounds[r])
+	}
+	res.Combine = bench.Summarize(combine)
+	res.Sign = bench.Summarize(signDur)
+	res.Verify = bench.Summarize(verifyDur)
+	return res, nil
+}
+
+// Sweep benchmarks every (T, N) with 2 <= T <= N <= maxN.
+func Sweep(maxN, runs int, params *Params, each func(*bench.Result)) error {
+	for n := 2; n <= maxN; n++ {
+		for t := 2; t <= n; t++ {
+			res, err := Bench(t, n, runs, params)
+			if err != nil {
+				return err
+			}
+			each(res)
+		}
+	}
+	return nil
+}
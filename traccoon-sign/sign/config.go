@@ -1,23 +1,147 @@
 package sign
 
-// PARAMETERS
+import (
+	"fmt"
+	"math"
+	"math/big"
+	"math/bits"
+)
+
+// Params is a set of parameters of T-Raccoon. Every set works over the rings
+// of degree 2^LogN, and is for a largest number of parties.
+type Params struct {
+	// ID identifies the set in encoded keys and signatures
+	ID   byte
+	Name string
+
+	// MaxParties is the largest number of parties dealt keys
+	MaxParties int
+
+	DimK      int
+	DimEll    int
+	Kappa     int     // weight of the challenge
+	Q         uint64  // NTT-friendly prime
+	QXi       uint64  // modulus of Btilde, 2^(bits(Q) - Xi) - 1
+	QNu       uint64  // modulus of the rounded commitments, 2^(bits(Q) - Nu) - 1
+	Xi        uint    // bits dropped from b
+	Nu        uint    // bits dropped from the commitments
+	SigmaE    float64 // key and error sampling
+	BoundE    float64
+	SigmaStar float64 // signing randomness
+	BoundStar float64
+
+	// B bounds the norm of a signature, Bsquare being float64(B*B)
+	B       float64
+	Bsquare string
+}
+
+// Parameters shared by every set
+const (
+	LogN    = 8  // degree 2^LogN of the rings, at most 256 for the challenge encoding
+	KeySize = 32 // 256 bits
+)
+
+// Roles of the parties, which do not depend on the parameters
 const (
-	DimK            = 8
-	DimEll          = 9
-	B               = 70939015634276.8
-	Bsquare         = "5032343939160168088238817280" // B^2
-	Kappa           = 23
-	LogN            = 8
-	SigmaE          = 16384
-	BoundE          = SigmaE * 15
-	SigmaStar       = 2147483648
-	BoundStar       = SigmaStar * 15
-	KeySize         = 32              // 256 bits
-	Q               = 562949953417729 // 49-bit NTT-friendly prime
-	QNu             = 4095
-	QXi             = 262143
 	TrustedDealerID = 0
 	CombinerID      = 1
-	Xi              = 31
-	Nu              = 37
 )
+
+// PARAMETERS
+//
+// TRaccoon128 is the set of Ringtail (eprint.iacr.org/2024/1113, Section 7),
+// for 128 bits of security and up to 1024 parties. The sets for fewer
+// parties keep its ranks, moduli and bound B: as z sums the r* of every
+// signer, each r* is sampled with a width SigmaStar grown by
+// sqrt(1024 / MaxParties), so that the sum over MaxParties signers has the
+// width of the paper, on which B and the security estimate rest.
+//
+// Every set is therefore for 128 bits of security. The paper gives no set for
+// a higher level, and one would need its own ranks and moduli, checked with
+// the lattice estimator, before being added here.
+var (
+	TRaccoon128 = &Params{
+		ID:         1,
+		Name:       "T-Raccoon-128",
+		MaxParties: 1024,
+		DimK:       8,
+		DimEll:     9,
+		Kappa:      23,
+		Q:          562949953417729, // 49 bits
+		QXi:        262143,
+		QNu:        4095,
+		Xi:         31,
+		Nu:         37,
+		SigmaE:     16384,
+		BoundE:     16384 * 15,
+		SigmaStar:  2147483648,
+		BoundStar:  2147483648 * 15,
+		B:          70939015634276.8,
+		Bsquare:    "5032343939160168088238817280",
+	}
+	TRaccoon128N256 = TRaccoon128.withMaxParties(2, 256)
+	TRaccoon128N64  = TRaccoon128.withMaxParties(3, 64)
+	TRaccoon128N16  = TRaccoon128.withMaxParties(4, 16)
+
+	// DefaultParams is the set used when none is given
+	DefaultParams = TRaccoon128
+
+	// ParamSets lists the sets which keys and signatures may be encoded with
+	ParamSets = []*Params{TRaccoon128, TRaccoon128N256, TRaccoon128N64, TRaccoon128N16}
+)
+
+// withMaxParties returns the set p for at most maxParties parties, which
+// must divide p.MaxParties, identified by id.
+func (p *Params) withMaxParties(id byte, maxParties int) *Params {
+	scale := math.Sqrt(float64(p.MaxParties / maxParties))
+	q := *p
+	q.ID = id
+	q.Name = fmt.Sprintf("%s-N%d", p.Name, maxParties)
+	q.MaxParties = maxParties
+	q.SigmaStar *= scale
+	q.BoundStar *= scale
+	return &q
+}
+
+// ParamsByName returns the set named name.
+func ParamsByName(name string) (*Params, error) {
+	for _, p := range ParamSets {
+		if p.Name == name {
+			return p, nil
+		}
+	}
+	return nil, fmt.Errorf("unknown parameter set %q", name)
+}
+
+// paramsByID returns the set identified by id in an encoding.
+func paramsByID(id byte) (*Params, error) {
+	for _, p := range ParamSets {
+		if p.ID == id {
+			return p, nil
+		}
+	}
+	return nil, fmt.Errorf("unknown parameter set %d", id)
+}
+
+// BoundW bounds the infinity norm of w - (A*z - c*t) checked for each party
+// in SignFinalize, with n parties holding shares. An honest one is e* + c*e,
+// where the error e of a commitment t sums those of all n parties of a
+// distributed key generation, and c has Kappa coefficients of 1 or -1.
+func (p *Params) BoundW(n int) uint64 {
+	return uint64(p.BoundStar) + uint64(p.Kappa)*uint64(n)*uint64(p.BoundE)
+}
+
+// BoundZ bounds the infinity norm of the response z = r* + c*s checked for
+// each party in SignFinalize, c*s being much smaller than BoundStar.
+func (p *Params) BoundZ() uint64 { return 2 * uint64(p.BoundStar) }
+
+// Bits of the coefficients modulo QNu, QXi and Q
+func (p *Params) NuBits() uint { return uint(bits.Len64(p.QNu - 1)) }
+func (p *Params) XiBits() uint { return uint(bits.Len64(p.QXi - 1)) }
+func (p *Params) QBits() uint  { return uint(bits.Len64(p.Q - 1)) }
+
+// bsquare returns Bsquare as an integer.
+func (p *Params) bsquare() *big.Int {
+	Bsquare, _ := new(big.Int).SetString(p.Bsquare, 10)
+	return Bsquare
+}
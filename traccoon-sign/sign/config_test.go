@@ -0,0 +1,61 @@
+package sign
+
+import (
+	"math"
+	"math/big"
+	"testing"
+)
+
+func TestParamSets(t *testing.T) {
+	ids := make(map[byte]bool)
+	for _, p := range ParamSets {
+		if ids[p.ID] {
+			t.Fatalf("%s: ID %d used twice", p.Name, p.ID)
+		}
+		ids[p.ID] = true
+
+		if found, err := ParamsByName(p.Name); err != nil || found != p {
+			t.Fatalf("%s: not found by name", p.Name)
+		}
+
+		// Bsquare is float64(B*B), the bound verification was written for
+		want, _ := big.NewFloat(p.B * p.B).Int(nil)
+		if p.bsquare() == nil || p.bsquare().Cmp(want) != 0 {
+			t.Errorf("%s: Bsquare is %s instead of %s", p.Name, p.Bsquare, want)
+		}
+
+		// The sum of the r* of MaxParties signers is as wide as for the set
+		// of the paper, whose B bounds it
+		sum := p.SigmaStar * math.Sqrt(float64(p.MaxParties))
+		paper := TRaccoon128.SigmaStar * math.Sqrt(float64(TRaccoon128.MaxParties))
+		if sum != paper || p.B != TRaccoon128.B {
+			t.Errorf("%s: r* sums to a width of %g for B = %g, instead of %g for %g", p.Name, sum, p.B, paper, TRaccoon128.B)
+		}
+
+		if _, _, err := NewThresholdKeys(2, p.MaxParties+1, p); err == nil {
+			t.Errorf("%s: dealt keys to more than %d parties", p.Name, p.MaxParties)
+		}
+	}
+	if _, err := ParamsByName("T-Raccoon-64"); err == nil {
+		t.Fatal("unknown parameter set found")
+	}
+}
+
+// TestMaxParties signs with as many parties as TRaccoon128N16 is for, whose
+// responses are the widest its bound B has to hold.
+func TestMaxParties(t *testing.T) {
+	p := TRaccoon128N16
+	N := p.MaxParties
+	pk, sks, err := NewThresholdKeys(N, N, p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	T := make([]int, N)
+	for i := range T {
+		T[i] = i
+	}
+	results := runSigning(t, pk, sks, T, N, []byte("Message"), nil)
+	if !results[Combiner(T)].Valid {
+		t.Fatal("invalid signature")
+	}
+}
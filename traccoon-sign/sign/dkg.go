@@ -0,0 +1,423 @@
+package sign
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"traccoon-sign/primitives"
+	"traccoon-sign/utils"
+
+	"github.com/tuneinsight/lattigo/v5/ring"
+	"github.com/tuneinsight/lattigo/v5/utils/sampling"
+	"github.com/tuneinsight/lattigo/v5/utils/structs"
+)
+
+// DKG holds the state of one party in the distributed generation of a T out
+// of N key, which needs no trusted dealer. Every party i samples a secret s_i
+// and an error e_i, and shares s_i with Share as the dealer of
+// NewThresholdKeys shares s. The key is then s = sum s_i with b = sum b_i,
+// where b_i = A*s_i + e_i, and the share of index idx of a party is the sum of
+// the shares of index idx it received.
+//
+//   - Round 1: commit to a seed, and send its shares of s_i to every party
+//   - Round 2: reveal the seed, A being sampled from the seeds of all parties
+//   - Round 3: publish b_i and a commitment t = A*x + e to every share x of s_i
+//   - Round 4: check the received shares against their commitments, and
+//     publish a hash of the transcript
+//
+// Finalize then checks that every party hashed the same transcript before
+// returning the keys of the party. The messages of rounds 1 to 3 other than
+// the shares are meant for every party, and a party sending different ones to
+// different parties would leave them with different keys, so the transcript
+// covers all of them: the seed commitments, the seeds, and the messages of
+// round 3, which start with a hash of the first two. All the messages of a
+// round, including the own ones of the party, are passed to the next round
+// keyed by party ID.
+type DKG struct {
+	ID     int
+	T      int
+	N      int
+	Params *Params
+
+	r       *ring.Ring
+	sampler *ring.GaussianSampler
+
+	seed   []byte
+	s      structs.Vector[ring.Poly]
+	shares ShareMap
+
+	commits  map[int][]byte
+	received map[int]map[string]structs.Vector[ring.Poly]
+	A        structs.Matrix[ring.Poly]
+	b        structs.Vector[ring.Poly]
+	t        map[string]structs.Vector[ring.Poly]
+
+	seeds  map[int][]byte
+	digest []byte
+	pk     *PublicKey
+	sk     *PrivateKey
+}
+
+// NewDKG initializes the key generation of party id with the parameters
+// params.
+func NewDKG(id, T, N int, params *Params) (*DKG, error) {
+	if T == 0 || T > N {
+		return nil, errors.New("Invalid threshold parameters")
+	}
+	if N > params.MaxParties {
+		return nil, fmt.Errorf("%d parties, more than the %d of %s", N, params.MaxParties, params.Name)
+	}
+	if id < 0 || id >= N {
+		return nil, fmt.Errorf("no party %d out of %d", id, N)
+	}
+	r, _, _ := params.newRings()
+	prng, _ := sampling.NewPRNG()
+	gaussianParams := ring.DiscreteGaussian{Sigma: params.SigmaE, Bound: params.BoundE}
+	return &DKG{
+		ID:      id,
+		T:       T,
+		N:       N,
+		Params:  params,
+		r:       r,
+		sampler: ring.NewGaussianSampler(prng, r, gaussianParams, false),
+	}, nil
+}
+
+// parties returns the parties [0, 1, ... , N-1]
+func (d *DKG) parties() []int {
+	P := make([]int, d.N)
+	for i := range P {
+		P[i] = i
+	}
+	return P
+}
+
+// Round1 samples the secret of the party and returns the commitment to its
+// seed, to be sent to every party, and the shares of its secret, shares[j]
+// to be sent to party j only.
+func (d *DKG) Round1() ([]byte, map[int][]byte) {
+	d.seed = make([]byte, KeySize)
+	if _, err := rand.Read(d.seed); err != nil {
+		panic(err)
+	}
+	d.s = utils.SamplePolyVector(d.r, d.Params.DimEll, d.sampler, false, false)
+
+	s_copy := utils.InitializeVector(d.r, d.Params.DimEll)
+	utils.VectorAdd(d.r, d.s, s_copy, s_copy)
+	d.shares = Share(d.r, d.sampler, s_copy, d.parties(), d.T, "")
+
+	shares := make(map[int][]byte)
+	for j, userShares := range d.shares {
+		shares[j] = encodeVectorMap(userShares)
+	}
+	return primitives.HashSeed(d.seed, d.ID), shares
+}
+
+// Round2 stores the seed commitments and the shares received from every party
+// and returns the seed of the party. It returns an AbortError naming the
+// parties whose shares are missing or malformed.
+func (d *DKG) Round2(commits map[int][]byte, shares map[int][]byte) ([]byte, error) {
+	var culprits []int
+	d.commits = commits
+	d.received = make(map[int]map[string]structs.Vector[ring.Poly])
+	for _, j := range d.parties() {
+		m, err := decodeVectorMap(shares[j], d.Params.DimEll)
+		if err != nil || len(commits[j]) != KeySize || !sameIndices(m, d.shares[d.ID]) {
+			culprits = append(culprits, j)
+			continue
+		}
+		d.received[j] = m
+	}
+	if err := abort(1, culprits); err != nil {
+		return nil, err
+	}
+	return d.seed, nil
+}
+
+// Round3 checks the seeds against their commitments, samples A, and returns
+// the hash of the commitments and the seeds, b_i and the commitments to the
+// shares of the secret of the party, to be sent to every party. It returns an
+// AbortError naming the parties whose seed does not match their commitment.
+func (d *DKG) Round3(seeds map[int][]byte) ([]byte, error) {
+	var culprits []int
+	ordered := make([][]byte, d.N)
+	for _, j := range d.parties() {
+		if !bytes.Equal(primitives.HashSeed(seeds[j], j), d.commits[j]) {
+			culprits = append(culprits, j)
+			continue
+		}
+		ordered[j] = seeds[j]
+	}
+	if err := abort(2, culprits); err != nil {
+		return nil, err
+	}
+
+	d.seeds = seeds
+	d.seed = primitives.CombineSeeds(ordered)
+	d.A = d.Params.expandA(d.r, d.seed)
+
+	d.b = d.Params.commitShare(d.r, d.A, d.sampler, d.s)
+	d.t = make(map[string]structs.Vector[ring.Poly])
+	for _, userShares := range d.shares {
+		for idx, x := range userShares {
+			if _, ok := d.t[idx]; !ok {
+				d.t[idx] = d.Params.commitShare(d.r, d.A, d.sampler, x)
+			}
+		}
+	}
+
+	buf := bytes.NewBuffer(d.transcript(d.commits, d.seeds))
+	if _, err := d.b.WriteTo(buf); err != nil {
+		panic(err)
+	}
+	buf.Write(encodeVectorMap(d.t))
+	return buf.Bytes(), nil
+}
+
+// Round4 checks the messages of round 3, computes the keys of the party, and
+// returns the hash of the transcript, to be sent to every party. Every party
+// must have hashed the same commitments and seeds, the shares received from
+// party j must be short and match the commitments of party j, and the
+// commitments of party j must add up to b_j for every set of T parties.
+// Round4 returns an AbortError naming the parties failing these checks. The
+// parties whose hash of the commitments and seeds differs come first, as
+// different seeds lead to different commitments: the party cannot tell which
+// of them equivocated, but no key is to be used.
+func (d *DKG) Round4(msgs3 map[int][]byte) ([]byte, error) {
+	var culprits []int
+	digest := d.transcript(d.commits, d.seeds)
+	for _, j := range d.parties() {
+		if len(msgs3[j]) < KeySize || !bytes.Equal(msgs3[j][:KeySize], digest) {
+			culprits = append(culprits, j)
+		}
+	}
+	if err := abort(3, culprits); err != nil {
+		return nil, err
+	}
+
+	r := d.r
+	_, r_xi, r_nu := d.Params.newRings()
+	bs := make(map[int]structs.Vector[ring.Poly])
+	ts := make(map[int]map[string]structs.Vector[ring.Poly])
+	for _, j := range d.parties() {
+		b_j, t_j, err := decodeRound3(msgs3[j][KeySize:], d.Params.DimK)
+		if err != nil || !sameIndices(t_j, d.t) || !d.checkShares(d.received[j], t_j) || !d.checkCommitments(b_j, t_j) {
+			culprits = append(culprits, j)
+			continue
+		}
+		bs[j], ts[j] = b_j, t_j
+	}
+	if err := abort(3, culprits); err != nil {
+		return nil, err
+	}
+
+	pk := &PublicKey{
+		Params:           d.Params,
+		Ring:             r,
+		RingXi:           r_xi,
+		RingNu:           r_nu,
+		Seed:             d.seed,
+		A:                d.A,
+		ShareCommitments: make(map[string]structs.Vector[ring.Poly]),
+	}
+	sk := &PrivateKey{
+		Params: d.Params,
+		ID:     d.ID,
+		Shares: make(map[string]structs.Vector[ring.Poly]),
+	}
+
+	b := utils.InitializeVector(r, d.Params.DimK)
+	for _, j := range d.parties() {
+		utils.VectorAdd(r, b, bs[j], b)
+		for idx, t_j := range ts[j] {
+			if _, ok := pk.ShareCommitments[idx]; !ok {
+				pk.ShareCommitments[idx] = utils.InitializeVector(r, d.Params.DimK)
+			}
+			utils.VectorAdd(r, pk.ShareCommitments[idx], t_j, pk.ShareCommitments[idx])
+		}
+		for idx, x := range d.received[j] {
+			if _, ok := sk.Shares[idx]; !ok {
+				sk.Shares[idx] = utils.InitializeVector(r, d.Params.DimEll)
+			}
+			utils.VectorAdd(r, sk.Shares[idx], x, sk.Shares[idx])
+		}
+	}
+	pk.Btilde = utils.RoundVector(r, r_xi, b, d.Params.Xi)
+
+	d.pk, d.sk = pk, sk
+	d.digest = d.transcript(d.commits, d.seeds, msgs3)
+	return d.digest, nil
+}
+
+// Finalize checks the hashes of the transcript of every party and returns the
+// keys of the party. It returns an AbortError naming the parties whose hash
+// differs from the one of the party, which received other messages than them.
+func (d *DKG) Finalize(digests map[int][]byte) (*PublicKey, *PrivateKey, error) {
+	var culprits []int
+	for _, j := range d.parties() {
+		if !bytes.Equal(digests[j], d.digest) {
+			culprits = append(culprits, j)
+		}
+	}
+	if err := abort(4, culprits); err != nil {
+		return nil, nil, err
+	}
+	return d.pk, d.sk, nil
+}
+
+// transcript hashes the messages of the given rounds in the order of the
+// parties.
+func (d *DKG) transcript(rounds ...map[int][]byte) []byte {
+	ordered := make([][][]byte, len(rounds))
+	for r, msgs := range rounds {
+		ordered[r] = make([][]byte, d.N)
+		for _, j := range d.parties() {
+			ordered[r][j] = msgs[j]
+		}
+	}
+	return primitives.HashTranscript(ordered...)
+}
+
+// checkShares checks the shares received from a party against its
+// commitments t: a share x is short and t - A*x is within BoundE.
+func (d *DKG) checkShares(received, t map[string]structs.Vector[ring.Poly]) bool {
+	// A share sums or subtracts at most N + log(N) + 1 Gaussian samples
+	boundE := uint64(d.Params.BoundE)
+	shareBound := uint64(2*d.N) * boundE
+	for idx, x := range received {
+		if !withinBound(d.r, x, shareBound) {
+			return false
+		}
+		e := mulA(d.r, d.A, x)
+		utils.VectorSub(d.r, t[idx], e, e)
+		if !withinBound(d.r, e, boundE) {
+			return false
+		}
+	}
+	return true
+}
+
+// checkCommitments checks that the commitments t of a party add up to its
+// b, up to the errors, for every set of T parties, so that every set signs
+// with the same secret.
+func (d *DKG) checkCommitments(b structs.Vector[ring.Poly], t map[string]structs.Vector[ring.Poly]) bool {
+	P := d.parties()
+	act := make([]int, 0, d.T)
+	var check func(next int) bool
+	check = func(next int) bool {
+		if len(act) == d.T {
+			diff := utils.InitializeVector(d.r, d.Params.DimK)
+			for _, idx := range Recover(append([]int{}, act...), P, "") {
+				utils.VectorAdd(d.r, diff, t[idx], diff)
+			}
+			utils.VectorSub(d.r, diff, b, diff)
+			return withinBound(d.r, diff, uint64(d.T+1)*uint64(d.Params.BoundE))
+		}
+		for j := next; j < d.N; j++ {
+			act = append(act, j)
+			ok := check(j + 1)
+			act = act[:len(act)-1]
+			if !ok {
+				return false
+			}
+		}
+		return true
+	}
+	return check(0)
+}
+
+// sameIndices reports whether two share maps hold the same indices.
+func sameIndices(m1, m2 map[string]structs.Vector[ring.Poly]) bool {
+	if len(m1) != len(m2) {
+		return false
+	}
+	for idx := range m1 {
+		if _, ok := m2[idx]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// decodeRound3 decodes b_i and the commitments of a message of round 3, all
+// of dimension dimK.
+func decodeRound3(buf []byte, dimK int) (structs.Vector[ring.Poly], map[string]structs.Vector[ring.Poly], error) {
+	if buf == nil {
+		return nil, nil, errors.New("missing message")
+	}
+	reader := bufio.NewReader(bytes.NewReader(buf))
+	var b structs.Vector[ring.Poly]
+	if _, err := b.ReadFrom(reader); err != nil {
+		return nil, nil, err
+	}
+	if err := checkVector(b, dimK); err != nil {
+		return nil, nil, err
+	}
+	t, err := readVectorMap(reader, dimK)
+	return b, t, err
+}
+
+// encodeVectorMap serializes a map of vectors, such as the shares of a
+// PrivateKey, in the order of its indices.
+func encodeVectorMap(m map[string]structs.Vector[ring.Poly]) []byte {
+	indices := make([]string, 0, len(m))
+	for idx := range m {
+		indices = append(indices, idx)
+	}
+	sort.Strings(indices)
+
+	buf := new(bytes.Buffer)
+	binary.Write(buf, binary.BigEndian, uint32(len(m)))
+	for _, idx := range indices {
+		binary.Write(buf, binary.BigEndian, uint16(len(idx)))
+		buf.WriteString(idx)
+		if _, err := m[idx].WriteTo(buf); err != nil {
+			panic(err)
+		}
+	}
+	return buf.Bytes()
+}
+
+func decodeVectorMap(buf []byte, dim int) (map[string]structs.Vector[ring.Poly], error) {
+	if buf == nil {
+		return nil, errors.New("missing message")
+	}
+	return readVectorMap(bufio.NewReader(bytes.NewReader(buf)), dim)
+}
+
+// readVectorMap reads a map of vectors of dim polynomials written by
+// encodeVectorMap.
+func readVectorMap(reader *bufio.Reader, dim int) (map[string]structs.Vector[ring.Poly], error) {
+	var count uint32
+	if err := binary.Read(reader, binary.BigEndian, &count); err != nil {
+		return nil, err
+	}
+	m := make(map[string]structs.Vector[ring.Poly])
+	for i := uint32(0); i < count; i++ {
+		var length uint16
+		if err := binary.Read(reader, binary.BigEndian, &length); err != nil {
+			return nil, err
+		}
+		idx := make([]byte, length)
+		if _, err := io.ReadFull(reader, idx); err != nil {
+			return nil, err
+		}
+		var v structs.Vector[ring.Poly]
+		if _, err := v.ReadFrom(reader); err != nil {
+			return nil, err
+		}
+		if err := checkVector(v, dim); err != nil {
+			return nil, err
+		}
+		m[string(idx)] = v
+	}
+	if _, err := reader.ReadByte(); err != io.EOF {
+		return nil, errors.New("trailing data")
+	}
+	return m, nil
+}
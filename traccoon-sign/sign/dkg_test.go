@@ -0,0 +1,228 @@
+package sign
+
+import (
+	"testing"
+	"traccoon-sign/primitives"
+)
+
+// newDKGs initializes the key generation of N parties.
+func newDKGs(t *testing.T, T, N int) []*DKG {
+	t.Helper()
+	dkgs := make([]*DKG, N)
+	for i := range dkgs {
+		var err error
+		if dkgs[i], err = NewDKG(i, T, N, DefaultParams); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dkgs
+}
+
+// runDKG runs the key generation of N parties, with tamper altering the
+// messages received by party to in each round before they are delivered,
+// keyed by sender. The messages of round 1 are the commitment to the seed of
+// the sender followed by its shares to party to, as sent by RunDKG. runDKG
+// returns the keys of every party, or the error of the first party failing.
+func runDKG(t *testing.T, T, N int, tamper func(round, to int, msgs map[int][]byte)) (*PublicKey, []*PrivateKey, error) {
+	t.Helper()
+	return runDKGs(newDKGs(t, T, N), tamper)
+}
+
+func runDKGs(dkgs []*DKG, tamper func(round, to int, msgs map[int][]byte)) (*PublicKey, []*PrivateKey, error) {
+	N := len(dkgs)
+	received := make([]map[int][]byte, N) // received[j][i] sent by i to j
+	for j := range received {
+		received[j] = make(map[int][]byte)
+	}
+	for i, d := range dkgs {
+		commit, shares := d.Round1()
+		for j, buf := range shares {
+			received[j][i] = append(append([]byte{}, commit...), buf...)
+		}
+	}
+
+	out := make(map[int][]byte)
+	for j, d := range dkgs {
+		tamper(1, j, received[j])
+		commits := make(map[int][]byte)
+		shares := make(map[int][]byte)
+		for i, msg := range received[j] {
+			commits[i], shares[i] = msg[:KeySize], msg[KeySize:]
+		}
+		var err error
+		if out[j], err = d.Round2(commits, shares); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	// broadcast delivers the messages of round to every party after tamper
+	broadcast := func(round int) []map[int][]byte {
+		msgs := make([]map[int][]byte, N)
+		for j := range msgs {
+			msgs[j] = make(map[int][]byte)
+			for i, msg := range out {
+				msgs[j][i] = msg
+			}
+			tamper(round, j, msgs[j])
+		}
+		return msgs
+	}
+
+	seeds := broadcast(2)
+	for j, d := range dkgs {
+		var err error
+		if out[j], err = d.Round3(seeds[j]); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	msgs3 := broadcast(3)
+	for j, d := range dkgs {
+		var err error
+		if out[j], err = d.Round4(msgs3[j]); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	digests := broadcast(4)
+	var pk *PublicKey
+	sks := make([]*PrivateKey, N)
+	for j, d := range dkgs {
+		var err error
+		if pk, sks[j], err = d.Finalize(digests[j]); err != nil {
+			return nil, nil, err
+		}
+	}
+	return pk, sks, nil
+}
+
+func TestDKG(t *testing.T) {
+	for _, tc := range []struct{ T, N int }{{1, 2}, {2, 3}, {3, 5}, {4, 4}} {
+		pk, sks, err := runDKG(t, tc.T, tc.N, func(int, int, map[int][]byte) {})
+		if err != nil {
+			t.Fatal(err)
+		}
+		mu := message(t, pk)
+
+		// Sign with the first and the last T parties
+		for _, first := range []int{0, tc.N - tc.T} {
+			T := make([]int, tc.T)
+			for i := range T {
+				T[i] = first + i
+			}
+			parties := make(map[int]*Party)
+			msgs1 := make(map[int][]byte)
+			strd1 := make(map[int]StRound1)
+			for _, ID := range T {
+				parties[ID] = NewParty(ID, pk)
+				msgs1[ID], strd1[ID] = parties[ID].SignRound1(pk)
+			}
+			msgs2 := make(map[int][]byte)
+			strd2 := make(map[int]StRound2)
+			for _, ID := range T {
+				msgs2[ID], strd2[ID] = parties[ID].SignRound2(pk, msgs1, strd1[ID], mu, T)
+			}
+			msgs3 := make(map[int][]byte)
+			for _, ID := range T {
+				if msgs3[ID], err = parties[ID].SignRound3(pk, sks[ID], msgs2, strd2[ID], mu, T, tc.N); err != nil {
+					t.Fatal(err)
+				}
+			}
+			c, z, Delta, err := parties[T[0]].SignFinalize(pk, msgs2, msgs3, mu, T, tc.N)
+			if err != nil {
+				t.Fatalf("T=%d N=%d signers %v: %v", tc.T, tc.N, T, err)
+			}
+			if !verify(pk, z, mu, c, Delta) {
+				t.Fatalf("T=%d N=%d signers %v: invalid signature", tc.T, tc.N, T)
+			}
+		}
+	}
+}
+
+func TestDKGAborts(t *testing.T) {
+	t.Run("wrong share", func(t *testing.T) {
+		_, _, err := runDKG(t, 2, 3, func(round, to int, msgs map[int][]byte) {
+			if round == 1 && to == 0 {
+				// Party 1 sends party 0 a share off by one
+				m, _ := decodeVectorMap(msgs[1][KeySize:], DefaultParams.DimEll)
+				for _, x := range m {
+					x[0].Coeffs[0][0]++
+				}
+				msgs[1] = append(msgs[1][:KeySize], encodeVectorMap(m)...)
+			}
+		})
+		requireCulprits(t, err, 3, []int{1})
+	})
+
+	t.Run("missing share", func(t *testing.T) {
+		_, _, err := runDKG(t, 2, 3, func(round, to int, msgs map[int][]byte) {
+			if round == 1 && to == 0 {
+				delete(msgs, 2)
+			}
+		})
+		requireCulprits(t, err, 1, []int{2})
+	})
+
+	t.Run("wrong seed", func(t *testing.T) {
+		_, _, err := runDKG(t, 2, 3, func(round, _ int, msgs map[int][]byte) {
+			if round == 2 {
+				msgs[0] = make([]byte, KeySize)
+			}
+		})
+		requireCulprits(t, err, 2, []int{0})
+	})
+
+	t.Run("inconsistent commitments", func(t *testing.T) {
+		_, _, err := runDKG(t, 3, 4, func(round, _ int, msgs map[int][]byte) {
+			if round == 3 {
+				// Party 3 publishes the b of party 2
+				b_2, _, _ := decodeRound3(msgs[2][KeySize:], DefaultParams.DimK)
+				_, t_3, _ := decodeRound3(msgs[3][KeySize:], DefaultParams.DimK)
+				buf, _ := b_2.MarshalBinary()
+				msgs[3] = append(append(msgs[3][:KeySize:KeySize], buf...), encodeVectorMap(t_3)...)
+			}
+		})
+		requireCulprits(t, err, 3, []int{3})
+	})
+}
+
+// TestDKGEquivocation has party 2 send different messages to parties 0 and 1
+// where it should send the same to both, which must abort the key generation
+// rather than leave them with different keys.
+func TestDKGEquivocation(t *testing.T) {
+	t.Run("seed", func(t *testing.T) {
+		// Party 2 commits to another seed towards party 1, and reveals it
+		other := make([]byte, KeySize)
+		other[0] = 1
+		_, _, err := runDKG(t, 2, 3, func(round, to int, msgs map[int][]byte) {
+			switch {
+			case round == 1 && to == 1:
+				copy(msgs[2], primitives.HashSeed(other, 2))
+			case round == 2 && to == 1:
+				msgs[2] = other
+			}
+		})
+		// Party 0 is the first to see that party 1 hashed other seeds
+		requireCulprits(t, err, 3, []int{1})
+	})
+
+	t.Run("round 3", func(t *testing.T) {
+		// Party 2 sends party 1 a second message of round 3, with other
+		// errors, which passes every check of party 1
+		dkgs := newDKGs(t, 2, 3)
+		var seeds map[int][]byte
+		_, _, err := runDKGs(dkgs, func(round, to int, msgs map[int][]byte) {
+			switch {
+			case round == 2 && to == 2:
+				seeds = msgs
+			case round == 3 && to == 1:
+				msg, err := dkgs[2].Round3(seeds)
+				if err != nil {
+					t.Fatal(err)
+				}
+				msgs[2] = msg
+			}
+		})
+		requireCulprits(t, err, 4, []int{1})
+	})
+}
@@ -0,0 +1,439 @@
+package sign
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math/bits"
+	"sort"
+	"traccoon-sign/primitives"
+	"traccoon-sign/utils"
+
+	"github.com/tuneinsight/lattigo/v5/ring"
+	"github.com/tuneinsight/lattigo/v5/utils/structs"
+)
+
+// Sizes of the fixed-width parts of an encoded signature: the challenge c
+// as the positions of its Kappa nonzero coefficients followed by their
+// signs, and Delta with the coefficients of the ν-ring packed on NuBits bits.
+func (p *Params) challengeSize() int { return p.Kappa + (p.Kappa+7)/8 }
+func (p *Params) deltaSize() int     { return p.DimK * (1 << LogN) * int(p.NuBits()) / 8 }
+
+var errMalformed = errors.New("malformed encoding")
+
+// bitWriter appends values to a byte slice, least significant bits first.
+type bitWriter struct {
+	buf  []byte
+	acc  uint64
+	nacc uint
+}
+
+// write writes the n low bits of v, with n <= 56.
+func (w *bitWriter) write(v uint64, n uint) {
+	w.acc |= (v & (1<<n - 1)) << w.nacc
+	w.nacc += n
+	for w.nacc >= 8 {
+		w.buf = append(w.buf, byte(w.acc))
+		w.acc >>= 8
+		w.nacc -= 8
+	}
+}
+
+// bytes flushes the last bits, padded with zeros, and returns the output.
+func (w *bitWriter) bytes() []byte {
+	if w.nacc > 0 {
+		w.buf = append(w.buf, byte(w.acc))
+		w.acc, w.nacc = 0, 0
+	}
+	return w.buf
+}
+
+// bitReader reads the values written by a bitWriter.
+type bitReader struct {
+	buf []byte
+	pos uint
+}
+
+// read reads n bits, with n <= 56.
+func (r *bitReader) read(n uint) (uint64, error) {
+	if r.pos+n > uint(len(r.buf))*8 {
+		return 0, errMalformed
+	}
+	var v uint64
+	for i := uint(0); i < n; {
+		byteIdx, bitIdx := (r.pos+i)/8, (r.pos+i)%8
+		take := min(8-bitIdx, n-i)
+		chunk := uint64(r.buf[byteIdx]>>bitIdx) & (1<<take - 1)
+		v |= chunk << i
+		i += take
+	}
+	r.pos += n
+	return v, nil
+}
+
+// finish checks that only the zero padding of the last byte is left, so that
+// every value has a single encoding.
+func (r *bitReader) finish() error {
+	if (r.pos+7)/8 != uint(len(r.buf)) {
+		return errMalformed
+	}
+	if r.pos%8 != 0 && r.buf[len(r.buf)-1]>>(r.pos%8) != 0 {
+		return errMalformed
+	}
+	return nil
+}
+
+// packVector writes the coefficients of v reduced modulo r, which must be
+// below 2^n, on n bits each.
+func packVector(w *bitWriter, r *ring.Ring, v structs.Vector[ring.Poly], n uint) {
+	q := r.Modulus().Uint64()
+	for _, poly := range v {
+		for _, coeff := range poly.Coeffs[0] {
+			w.write(coeff%q, n)
+		}
+	}
+}
+
+// unpackVector reads a vector of dim polynomials of r packed by packVector,
+// rejecting coefficients out of [0, modulus).
+func unpackVector(br *bitReader, r *ring.Ring, dim int, n uint) (structs.Vector[ring.Poly], error) {
+	v := utils.InitializeVector(r, dim)
+	q := r.Modulus().Uint64()
+	for _, poly := range v {
+		for j := range poly.Coeffs[0] {
+			coeff, err := br.read(n)
+			if err != nil {
+				return nil, err
+			}
+			if coeff >= q {
+				return nil, errMalformed
+			}
+			poly.Coeffs[0][j] = coeff
+		}
+	}
+	return v, nil
+}
+
+// riceParameter returns the number of low bits minimizing the size of the
+// Golomb-Rice encoding of the coefficients of v centered modulo q.
+func riceParameter(q uint64, v structs.Vector[ring.Poly]) uint {
+	best, bestSize := uint(0), uint64(0)
+	for k := uint(0); k < uint(bits.Len64(q-1)); k++ {
+		size := uint64(0)
+		for _, poly := range v {
+			for _, coeff := range poly.Coeffs[0] {
+				size += uint64(k) + 2 + centered(q, coeff)>>k
+			}
+		}
+		if k == 0 || size < bestSize {
+			best, bestSize = k, size
+		}
+	}
+	return best
+}
+
+// centered returns the absolute value of coeff centered modulo q, which may
+// not be reduced.
+func centered(q, coeff uint64) uint64 {
+	coeff %= q
+	if coeff > q/2 {
+		return q - coeff
+	}
+	return coeff
+}
+
+// encodeGaussian encodes a vector of short coefficients modulo the modulus of
+// r, such as Gaussian samples, with a Golomb-Rice code: the number k of low
+// bits on one byte, then for each coefficient its sign, the k low bits of its
+// absolute value, and the remaining high bits in unary.
+func encodeGaussian(r *ring.Ring, v structs.Vector[ring.Poly]) []byte {
+	q := r.Modulus().Uint64()
+	k := riceParameter(q, v)
+	w := &bitWriter{buf: []byte{byte(k)}}
+	for _, poly := range v {
+		for _, coeff := range poly.Coeffs[0] {
+			abs := centered(q, coeff)
+			sign := uint64(0)
+			if coeff%q > q/2 {
+				sign = 1
+			}
+			w.write(sign, 1)
+			w.write(abs, k)
+			for high := abs >> k; high > 0; high-- {
+				w.write(1, 1)
+			}
+			w.write(0, 1)
+		}
+	}
+	return w.bytes()
+}
+
+// decodeGaussian decodes a vector of dim polynomials of r encoded by
+// encodeGaussian. Negative zeros are rejected so that the encoding is unique.
+func decodeGaussian(r *ring.Ring, data []byte, dim int) (structs.Vector[ring.Poly], error) {
+	q := r.Modulus().Uint64()
+	if len(data) == 0 || int(data[0]) >= bits.Len64(q-1) {
+		return nil, errMalformed
+	}
+	k := uint(data[0])
+	br := &bitReader{buf: data[1:]}
+	v := utils.InitializeVector(r, dim)
+	for _, poly := range v {
+		for j := range poly.Coeffs[0] {
+			sign, err := br.read(1)
+			if err != nil {
+				return nil, err
+			}
+			abs, err := br.read(k)
+			if err != nil {
+				return nil, err
+			}
+			for {
+				bit, err := br.read(1)
+				if err != nil {
+					return nil, err
+				}
+				if bit == 0 {
+					break
+				}
+				abs += 1 << k
+				if abs > q/2 {
+					return nil, errMalformed
+				}
+			}
+			switch {
+			case sign == 1 && abs == 0:
+				return nil, errMalformed
+			case sign == 1:
+				poly.Coeffs[0][j] = q - abs
+			default:
+				poly.Coeffs[0][j] = abs
+			}
+		}
+	}
+	return v, br.finish()
+}
+
+// encodeChallenge encodes c, in the NTT domain, as the positions of its Kappa
+// nonzero coefficients followed by a bit per position set for -1.
+func (p *Params) encodeChallenge(r *ring.Ring, c ring.Poly) ([]byte, error) {
+	coeffs := r.NewPoly()
+	r.IMForm(c, coeffs)
+	r.INTT(coeffs, coeffs)
+
+	buf := make([]byte, p.challengeSize())
+	w := &bitWriter{}
+	n := 0
+	for i, coeff := range coeffs.Coeffs[0] {
+		if coeff %= p.Q; coeff == 0 {
+			continue
+		}
+		if n == p.Kappa || (coeff != 1 && coeff != p.Q-1) {
+			return nil, errors.New("challenge is not ternary of weight Kappa")
+		}
+		buf[n] = byte(i)
+		if coeff == p.Q-1 {
+			w.write(1, 1)
+		} else {
+			w.write(0, 1)
+		}
+		n++
+	}
+	if n != p.Kappa {
+		return nil, errors.New("challenge is not ternary of weight Kappa")
+	}
+	copy(buf[p.Kappa:], w.bytes())
+	return buf, nil
+}
+
+// decodeChallenge decodes a challenge encoded by encodeChallenge, back in the
+// NTT domain. The positions must be increasing.
+func (p *Params) decodeChallenge(r *ring.Ring, data []byte) (ring.Poly, error) {
+	if len(data) != p.challengeSize() {
+		return ring.Poly{}, errMalformed
+	}
+	c := r.NewPoly()
+	br := &bitReader{buf: data[p.Kappa:]}
+	for n := 0; n < p.Kappa; n++ {
+		if n > 0 && data[n] <= data[n-1] {
+			return ring.Poly{}, errMalformed
+		}
+		sign, _ := br.read(1)
+		c.Coeffs[0][data[n]] = 1
+		if sign == 1 {
+			c.Coeffs[0][data[n]] = p.Q - 1
+		}
+	}
+	if err := br.finish(); err != nil {
+		return ring.Poly{}, err
+	}
+	r.NTT(c, c)
+	r.MForm(c, c)
+	return c, nil
+}
+
+// Signature is a T-Raccoon signature as output by SignFinalize, with the
+// challenge c and the response z in the NTT domain.
+type Signature struct {
+	Params *Params
+	C      ring.Poly
+	Z      structs.Vector[ring.Poly]
+	Delta  structs.Vector[ring.Poly]
+}
+
+// MarshalBinary encodes the signature as the ID of its parameters, c, Delta
+// bit-packed in the ν-ring, and z with the Golomb-Rice code of its Gaussian
+// coefficients.
+func (sig *Signature) MarshalBinary() ([]byte, error) {
+	p := sig.Params
+	r, _, r_nu := p.newRings()
+	challenge, err := p.encodeChallenge(r, sig.C)
+	if err != nil {
+		return nil, err
+	}
+
+	w := &bitWriter{buf: append([]byte{p.ID}, challenge...)}
+	packVector(w, r_nu, sig.Delta, p.NuBits())
+	data := w.bytes()
+
+	z := utils.InitializeVector(r, p.DimEll)
+	utils.VectorAdd(r, sig.Z, z, z)
+	utils.ConvertVectorFromNTT(r, z)
+	return append(data, encodeGaussian(r, z)...), nil
+}
+
+// UnmarshalBinary decodes a signature encoded by MarshalBinary.
+func (sig *Signature) UnmarshalBinary(data []byte) error {
+	if len(data) == 0 {
+		return errMalformed
+	}
+	p, err := paramsByID(data[0])
+	if err != nil {
+		return err
+	}
+	data = data[1:]
+	r, _, r_nu := p.newRings()
+	challengeSize, deltaSize := p.challengeSize(), p.deltaSize()
+	if len(data) < challengeSize+deltaSize {
+		return fmt.Errorf("signature of %d bytes", len(data)+1)
+	}
+	c, err := p.decodeChallenge(r, data[:challengeSize])
+	if err != nil {
+		return err
+	}
+	br := &bitReader{buf: data[challengeSize : challengeSize+deltaSize]}
+	Delta, err := unpackVector(br, r_nu, p.DimK, p.NuBits())
+	if err != nil {
+		return err
+	}
+	z, err := decodeGaussian(r, data[challengeSize+deltaSize:], p.DimEll)
+	if err != nil {
+		return err
+	}
+	utils.ConvertVectorToNTT(r, z)
+	*sig = Signature{Params: p, C: c, Z: z, Delta: Delta}
+	return nil
+}
+
+// Verify verifies an encoded signature of msg with the context ctx under an
+// encoded public key.
+func Verify(pkBytes, msg, ctx, sigBytes []byte) bool {
+	if len(ctx) > MaxContextSize {
+		return false
+	}
+	pk := new(PublicKey)
+	if err := pk.UnmarshalBinary(pkBytes); err != nil {
+		return false
+	}
+	sig := new(Signature)
+	if err := sig.UnmarshalBinary(sigBytes); err != nil || sig.Params != pk.Params {
+		return false
+	}
+	return verify(pk, sig.Z, primitives.HashMessage(pkBytes, ctx, msg), sig.C, sig.Delta)
+}
+
+// packed returns the coefficients of v modulo r packed on n bits each.
+func packed(r *ring.Ring, v structs.Vector[ring.Poly], n uint) []byte {
+	w := &bitWriter{}
+	packVector(w, r, v, n)
+	return w.bytes()
+}
+
+// unpacked decodes a vector of dim polynomials of r packed by packed.
+func unpacked(r *ring.Ring, data []byte, dim int, n uint) (structs.Vector[ring.Poly], error) {
+	br := &bitReader{buf: data}
+	v, err := unpackVector(br, r, dim, n)
+	if err != nil {
+		return nil, err
+	}
+	return v, br.finish()
+}
+
+// encodeMap encodes a map of vectors, such as the shares of a PrivateKey, in
+// the order of its indices, each index and each encoded vector being
+// prefixed by its length.
+func encodeMap(m map[string]structs.Vector[ring.Poly], encode func(structs.Vector[ring.Poly]) []byte) []byte {
+	indices := make([]string, 0, len(m))
+	for idx := range m {
+		indices = append(indices, idx)
+	}
+	sort.Strings(indices)
+
+	buf := binary.BigEndian.AppendUint32(nil, uint32(len(m)))
+	for _, idx := range indices {
+		buf = binary.BigEndian.AppendUint16(buf, uint16(len(idx)))
+		buf = append(buf, idx...)
+		v := encode(m[idx])
+		buf = binary.BigEndian.AppendUint32(buf, uint32(len(v)))
+		buf = append(buf, v...)
+	}
+	return buf
+}
+
+// decodeMap decodes a map encoded by encodeMap, whose indices must be
+// increasing.
+func decodeMap(data []byte, decode func([]byte) (structs.Vector[ring.Poly], error)) (map[string]structs.Vector[ring.Poly], error) {
+	next := func(n int) ([]byte, error) {
+		if len(data) < n {
+			return nil, errMalformed
+		}
+		field := data[:n]
+		data = data[n:]
+		return field, nil
+	}
+
+	field, err := next(4)
+	if err != nil {
+		return nil, err
+	}
+	count := binary.BigEndian.Uint32(field)
+	m := make(map[string]structs.Vector[ring.Poly])
+	last := ""
+	for i := uint32(0); i < count; i++ {
+		if field, err = next(2); err != nil {
+			return nil, err
+		}
+		if field, err = next(int(binary.BigEndian.Uint16(field))); err != nil {
+			return nil, err
+		}
+		idx := string(field)
+		if i > 0 && idx <= last {
+			return nil, errMalformed
+		}
+		last = idx
+
+		if field, err = next(4); err != nil {
+			return nil, err
+		}
+		if field, err = next(int(binary.BigEndian.Uint32(field))); err != nil {
+			return nil, err
+		}
+		if m[idx], err = decode(field); err != nil {
+			return nil, err
+		}
+	}
+	if len(data) != 0 {
+		return nil, errors.New("trailing data")
+	}
+	return m, nil
+}
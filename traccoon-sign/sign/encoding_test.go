@@ -0,0 +1,280 @@
+package sign
+
+import (
+	"bytes"
+	"math/bits"
+	"testing"
+
+	"github.com/tuneinsight/lattigo/v5/ring"
+	"github.com/tuneinsight/lattigo/v5/utils/structs"
+)
+
+func TestEncodingSizes(t *testing.T) {
+	// Rounding drops Xi and Nu bits of the coefficients modulo Q, which are
+	// left with the bits of QXi and QNu
+	for _, p := range ParamSets {
+		for _, tc := range []struct {
+			name       string
+			q, dropped uint64
+		}{
+			{"QNu", p.QNu, uint64(p.Nu)},
+			{"QXi", p.QXi, uint64(p.Xi)},
+		} {
+			if want := uint64(1)<<(uint64(bits.Len64(p.Q))-tc.dropped) - 1; tc.q != want {
+				t.Errorf("%s: %s is %d instead of %d", p.Name, tc.name, tc.q, want)
+			}
+		}
+	}
+}
+
+// signedMessage returns an encoded public key and signature of the test
+// message by the parties T out of N.
+func signedMessage(t *testing.T, T []int, N int) ([]byte, []byte, *Signature) {
+	t.Helper()
+	pk, msgs2, msgs3, err := signRounds(t, T, N, func(_, _ map[int][]byte) {})
+	if err != nil {
+		t.Fatal(err)
+	}
+	mu := message(t, pk)
+	c, z, Delta, err := NewParty(T[0], pk).SignFinalize(pk, msgs2, msgs3, mu, T, N)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sig := &Signature{Params: pk.Params, C: c, Z: z, Delta: Delta}
+	sigBytes, err := sig.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	pkBytes, err := pk.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pkBytes, sigBytes, sig
+}
+
+func TestSignatureEncoding(t *testing.T) {
+	msg := []byte("Message")
+	pkBytes, sigBytes, sig := signedMessage(t, []int{0, 2, 3}, 4)
+	if !Verify(pkBytes, msg, nil, sigBytes) {
+		t.Fatal("invalid signature")
+	}
+	if Verify(pkBytes, []byte("Other message"), nil, sigBytes) {
+		t.Fatal("signature valid for another message")
+	}
+
+	// The coefficients of z take about 2 + log2(sqrt(3) * SigmaStar) bits
+	// instead of 8 bytes
+	p := DefaultParams
+	t.Logf("signature of %d bytes", len(sigBytes))
+	if max := 1 + p.challengeSize() + p.deltaSize() + 1 + p.DimEll*(1<<LogN)*36/8; len(sigBytes) > max {
+		t.Fatalf("signature of %d bytes, more than %d", len(sigBytes), max)
+	}
+
+	decoded := new(Signature)
+	if err := decoded.UnmarshalBinary(sigBytes); err != nil {
+		t.Fatal(err)
+	}
+	r, _, r_nu := p.newRings()
+	if decoded.Params != p || !r.Equal(decoded.C, sig.C) || !equalVectors(r, decoded.Z, sig.Z) || !equalVectors(r_nu, decoded.Delta, sig.Delta) {
+		t.Fatal("signature changed by encoding")
+	}
+
+	for _, tc := range []struct {
+		name   string
+		modify func(sig []byte) []byte
+	}{
+		{"truncated", func(sig []byte) []byte { return sig[:len(sig)-1] }},
+		{"trailing byte", func(sig []byte) []byte { return append(sig, 0) }},
+		{"unknown parameters", func(sig []byte) []byte {
+			sig[0] = 0
+			return sig
+		}},
+		{"other parameters", func(sig []byte) []byte {
+			sig[0] = TRaccoon128N64.ID
+			return sig
+		}},
+		{"unordered challenge", func(sig []byte) []byte {
+			sig[1], sig[2] = sig[2], sig[1]
+			return sig
+		}},
+		{"other challenge sign", func(sig []byte) []byte {
+			sig[1+p.Kappa] ^= 1
+			return sig
+		}},
+		{"other Delta", func(sig []byte) []byte {
+			sig[1+p.challengeSize()+100] ^= 1
+			return sig
+		}},
+		{"other z", func(sig []byte) []byte {
+			sig[len(sig)-100] ^= 4
+			return sig
+		}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if Verify(pkBytes, msg, nil, tc.modify(bytes.Clone(sigBytes))) {
+				t.Fatal("modified signature accepted")
+			}
+		})
+	}
+}
+
+func TestKeyEncoding(t *testing.T) {
+	pk, sks, err := NewThresholdKeys(3, 4, DefaultParams)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := pk.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded := new(PublicKey)
+	if err := decoded.UnmarshalBinary(data); err != nil {
+		t.Fatal(err)
+	}
+	for i := range pk.A {
+		if !equalVectors(pk.Ring, pk.A[i], decoded.A[i]) {
+			t.Fatal("A not rebuilt from its seed")
+		}
+	}
+	if !equalVectors(pk.RingXi, pk.Btilde, decoded.Btilde) || decoded.ShareCommitments != nil {
+		t.Fatal("public key changed by encoding")
+	}
+	if err := decoded.UnmarshalBinary(data[:len(data)-1]); err == nil {
+		t.Fatal("truncated public key accepted")
+	}
+
+	// The verification key does not grow with the number of parties
+	if want := 1 + KeySize + DefaultParams.DimK*(1<<LogN)*int(DefaultParams.XiBits())/8; len(data) != want {
+		t.Fatalf("verification key of %d bytes instead of %d", len(data), want)
+	}
+
+	data, err = pk.MarshalCommitments()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := decoded.UnmarshalCommitments(data); err != nil {
+		t.Fatal(err)
+	}
+	if len(decoded.ShareCommitments) != len(pk.ShareCommitments) {
+		t.Fatal("commitments changed by encoding")
+	}
+	for idx, t_i := range pk.ShareCommitments {
+		if !equalVectors(pk.Ring, t_i, decoded.ShareCommitments[idx]) {
+			t.Fatalf("commitment %q changed by encoding", idx)
+		}
+	}
+	if err := decoded.UnmarshalCommitments(data[:len(data)-1]); err == nil {
+		t.Fatal("truncated commitments accepted")
+	}
+	data[0] = TRaccoon128N64.ID
+	if err := decoded.UnmarshalCommitments(data); err == nil {
+		t.Fatal("commitments of other parameters accepted")
+	}
+
+	for _, sk := range sks {
+		data, err := sk.MarshalBinary()
+		if err != nil {
+			t.Fatal(err)
+		}
+		decoded := new(PrivateKey)
+		if err := decoded.UnmarshalBinary(data); err != nil {
+			t.Fatal(err)
+		}
+		if decoded.ID != sk.ID || len(decoded.Shares) != len(sk.Shares) {
+			t.Fatalf("private key of party %d changed by encoding", sk.ID)
+		}
+		for idx, s := range sk.Shares {
+			if !equalVectors(pk.Ring, s, decoded.Shares[idx]) {
+				t.Fatalf("share %q changed by encoding", idx)
+			}
+		}
+	}
+}
+
+func TestGaussianEncoding(t *testing.T) {
+	Q := DefaultParams.Q
+	r, _, _ := DefaultParams.newRings()
+	v := structs.Vector[ring.Poly]{r.NewPoly()}
+	v[0].Coeffs[0][0] = 5
+	v[0].Coeffs[0][1] = Q - 5
+	v[0].Coeffs[0][2] = Q // unreduced zero
+	data := encodeGaussian(r, v)
+	decoded, err := decodeGaussian(r, data, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := decoded[0].Coeffs[0][:3]; got[0] != 5 || got[1] != Q-5 || got[2] != 0 {
+		t.Fatalf("decoded %v", got)
+	}
+
+	// A zero coefficient with the sign bit set, the zero vector being
+	// encoded with no low bits
+	negativeZero := encodeGaussian(r, structs.Vector[ring.Poly]{r.NewPoly()})
+	if negativeZero[0] != 0 {
+		t.Fatalf("zero vector encoded with %d low bits", negativeZero[0])
+	}
+	negativeZero[1] |= 1
+	if _, err := decodeGaussian(r, negativeZero, 1); err == nil {
+		t.Fatal("negative zero accepted")
+	}
+}
+
+func equalVectors(r *ring.Ring, v1, v2 structs.Vector[ring.Poly]) bool {
+	if len(v1) != len(v2) {
+		return false
+	}
+	for i := range v1 {
+		if !r.Equal(v1[i], v2[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+func TestContext(t *testing.T) {
+	pk, sks, err := NewThresholdKeys(2, 2, DefaultParams)
+	if err != nil {
+		t.Fatal(err)
+	}
+	msg, ctx := []byte("Message"), []byte("application")
+	results := runSigning(t, pk, sks, []int{0, 1}, 2, msg, ctx)
+	sigBytes := results[CombinerID].Signature
+	pkBytes, err := pk.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !Verify(pkBytes, msg, ctx, sigBytes) {
+		t.Fatal("invalid signature")
+	}
+	if Verify(pkBytes, msg, nil, sigBytes) || Verify(pkBytes, msg, []byte("other application"), sigBytes) {
+		t.Fatal("signature valid with another context")
+	}
+
+	// Mu only hashes the verification key, which Verify is given
+	vk := new(PublicKey)
+	if err := vk.UnmarshalBinary(pkBytes); err != nil {
+		t.Fatal(err)
+	}
+	mu, err := Mu(pk, msg, ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if vkMu, err := Mu(vk, msg, ctx); err != nil || !bytes.Equal(vkMu, mu) {
+		t.Fatal("digest depends on the commitments to the key shares")
+	}
+
+	// The signature is bound to the public key
+	otherKey := bytes.Clone(pkBytes)
+	otherKey[len(otherKey)-1] ^= 1
+	if Verify(otherKey, msg, ctx, sigBytes) {
+		t.Fatal("signature valid under another public key")
+	}
+
+	long := make([]byte, MaxContextSize+1)
+	if _, err := Mu(pk, msg, long); err == nil {
+		t.Fatal("context longer than MaxContextSize accepted")
+	}
+	if Verify(pkBytes, msg, long, sigBytes) {
+		t.Fatal("context longer than MaxContextSize accepted")
+	}
+}
@@ -0,0 +1,205 @@
+package sign
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/tuneinsight/lattigo/v5/ring"
+	"github.com/tuneinsight/lattigo/v5/utils/structs"
+)
+
+// MarshalBinary encodes the verification key, all that Verify needs: the ID
+// of the parameters, the seed of A and Btilde bit-packed in the ξ-ring. A and
+// the rings are rebuilt when decoding. The commitments to the key shares,
+// which only the signers need, are encoded by MarshalCommitments.
+func (pk *PublicKey) MarshalBinary() ([]byte, error) {
+	if len(pk.Seed) != KeySize {
+		return nil, errors.New("public key without the seed of A")
+	}
+	p := pk.Params
+	data := append([]byte{p.ID}, pk.Seed...)
+	return append(data, packed(pk.RingXi, pk.Btilde, p.XiBits())...), nil
+}
+
+// UnmarshalBinary decodes a verification key encoded by MarshalBinary, into
+// a public key without commitments to the key shares.
+func (pk *PublicKey) UnmarshalBinary(data []byte) error {
+	if len(data) == 0 {
+		return errMalformed
+	}
+	p, err := paramsByID(data[0])
+	if err != nil {
+		return err
+	}
+	data = data[1:]
+	r, r_xi, r_nu := p.newRings()
+	btildeSize := p.DimK * (1 << LogN) * int(p.XiBits()) / 8
+	if len(data) != KeySize+btildeSize {
+		return fmt.Errorf("public key of %d bytes", len(data)+1)
+	}
+	seed := append([]byte(nil), data[:KeySize]...)
+	Btilde, err := unpacked(r_xi, data[KeySize:], p.DimK, p.XiBits())
+	if err != nil {
+		return err
+	}
+
+	*pk = PublicKey{
+		Params: p,
+		Ring:   r,
+		RingXi: r_xi,
+		RingNu: r_nu,
+		Seed:   seed,
+		A:      p.expandA(r, seed),
+		Btilde: Btilde,
+	}
+	return nil
+}
+
+// MarshalCommitments encodes the commitments to the key shares, with which
+// the signers check the responses of each other: the ID of the parameters
+// and the commitments bit-packed modulo Q.
+func (pk *PublicKey) MarshalCommitments() ([]byte, error) {
+	p := pk.Params
+	return append([]byte{p.ID}, encodeMap(pk.ShareCommitments, func(t structs.Vector[ring.Poly]) []byte {
+		return packed(pk.Ring, t, p.QBits())
+	})...), nil
+}
+
+// UnmarshalCommitments decodes the commitments encoded by MarshalCommitments
+// into pk, which must have the same parameters.
+func (pk *PublicKey) UnmarshalCommitments(data []byte) error {
+	if len(data) == 0 {
+		return errMalformed
+	}
+	p, err := paramsByID(data[0])
+	if err != nil {
+		return err
+	}
+	if p != pk.Params {
+		return fmt.Errorf("commitments for %s and public key for %s", p.Name, pk.Params.Name)
+	}
+	commitments, err := decodeMap(data[1:], func(buf []byte) (structs.Vector[ring.Poly], error) {
+		return unpacked(pk.Ring, buf, p.DimK, p.QBits())
+	})
+	if err != nil {
+		return err
+	}
+	pk.ShareCommitments = commitments
+	return nil
+}
+
+// MarshalBinary encodes the ID of the parameters, the ID of the party and its
+// shares, whose short coefficients are entropy coded.
+func (sk *PrivateKey) MarshalBinary() ([]byte, error) {
+	r, _, _ := sk.Params.newRings()
+	data := binary.BigEndian.AppendUint32([]byte{sk.Params.ID}, uint32(sk.ID))
+	return append(data, encodeMap(sk.Shares, func(s structs.Vector[ring.Poly]) []byte {
+		return encodeGaussian(r, s)
+	})...), nil
+}
+
+// UnmarshalBinary decodes a private key encoded by MarshalBinary.
+func (sk *PrivateKey) UnmarshalBinary(data []byte) error {
+	if len(data) < 5 {
+		return errMalformed
+	}
+	p, err := paramsByID(data[0])
+	if err != nil {
+		return err
+	}
+	r, _, _ := p.newRings()
+	shares, err := decodeMap(data[5:], func(buf []byte) (structs.Vector[ring.Poly], error) {
+		return decodeGaussian(r, buf, p.DimEll)
+	})
+	if err != nil {
+		return err
+	}
+	*sk = PrivateKey{Params: p, ID: int(binary.BigEndian.Uint32(data[1:])), Shares: shares}
+	return nil
+}
+
+const (
+	publicKeyFile   = "public.key"
+	commitmentsFile = "commitments.key"
+)
+
+func privateKeyFile(partyID int) string {
+	return fmt.Sprintf("party-%d.key", partyID)
+}
+
+// WriteKeys writes the verification key, the commitments to the key shares
+// and the private key of every party into dir, the private keys being
+// readable by their owner only. Each party must then be given the public
+// files and its own private key file, and verifiers only the verification
+// key.
+func WriteKeys(dir string, pk *PublicKey, sks []PrivateKey) error {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return err
+	}
+	data, err := pk.MarshalBinary()
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(filepath.Join(dir, publicKeyFile), data, 0o644); err != nil {
+		return err
+	}
+	if data, err = pk.MarshalCommitments(); err != nil {
+		return err
+	}
+	if err := os.WriteFile(filepath.Join(dir, commitmentsFile), data, 0o644); err != nil {
+		return err
+	}
+	for i := range sks {
+		data, err := sks[i].MarshalBinary()
+		if err != nil {
+			return err
+		}
+		if err := os.WriteFile(filepath.Join(dir, privateKeyFile(sks[i].ID)), data, 0o600); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ReadKeys reads the public key, with the commitments to the key shares, and
+// the private key of party partyID from dir, as written by WriteKeys.
+func ReadKeys(dir string, partyID int) (*PublicKey, *PrivateKey, error) {
+	data, err := os.ReadFile(filepath.Join(dir, publicKeyFile))
+	if err != nil {
+		return nil, nil, err
+	}
+	pk := new(PublicKey)
+	if err := pk.UnmarshalBinary(data); err != nil {
+		return nil, nil, fmt.Errorf("%s: %w", publicKeyFile, err)
+	}
+	if data, err = os.ReadFile(filepath.Join(dir, commitmentsFile)); err != nil {
+		return nil, nil, err
+	}
+	if err := pk.UnmarshalCommitments(data); err != nil {
+		return nil, nil, fmt.Errorf("%s: %w", commitmentsFile, err)
+	}
+
+	data, err = os.ReadFile(filepath.Join(dir, privateKeyFile(partyID)))
+	if err != nil {
+		return nil, nil, err
+	}
+	sk := new(PrivateKey)
+	if err := sk.UnmarshalBinary(data); err != nil {
+		return nil, nil, fmt.Errorf("%s: %w", privateKeyFile(partyID), err)
+	}
+	if sk.ID != partyID {
+		return nil, nil, errors.New("private key of another party")
+	}
+	if sk.Params != pk.Params {
+		return nil, nil, fmt.Errorf("private key for %s and public key for %s", sk.Params.Name, pk.Params.Name)
+	}
+	for idx := range sk.Shares {
+		if _, ok := pk.ShareCommitments[idx]; !ok {
+			return nil, nil, fmt.Errorf("private key share %q not in the commitments", idx)
+		}
+	}
+	return pk, sk, nil
+}
@@ -0,0 +1,53 @@
+package sign
+
+import (
+	"testing"
+)
+
+func TestReadKeys(t *testing.T) {
+	dir := t.TempDir()
+	pk, sks, err := NewThresholdKeys(2, 3, DefaultParams)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := WriteKeys(dir, pk, sks); err != nil {
+		t.Fatal(err)
+	}
+
+	// Parties 1 and 2 sign with the keys read back
+	T := []int{1, 2}
+	parties := make(map[int]*Party)
+	keys := make(map[int]*PrivateKey)
+	msgs1 := make(map[int][]byte)
+	strd1 := make(map[int]StRound1)
+	for _, ID := range T {
+		if pk, keys[ID], err = ReadKeys(dir, ID); err != nil {
+			t.Fatal(err)
+		}
+		parties[ID] = NewParty(ID, pk)
+		msgs1[ID], strd1[ID] = parties[ID].SignRound1(pk)
+	}
+	mu := message(t, pk)
+	msgs2 := make(map[int][]byte)
+	strd2 := make(map[int]StRound2)
+	for _, ID := range T {
+		msgs2[ID], strd2[ID] = parties[ID].SignRound2(pk, msgs1, strd1[ID], mu, T)
+	}
+	msgs3 := make(map[int][]byte)
+	for _, ID := range T {
+		if msgs3[ID], err = parties[ID].SignRound3(pk, keys[ID], msgs2, strd2[ID], mu, T, 3); err != nil {
+			t.Fatal(err)
+		}
+	}
+	c, z, Delta, err := parties[1].SignFinalize(pk, msgs2, msgs3, mu, T, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !verify(pk, z, mu, c, Delta) {
+		t.Fatal("invalid signature")
+	}
+
+	if _, _, err := ReadKeys(dir, 3); err == nil {
+		t.Fatal("read the keys of a party out of the committee")
+	}
+}
@@ -10,6 +10,7 @@ import (
 
 var K int
 var Threshold int
+var Parameters = DefaultParams
 
 // Main function orchestrates the threshold signature protocol
 func LocalRun(x int) {
@@ -26,7 +27,7 @@ func LocalRun(x int) {
 
 		log.Println("Gen")
 		start := time.Now()
-		pk, sks, err := NewThresholdKeys(Threshold, K)
+		pk, sks, err := NewThresholdKeys(Threshold, K, Parameters)
 		if err != nil {
 			panic(err)
 		}
@@ -50,7 +51,10 @@ func LocalRun(x int) {
 		signRound3Durations := make(map[int]time.Duration)
 
 		// SIGNATURE ROUND 1
-		mu := "Message"
+		mu, err := Mu(pk, []byte("Message"), nil)
+		if err != nil {
+			panic(err)
+		}
 		msgs1 := make(map[int][]byte)
 		strd1 := make(map[int]StRound1)
 		for _, partyID := range T {
@@ -79,11 +83,10 @@ func LocalRun(x int) {
 		for _, partyID := range T {
 			log.Println("Sign round 3 party", partyID)
 			start = time.Now()
-			var ok bool
-			ok, msgs3[partyID] = parties[partyID].SignRound3(pk, &sks[partyID], msgs2, strd2[partyID], mu, T, K)
+			msgs3[partyID], err = parties[partyID].SignRound3(pk, &sks[partyID], msgs2, strd2[partyID], mu, T, K)
 			fmt.Printf("[DEBUG] Size of msgs3[%d]: %d bytes\n", partyID, len(msgs3[partyID]))
-			if !ok {
-				panic("Hash mismatch")
+			if err != nil {
+				panic(err)
 			}
 			signRound3Durations[partyID] = time.Since(start)
 		}
@@ -92,14 +95,20 @@ func LocalRun(x int) {
 		log.Println("finalizing...")
 		finalParty := parties[0]
 		start = time.Now()
-		c, sig, Delta := finalParty.SignFinalize(pk, msgs2, msgs3, mu)
+		c, sig, Delta, err := finalParty.SignFinalize(pk, msgs2, msgs3, mu, T, K)
+		if err != nil {
+			panic(err)
+		}
 		finalizeDuration = time.Since(start)
 
 		// Verify the signature
 		start = time.Now()
-		valid := Verify(pk, sig, mu, c, Delta)
+		valid := verify(pk, sig, mu, c, Delta)
 		verifyDuration = time.Since(start)
 		fmt.Printf("Signature Verification Result: %v\n", valid)
+		if encoded, err := (&Signature{Params: pk.Params, C: c, Z: sig, Delta: Delta}).MarshalBinary(); err == nil {
+			fmt.Printf("Signature size: %d bytes\n", len(encoded))
+		}
 
 		// Accumulate durations
 		totalGenDuration += genDuration
@@ -0,0 +1,224 @@
+package sign
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+	"traccoon-sign/networking"
+)
+
+// RunResult is the outcome of one signing by a party over a Communicator.
+// The encoded signature is only set for the combiner.
+type RunResult struct {
+	Signature []byte
+	Valid     bool
+
+	// Combiner is the party which received the responses and finalized the
+	// signature
+	Combiner int
+
+	// Time spent computing each round, and exchanging its messages
+	Round1, Round2, Round3, Finalize, Verify time.Duration
+	Exchange1, Exchange2, Exchange3          time.Duration
+
+	// BytesSent counts the bytes sent by the party over the three rounds
+	BytesSent int
+}
+
+// Combiner returns the party finalizing the signatures of the signers T:
+// CombinerID if it signs, or else the first signer.
+func Combiner(T []int) int {
+	for _, i := range T {
+		if i == CombinerID {
+			return i
+		}
+	}
+	return T[0]
+}
+
+// RunParty runs the signing of msg with the context ctx, which may be empty,
+// by the signers T for party, going through comm for every message. Each
+// signer broadcasts its messages of the first two rounds to the other
+// signers, and sends its response to the combiner.
+func RunParty(comm networking.Communicator, party *Party, pk *PublicKey, sk *PrivateKey, msg, ctx []byte, T []int, K int) (*RunResult, error) {
+	signs := false
+	for _, i := range T {
+		signs = signs || i == party.ID
+	}
+	if !signs {
+		return nil, fmt.Errorf("party %d is not a signer", party.ID)
+	}
+	res := &RunResult{Combiner: Combiner(T)}
+	mu, err := Mu(pk, msg, ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// SIGNATURE ROUND 1
+	start := time.Now()
+	msg1, strd1 := party.SignRound1(pk)
+	res.Round1 = time.Since(start)
+
+	start = time.Now()
+	msgs1, err := exchange(comm, party.ID, T, msg1, res)
+	if err != nil {
+		return nil, fmt.Errorf("round 1: %w", err)
+	}
+	res.Exchange1 = time.Since(start)
+
+	// SIGNATURE ROUND 2
+	start = time.Now()
+	msg2, strd2 := party.SignRound2(pk, msgs1, strd1, mu, T)
+	res.Round2 = time.Since(start)
+
+	start = time.Now()
+	msgs2, err := exchange(comm, party.ID, T, msg2, res)
+	if err != nil {
+		return nil, fmt.Errorf("round 2: %w", err)
+	}
+	res.Exchange2 = time.Since(start)
+
+	// SIGNATURE ROUND 3
+	start = time.Now()
+	msg3, err := party.SignRound3(pk, sk, msgs2, strd2, mu, T, K)
+	if err != nil {
+		return nil, err
+	}
+	res.Round3 = time.Since(start)
+
+	start = time.Now()
+	if party.ID != res.Combiner {
+		n, err := comm.Send(res.Combiner, msg3)
+		if err != nil {
+			return nil, fmt.Errorf("round 3: %w", err)
+		}
+		res.BytesSent += n
+		res.Exchange3 = time.Since(start)
+		return res, nil
+	}
+	msgs3 := map[int][]byte{party.ID: msg3}
+	for _, i := range T {
+		if i != party.ID {
+			if msgs3[i], _, err = comm.Recv(i); err != nil {
+				return nil, fmt.Errorf("round 3: party %d: %w", i, err)
+			}
+		}
+	}
+	res.Exchange3 = time.Since(start)
+
+	// SIGNATURE FINALIZE
+	start = time.Now()
+	c, z, Delta, err := party.SignFinalize(pk, msgs2, msgs3, mu, T, K)
+	if err != nil {
+		return nil, err
+	}
+	res.Finalize = time.Since(start)
+
+	start = time.Now()
+	res.Valid = verify(pk, z, mu, c, Delta)
+	res.Verify = time.Since(start)
+	res.Signature, err = (&Signature{Params: pk.Params, C: c, Z: z, Delta: Delta}).MarshalBinary()
+	return res, err
+}
+
+// RunDKG runs the distributed key generation d of a party with every other
+// party, going through comm for every message, and returns its keys. The
+// commitment to the seed of the party is sent along with the shares of
+// round 1.
+func RunDKG(comm networking.Communicator, d *DKG) (*PublicKey, *PrivateKey, error) {
+	P := d.parties()
+	res := new(RunResult)
+
+	commit, shares := d.Round1()
+	out := make(map[int][]byte)
+	for _, j := range P {
+		out[j] = append(append([]byte{}, commit...), shares[j]...)
+	}
+	msgs1, err := exchangeEach(comm, d.ID, P, out, res)
+	if err != nil {
+		return nil, nil, fmt.Errorf("round 1: %w", err)
+	}
+	commits := make(map[int][]byte)
+	received := make(map[int][]byte)
+	for j, msg := range msgs1 {
+		if len(msg) >= KeySize {
+			commits[j], received[j] = msg[:KeySize], msg[KeySize:]
+		}
+	}
+
+	seed, err := d.Round2(commits, received)
+	if err != nil {
+		return nil, nil, err
+	}
+	seeds, err := exchange(comm, d.ID, P, seed, res)
+	if err != nil {
+		return nil, nil, fmt.Errorf("round 2: %w", err)
+	}
+
+	msg3, err := d.Round3(seeds)
+	if err != nil {
+		return nil, nil, err
+	}
+	msgs3, err := exchange(comm, d.ID, P, msg3, res)
+	if err != nil {
+		return nil, nil, fmt.Errorf("round 3: %w", err)
+	}
+
+	digest, err := d.Round4(msgs3)
+	if err != nil {
+		return nil, nil, err
+	}
+	digests, err := exchange(comm, d.ID, P, digest, res)
+	if err != nil {
+		return nil, nil, fmt.Errorf("round 4: %w", err)
+	}
+	return d.Finalize(digests)
+}
+
+// exchange sends msg to every other signer while receiving theirs, and
+// returns the messages of every signer, msg included.
+func exchange(comm networking.Communicator, id int, T []int, msg []byte, res *RunResult) (map[int][]byte, error) {
+	out := make(map[int][]byte)
+	for _, i := range T {
+		out[i] = msg
+	}
+	return exchangeEach(comm, id, T, out, res)
+}
+
+// exchangeEach sends out[i] to every other party i of T while receiving
+// theirs, and returns the messages of every party, out[id] included.
+func exchangeEach(comm networking.Communicator, id int, T []int, out map[int][]byte, res *RunResult) (map[int][]byte, error) {
+	msgs := map[int][]byte{id: out[id]}
+	var mu sync.Mutex
+	var errs []error
+	var wg sync.WaitGroup
+	for _, i := range T {
+		if i == id {
+			continue
+		}
+		wg.Add(2)
+		go func(i int) {
+			defer wg.Done()
+			n, err := comm.Send(i, out[i])
+			mu.Lock()
+			defer mu.Unlock()
+			res.BytesSent += n
+			if err != nil {
+				errs = append(errs, fmt.Errorf("party %d: %w", i, err))
+			}
+		}(i)
+		go func(i int) {
+			defer wg.Done()
+			received, _, err := comm.Recv(i)
+			mu.Lock()
+			defer mu.Unlock()
+			msgs[i] = received
+			if err != nil {
+				errs = append(errs, fmt.Errorf("party %d: %w", i, err))
+			}
+		}(i)
+	}
+	wg.Wait()
+	return msgs, errors.Join(errs...)
+}
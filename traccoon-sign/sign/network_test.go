@@ -0,0 +1,123 @@
+package sign
+
+import (
+	"bytes"
+	"sync"
+	"testing"
+	"traccoon-sign/networking"
+)
+
+// runSigning runs RunParty for every signer of T over in-memory channels.
+func runSigning(t *testing.T, pk *PublicKey, sks []PrivateKey, T []int, N int, msg, ctx []byte) map[int]*RunResult {
+	t.Helper()
+	comms := networking.NewMemNetwork(N)
+	results := make(map[int]*RunResult)
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for _, ID := range T {
+		wg.Add(1)
+		go func(ID int) {
+			defer wg.Done()
+			res, err := RunParty(comms[ID], NewParty(ID, pk), pk, &sks[ID], msg, ctx, T, N)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			mu.Lock()
+			results[ID] = res
+			mu.Unlock()
+		}(ID)
+	}
+	wg.Wait()
+	if t.Failed() {
+		t.FailNow()
+	}
+	return results
+}
+
+func TestRunParty(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		T    []int
+		N    int
+	}{
+		{"all parties", []int{0, 1, 2}, 3},
+		{"without the combiner", []int{0, 2, 3}, 4},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			pk, sks, err := NewThresholdKeys(len(tc.T), tc.N, DefaultParams)
+			if err != nil {
+				t.Fatal(err)
+			}
+			results := runSigning(t, pk, sks, tc.T, tc.N, []byte("Message"), nil)
+
+			combiner := Combiner(tc.T)
+			for ID, res := range results {
+				if res.Combiner != combiner {
+					t.Fatalf("party %d combined at %d instead of %d", ID, res.Combiner, combiner)
+				}
+			}
+			if !results[combiner].Valid {
+				t.Fatal("invalid signature")
+			}
+		})
+	}
+}
+
+func TestRunPartyClosed(t *testing.T) {
+	pk, sks, err := NewThresholdKeys(2, 2, DefaultParams)
+	if err != nil {
+		t.Fatal(err)
+	}
+	comms := networking.NewMemNetwork(2)
+	comms[0].Close()
+	if _, err := RunParty(comms[0], NewParty(0, pk), pk, &sks[0], []byte("Message"), nil, []int{0, 1}, 2); err == nil {
+		t.Fatal("signed without the other party")
+	}
+	if _, err := RunParty(comms[1], NewParty(1, pk), pk, &sks[1], []byte("Message"), nil, []int{0}, 2); err == nil {
+		t.Fatal("signed as a party outside of the signers")
+	}
+}
+
+func TestRunDKG(t *testing.T) {
+	T, N := 2, 3
+	comms := networking.NewMemNetwork(N)
+	pks := make([]*PublicKey, N)
+	sks := make([]PrivateKey, N)
+	var wg sync.WaitGroup
+	for i := 0; i < N; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			d, err := NewDKG(i, T, N, DefaultParams)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			pk, sk, err := RunDKG(comms[i], d)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			pks[i], sks[i] = pk, *sk
+		}(i)
+	}
+	wg.Wait()
+	if t.Failed() {
+		t.FailNow()
+	}
+
+	want, err := pks[0].MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, pk := range pks[1:] {
+		if got, err := pk.MarshalBinary(); err != nil || !bytes.Equal(got, want) {
+			t.Fatalf("party %d holds another public key", i+1)
+		}
+	}
+	results := runSigning(t, pks[0], sks, []int{0, 2}, N, []byte("Message"), nil)
+	if !results[Combiner([]int{0, 2})].Valid {
+		t.Fatal("invalid signature")
+	}
+}
@@ -2,9 +2,13 @@ package sign
 
 import (
 	"bytes"
+	"crypto/rand"
 	"errors"
+	"fmt"
+	"io"
 	"log"
 	"math/big"
+	"sort"
 	"traccoon-sign/primitives"
 	"traccoon-sign/utils"
 
@@ -14,14 +18,25 @@ import (
 )
 
 type PublicKey struct {
+	Params *Params
+
 	Ring   *ring.Ring
 	RingXi *ring.Ring
 	RingNu *ring.Ring
+
+	// Seed is the key of the PRNG sampling A, which is encoded instead of A
+	Seed   []byte
 	A      structs.Matrix[ring.Poly]
 	Btilde structs.Vector[ring.Poly]
+
+	// ShareCommitments holds t = A*s_i + e_i for every key share s_i, indexed
+	// as in PrivateKey.Shares, so that the responses of each party can be
+	// checked on their own
+	ShareCommitments map[string]structs.Vector[ring.Poly]
 }
 
 type PrivateKey struct {
+	Params *Params
 	ID     int
 	Shares map[string]structs.Vector[ring.Poly]
 }
@@ -29,6 +44,10 @@ type PrivateKey struct {
 // Party struct holds all state and methods for a party in the protocol
 type Party struct {
 	ID int
+
+	// Rand is the source of the randomness of SignRound1, crypto/rand if
+	// nil. Setting it to a deterministic source reproduces a signing run.
+	Rand io.Reader
 }
 
 type StRound1 struct {
@@ -41,6 +60,49 @@ type StRound2 struct {
 	Rstar  structs.Vector[ring.Poly]
 }
 
+// AbortError identifies the parties whose messages failed verification in
+// a round of signing, so that they can be excluded from the next attempts.
+type AbortError struct {
+	Round   int
+	Parties []int
+}
+
+func (e *AbortError) Error() string {
+	return fmt.Sprintf("misbehaving parties %v in round %d", e.Parties, e.Round)
+}
+
+func abort(round int, parties []int) error {
+	if len(parties) == 0 {
+		return nil
+	}
+	sort.Ints(parties)
+	return &AbortError{Round: round, Parties: parties}
+}
+
+// ErrInvalidSignature is returned by SignFinalize when the responses pass
+// the checks of every party but their sum does not verify, as when each of
+// them is pushed to the bounds of the checks.
+var ErrInvalidSignature = errors.New("combined signature does not verify")
+
+// MaxContextSize bounds the size of the context string of a signature.
+const MaxContextSize = 255
+
+// Mu returns the digest signed for msg, H(tag || vk || ctx || msg), which
+// binds the signature to the verification key vk encoded by MarshalBinary,
+// and to the application identified by ctx. The commitments to the key
+// shares are left out, so that a verifier computes Mu from vk alone. The
+// context may be empty, but not longer than MaxContextSize.
+func Mu(pk *PublicKey, msg, ctx []byte) ([]byte, error) {
+	if len(ctx) > MaxContextSize {
+		return nil, fmt.Errorf("context of %d bytes", len(ctx))
+	}
+	vk, err := pk.MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+	return primitives.HashMessage(vk, ctx, msg), nil
+}
+
 // NewParty initializes a new Party instance
 func NewParty(id int, pk *PublicKey) *Party {
 	return &Party{
@@ -48,31 +110,56 @@ func NewParty(id int, pk *PublicKey) *Party {
 	}
 }
 
-func NewThresholdKeys(T, N int) (*PublicKey, []PrivateKey, error) {
+// SeedSize is the size of the seed of NewThresholdKeysFromSeed.
+const SeedSize = 32
+
+// Purposes of the keys derived from the seed of the key generation
+const (
+	matrixSeed = "matrix"
+	secretSeed = "secret"
+)
+
+// NewThresholdKeys deals the keys of N parties, T of which can sign, with the
+// parameters params from a random seed.
+func NewThresholdKeys(T, N int, params *Params) (*PublicKey, []PrivateKey, error) {
+	var seed [SeedSize]byte
+	if _, err := rand.Read(seed[:]); err != nil {
+		return nil, nil, err
+	}
+	return NewThresholdKeysFromSeed(&seed, T, N, params)
+}
+
+// NewThresholdKeysFromSeed deterministically deals the keys of N parties, T
+// of which can sign, from seed: the seed of A and the key of the sampler of
+// the secret, its shares and the errors are derived from it.
+func NewThresholdKeysFromSeed(seed *[SeedSize]byte, T, N int, params *Params) (*PublicKey, []PrivateKey, error) {
 	if T == 0 || T > N {
 		return nil, nil, errors.New("Invalid threshold parameters")
 	}
-	r, _ := ring.NewRing(1<<LogN, []uint64{Q})
-	r_xi, _ := ring.NewRing(1<<LogN, []uint64{QXi})
-	r_nu, _ := ring.NewRing(1<<LogN, []uint64{QNu})
-
-	prng, _ := sampling.NewPRNG()
-	uniformSampler := ring.NewUniformSampler(prng, r)
+	if N > params.MaxParties {
+		return nil, nil, fmt.Errorf("%d parties, more than the %d of %s", N, params.MaxParties, params.Name)
+	}
+	r, r_xi, r_nu := params.newRings()
 
-	A := utils.SamplePolyMatrix(r, DimK, DimEll, uniformSampler, true, true)
+	seedA := primitives.DeriveSeed(seed[:], matrixSeed)
+	A := params.expandA(r, seedA)
 
 	pk := PublicKey{
+		Params: params,
 		Ring:   r,
 		RingXi: r_xi,
 		RingNu: r_nu,
+		Seed:   seedA,
 		A:      A,
 	}
 
-	gaussianParams := ring.DiscreteGaussian{Sigma: SigmaE, Bound: BoundE}
+	prng, _ := sampling.NewKeyedPRNG(primitives.DeriveSeed(seed[:], secretSeed))
+
+	gaussianParams := ring.DiscreteGaussian{Sigma: params.SigmaE, Bound: params.BoundE}
 	gaussianSampler := ring.NewGaussianSampler(prng, r, gaussianParams, false)
 
 	// Sample a secret
-	s := utils.SamplePolyVector(r, DimEll, gaussianSampler, false, false)
+	s := utils.SamplePolyVector(r, params.DimEll, gaussianSampler, false, false)
 
 	// Generate a Vandermonde sharing of s
 	// The parties are a vector of integers [0, 1, ... , N-1]
@@ -81,25 +168,44 @@ func NewThresholdKeys(T, N int) (*PublicKey, []PrivateKey, error) {
 		P[i] = i
 	}
 
-	s_copy := utils.InitializeVector(r, DimEll)
+	s_copy := utils.InitializeVector(r, params.DimEll)
 	utils.VectorAdd(r, s, s_copy, s_copy)
 	shares := Share(r, gaussianSampler, s_copy, P, T, "")
 
+	// Commit to every share as t_i = A*s_i + e_i, in the order of the
+	// indices so that the errors only depend on the seed
+	unique := make(map[string]structs.Vector[ring.Poly])
+	for _, userShares := range shares {
+		for idx, s_i := range userShares {
+			unique[idx] = s_i
+		}
+	}
+	indices := make([]string, 0, len(unique))
+	for idx := range unique {
+		indices = append(indices, idx)
+	}
+	sort.Strings(indices)
+	pk.ShareCommitments = make(map[string]structs.Vector[ring.Poly])
+	for _, idx := range indices {
+		pk.ShareCommitments[idx] = params.commitShare(r, A, gaussianSampler, unique[idx])
+	}
+
 	// Sample e and compute the public key b = A*s + e
 	utils.ConvertVectorToNTT(r, s)
 
-	e := utils.SamplePolyVector(r, DimK, gaussianSampler, true, true)
-	b := utils.InitializeVector(r, DimK)
+	e := utils.SamplePolyVector(r, params.DimK, gaussianSampler, true, true)
+	b := utils.InitializeVector(r, params.DimK)
 	utils.MatrixVectorMul(r, A, s, b)
 	utils.VectorAdd(r, b, e, b)
 
 	// Round b
 	utils.ConvertVectorFromNTT(r, b)
-	pk.Btilde = utils.RoundVector(r, r_xi, b, Xi)
+	pk.Btilde = utils.RoundVector(r, r_xi, b, params.Xi)
 
 	sks := make([]PrivateKey, N)
 	for partyID := 0; partyID < N; partyID++ {
 		sks[partyID] = PrivateKey{
+			Params: params,
 			ID:     partyID,
 			Shares: shares[partyID],
 		}
@@ -108,18 +214,63 @@ func NewThresholdKeys(T, N int) (*PublicKey, []PrivateKey, error) {
 	return &pk, sks, nil
 }
 
+// newRings returns the rings of the scheme: modulo Q, and the rings of the
+// rounded public key and commitments
+func (p *Params) newRings() (*ring.Ring, *ring.Ring, *ring.Ring) {
+	r, _ := ring.NewRing(1<<LogN, []uint64{p.Q})
+	r_xi, _ := ring.NewRing(1<<LogN, []uint64{p.QXi})
+	r_nu, _ := ring.NewRing(1<<LogN, []uint64{p.QNu})
+	return r, r_xi, r_nu
+}
+
+// expandA samples the public matrix A, in the NTT domain, from seed
+func (p *Params) expandA(r *ring.Ring, seed []byte) structs.Matrix[ring.Poly] {
+	prng, _ := sampling.NewKeyedPRNG(seed)
+	return utils.SamplePolyMatrix(r, p.DimK, p.DimEll, ring.NewUniformSampler(prng, r), true, true)
+}
+
+// mulA returns A*s for s in the standard domain, in the standard domain
+func mulA(r *ring.Ring, A structs.Matrix[ring.Poly], s structs.Vector[ring.Poly]) structs.Vector[ring.Poly] {
+	s_copy := utils.InitializeVector(r, len(s))
+	utils.VectorAdd(r, s, s_copy, s_copy)
+	utils.ConvertVectorToNTT(r, s_copy)
+
+	As := utils.InitializeVector(r, len(A))
+	utils.MatrixVectorMul(r, A, s_copy, As)
+	utils.ConvertVectorFromNTT(r, As)
+	return As
+}
+
+// commitShare returns t = A*s + e for a share s in the standard domain, e
+// being sampled with sampler
+func (p *Params) commitShare(r *ring.Ring, A structs.Matrix[ring.Poly], sampler ring.Sampler, s structs.Vector[ring.Poly]) structs.Vector[ring.Poly] {
+	t := mulA(r, A, s)
+	e := utils.SamplePolyVector(r, p.DimK, sampler, false, false)
+	utils.VectorAdd(r, t, e, t)
+	return t
+}
+
 // SignRound1 performs the first round of signing
 func (party *Party) SignRound1(pk *PublicKey) ([]byte, StRound1) {
 	r := pk.Ring
+	params := pk.Params
 
-	// Initialize r_star and e_star
-	prng, _ := sampling.NewPRNG()
-	gaussianParams := ring.DiscreteGaussian{Sigma: SigmaStar, Bound: BoundStar}
+	// Initialize r_star and e_star from a fresh key of the party's source
+	source := party.Rand
+	if source == nil {
+		source = rand.Reader
+	}
+	key := make([]byte, KeySize)
+	if _, err := io.ReadFull(source, key); err != nil {
+		log.Fatalf("Error reading the signing randomness: %v\n", err)
+	}
+	prng, _ := sampling.NewKeyedPRNG(key)
+	gaussianParams := ring.DiscreteGaussian{Sigma: params.SigmaStar, Bound: params.BoundStar}
 	gaussianSampler := ring.NewGaussianSampler(prng, r, gaussianParams, false)
-	r_star := utils.SamplePolyVector(r, DimEll, gaussianSampler, true, true)
-	e_star := utils.SamplePolyVector(r, DimK, gaussianSampler, true, true)
+	r_star := utils.SamplePolyVector(r, params.DimEll, gaussianSampler, true, true)
+	e_star := utils.SamplePolyVector(r, params.DimK, gaussianSampler, true, true)
 
-	w := utils.InitializeVector(r, DimK)
+	w := utils.InitializeVector(r, params.DimK)
 
 	utils.MatrixVectorMul(r, pk.A, r_star, w)
 	utils.VectorAdd(r, e_star, w, w)
@@ -131,7 +282,7 @@ func (party *Party) SignRound1(pk *PublicKey) ([]byte, StRound1) {
 }
 
 // SignRound2 performs the second round of signing
-func (party *Party) SignRound2(pk *PublicKey, msgs1 map[int][]byte, strd1 StRound1, mu string, T []int) ([]byte, StRound2) {
+func (party *Party) SignRound2(pk *PublicKey, msgs1 map[int][]byte, strd1 StRound1, mu []byte, T []int) ([]byte, StRound2) {
 	buf := new(bytes.Buffer)
 	if _, err := strd1.W.WriteTo(buf); err != nil {
 		log.Fatalf("Error writing vector w: %v\n", err)
@@ -140,40 +291,44 @@ func (party *Party) SignRound2(pk *PublicKey, msgs1 map[int][]byte, strd1 StRoun
 	return buf.Bytes(), StRound2{msgs1, T, strd1.Rstar}
 }
 
-// SignRound2 performs the second round of signing
-func (party *Party) SignRound3(pk *PublicKey, sk *PrivateKey, msgs2 map[int][]byte, strd2 StRound2, mu string, T []int, K int) (bool, []byte) {
-	if len(msgs2) != len(strd2.Hashes) {
-		return false, nil
-	}
-
+// SignRound3 performs the third round of signing. It returns an AbortError
+// naming the parties whose revealed w does not match their round 1
+// commitment.
+func (party *Party) SignRound3(pk *PublicKey, sk *PrivateKey, msgs2 map[int][]byte, strd2 StRound2, mu []byte, T []int, K int) ([]byte, error) {
+	var culprits []int
 	ws := make(map[int]structs.Vector[ring.Poly])
-	for ID, buf := range msgs2 {
-		w := make(structs.Vector[ring.Poly], DimK)
-		if _, err := w.ReadFrom(bytes.NewReader(buf)); err != nil {
-			log.Fatalf("Failed to read vector: %v", err)
+	for ID, hash := range strd2.Hashes {
+		buf, ok := msgs2[ID]
+		if !ok {
+			culprits = append(culprits, ID)
+			continue
+		}
+		w, err := readVector(buf, pk.Params.DimK)
+		if err != nil || !bytes.Equal(hash, primitives.HashCommitment(pk.A, pk.Btilde, w, ID)) {
+			culprits = append(culprits, ID)
+			continue
 		}
 		ws[ID] = w
 	}
-
-	for ID, hash := range strd2.Hashes {
-		if !bytes.Equal(hash, primitives.HashCommitment(pk.A, pk.Btilde, ws[ID], ID)) {
-			return false, nil
-		}
+	if err := abort(2, culprits); err != nil {
+		return nil, err
 	}
+
 	r := pk.Ring
 	r_nu := pk.RingNu
+	params := pk.Params
 
-	h := utils.InitializeVector(r, DimK)
+	h := utils.InitializeVector(r, params.DimK)
 	for _, W_j := range ws {
 		utils.VectorAdd(r, h, W_j, h)
 	}
 
-	roundedH := utils.RoundVector(r, r_nu, h, Nu)
+	roundedH := utils.RoundVector(r, r_nu, h, params.Nu)
 
-	c := primitives.LowNormHash(r, pk.A, pk.Btilde, roundedH, mu, Kappa)
+	c := primitives.LowNormHash(r, pk.A, pk.Btilde, roundedH, mu, params.Kappa)
 
 	// Initialize z_i to r_i
-	z_i := utils.InitializeVector(r, DimEll)
+	z_i := utils.InitializeVector(r, params.DimEll)
 	utils.VectorAdd(r, strd2.Rstar, z_i, z_i)
 
 	// Compute s*c
@@ -186,7 +341,7 @@ func (party *Party) SignRound3(pk *PublicKey, sk *PrivateKey, msgs2 map[int][]by
 
 	recover_indeces := Recover(T, P, "")
 
-	s_c := utils.InitializeVector(r, DimEll)
+	s_c := utils.InitializeVector(r, params.DimEll)
 	utils.VectorAdd(r, sk.Shares[recover_indeces[party.ID]], s_c, s_c)
 	utils.ConvertVectorToNTT(r, s_c)
 	utils.VectorPolyMul(r, s_c, c, s_c)
@@ -197,83 +352,186 @@ func (party *Party) SignRound3(pk *PublicKey, sk *PrivateKey, msgs2 map[int][]by
 		log.Fatalf("Error writing vector z: %v\n", err)
 	}
 
-	return true, buf.Bytes()
+	return buf.Bytes(), nil
 }
 
-// SignFinalize finalizes the signature
-func (party *Party) SignFinalize(pk *PublicKey, msgs2 map[int][]byte, msgs3 map[int][]byte, mu string) (ring.Poly, structs.Vector[ring.Poly], structs.Vector[ring.Poly]) {
-	w_sum := utils.InitializeVector(pk.Ring, DimK)
-	for _, buf := range msgs2 {
-		w_j := make(structs.Vector[ring.Poly], DimK)
-		if _, err := w_j.ReadFrom(bytes.NewReader(buf)); err != nil {
-			log.Fatalf("Failed to read vector: %v", err)
+// SignFinalize finalizes the signature. Each response z_j is checked against
+// the w_j revealed by party j and the commitment t_j to its key share before
+// being combined: as z_j = r_j + c*s_j, A*z_j - c*t_j = w_j - e*_j - c*e_j
+// must be close to w_j. It returns an AbortError naming the parties whose
+// messages fail these checks, and ErrInvalidSignature if the combined
+// signature still does not verify.
+func (party *Party) SignFinalize(pk *PublicKey, msgs2 map[int][]byte, msgs3 map[int][]byte, mu []byte, T []int, K int) (ring.Poly, structs.Vector[ring.Poly], structs.Vector[ring.Poly], error) {
+	params := pk.Params
+	var culprits []int
+	ws := make(map[int]structs.Vector[ring.Poly])
+	w_sum := utils.InitializeVector(pk.Ring, params.DimK)
+	for _, ID := range T {
+		w_j, err := readVector(msgs2[ID], params.DimK)
+		if err != nil {
+			culprits = append(culprits, ID)
+			continue
 		}
+		ws[ID] = w_j
 		utils.VectorAdd(pk.Ring, w_sum, w_j, w_sum)
 	}
+	if err := abort(2, culprits); err != nil {
+		return ring.Poly{}, nil, nil, err
+	}
+
+	roundedH := utils.RoundVector(pk.Ring, pk.RingNu, w_sum, params.Nu)
+	c := primitives.LowNormHash(pk.Ring, pk.A, pk.Btilde, roundedH, mu, params.Kappa)
 
-	roundedH := utils.RoundVector(pk.Ring, pk.RingNu, w_sum, Nu)
-	c := primitives.LowNormHash(pk.Ring, pk.A, pk.Btilde, roundedH, mu, Kappa)
+	P := make([]int, K)
+	for i := 0; i < K; i++ {
+		P[i] = i
+	}
+	recover_indeces := Recover(T, P, "")
 
-	z_sum := utils.InitializeVector(pk.Ring, DimEll)
-	for _, buf := range msgs3 {
-		z_j := make(structs.Vector[ring.Poly], DimEll)
-		if _, err := z_j.ReadFrom(bytes.NewReader(buf)); err != nil {
-			log.Fatalf("Failed to read vector: %v", err)
+	z_sum := utils.InitializeVector(pk.Ring, params.DimEll)
+	for _, ID := range T {
+		z_j, err := readVector(msgs3[ID], params.DimEll)
+		if err != nil || !verifyResponse(pk, K, c, pk.ShareCommitments[recover_indeces[ID]], ws[ID], z_j) {
+			culprits = append(culprits, ID)
+			continue
 		}
 		utils.VectorAdd(pk.Ring, z_sum, z_j, z_sum)
 	}
+	if err := abort(3, culprits); err != nil {
+		return ring.Poly{}, nil, nil, err
+	}
 
-	Az_bc := utils.InitializeVector(pk.Ring, DimK)
+	Az_bc := utils.InitializeVector(pk.Ring, params.DimK)
 	utils.MatrixVectorMul(pk.Ring, pk.A, z_sum, Az_bc)
-	bc := utils.InitializeVector(pk.Ring, DimK)
+	bc := utils.InitializeVector(pk.Ring, params.DimK)
 
-	b := utils.RestoreVector(pk.Ring, pk.RingXi, pk.Btilde, Xi)
+	b := utils.RestoreVector(pk.Ring, pk.RingXi, pk.Btilde, params.Xi)
 	utils.ConvertVectorToNTT(pk.Ring, b)
 
 	utils.VectorPolyMul(pk.Ring, b, c, bc)
 	utils.VectorSub(pk.Ring, Az_bc, bc, Az_bc)
 
 	utils.ConvertVectorFromNTT(pk.Ring, Az_bc)
-	roundedAz_bc := utils.RoundVector(pk.Ring, pk.RingNu, Az_bc, Nu)
+	roundedAz_bc := utils.RoundVector(pk.Ring, pk.RingNu, Az_bc, params.Nu)
 
-	Delta := utils.InitializeVector(pk.RingNu, DimK)
+	Delta := utils.InitializeVector(pk.RingNu, params.DimK)
 	utils.VectorSub(pk.RingNu, roundedH, roundedAz_bc, Delta)
 
-	return c, z_sum, Delta
+	if !verify(pk, z_sum, mu, c, Delta) {
+		return ring.Poly{}, nil, nil, ErrInvalidSignature
+	}
+	return c, z_sum, Delta, nil
 }
 
-// Verify verifies the correctness of the signature
-func Verify(pk *PublicKey, z structs.Vector[ring.Poly], mu string, c ring.Poly, roundedDelta structs.Vector[ring.Poly]) bool {
-	Az_bc := utils.InitializeVector(pk.Ring, DimK)
+// verifyResponse checks the response z of a party, in the NTT domain, against
+// its revealed w and the commitment t to its key share: w - (A*z - c*t) must
+// be within BoundW(n) for n parties, and z within BoundZ.
+func verifyResponse(pk *PublicKey, n int, c ring.Poly, t, w, z structs.Vector[ring.Poly]) bool {
+	r := pk.Ring
+	params := pk.Params
+	if t == nil || w == nil {
+		return false
+	}
+
+	Az_ct := utils.InitializeVector(r, params.DimK)
+	utils.MatrixVectorMul(r, pk.A, z, Az_ct)
+
+	ct := utils.InitializeVector(r, params.DimK)
+	utils.VectorAdd(r, t, ct, ct)
+	utils.ConvertVectorToNTT(r, ct)
+	utils.VectorPolyMul(r, ct, c, ct)
+	utils.VectorSub(r, Az_ct, ct, Az_ct)
+	utils.ConvertVectorFromNTT(r, Az_ct)
+
+	utils.VectorSub(r, w, Az_ct, Az_ct)
+	if !withinBound(r, Az_ct, params.BoundW(n)) {
+		return false
+	}
+
+	z_copy := utils.InitializeVector(r, params.DimEll)
+	utils.VectorAdd(r, z, z_copy, z_copy)
+	utils.ConvertVectorFromNTT(r, z_copy)
+	return withinBound(r, z_copy, params.BoundZ())
+}
+
+// withinBound checks that the coefficients of v, centered modulo the modulus
+// of r, are at most bound in absolute value.
+func withinBound(r *ring.Ring, v structs.Vector[ring.Poly], bound uint64) bool {
+	q := r.Modulus().Uint64()
+	for _, poly := range v {
+		for _, coeff := range poly.Coeffs[0] {
+			if coeff > q/2 {
+				coeff = q - coeff
+			}
+			if coeff > bound {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// readVector reads a vector of dim polynomials sent by another party.
+func readVector(buf []byte, dim int) (structs.Vector[ring.Poly], error) {
+	if buf == nil {
+		return nil, errors.New("missing message")
+	}
+	v := make(structs.Vector[ring.Poly], dim)
+	if _, err := v.ReadFrom(bytes.NewReader(buf)); err != nil {
+		return nil, err
+	}
+	return v, checkVector(v, dim)
+}
+
+// checkVector checks that a vector read from another party has dim
+// polynomials of the ring modulo Q.
+func checkVector(v structs.Vector[ring.Poly], dim int) error {
+	if len(v) != dim {
+		return fmt.Errorf("vector of length %d instead of %d", len(v), dim)
+	}
+	for _, poly := range v {
+		if poly.N() != 1<<LogN || poly.Level() != 0 {
+			return errors.New("malformed polynomial")
+		}
+	}
+	return nil
+}
+
+// verify verifies the correctness of the signature
+func verify(pk *PublicKey, z structs.Vector[ring.Poly], mu []byte, c ring.Poly, roundedDelta structs.Vector[ring.Poly]) bool {
+	params := pk.Params
+	Az_bc := utils.InitializeVector(pk.Ring, params.DimK)
 	utils.MatrixVectorMul(pk.Ring, pk.A, z, Az_bc)
-	bc := utils.InitializeVector(pk.Ring, DimK)
+	bc := utils.InitializeVector(pk.Ring, params.DimK)
 
-	b := utils.RestoreVector(pk.Ring, pk.RingXi, pk.Btilde, Xi)
+	b := utils.RestoreVector(pk.Ring, pk.RingXi, pk.Btilde, params.Xi)
 	utils.ConvertVectorToNTT(pk.Ring, b)
 
 	utils.VectorPolyMul(pk.Ring, b, c, bc)
 	utils.VectorSub(pk.Ring, Az_bc, bc, Az_bc)
 
 	utils.ConvertVectorFromNTT(pk.Ring, Az_bc)
-	roundedAz_bc := utils.RoundVector(pk.Ring, pk.RingNu, Az_bc, Nu)
+	roundedAz_bc := utils.RoundVector(pk.Ring, pk.RingNu, Az_bc, params.Nu)
 
-	Az_bc_Delta := utils.InitializeVector(pk.RingNu, DimK)
+	Az_bc_Delta := utils.InitializeVector(pk.RingNu, params.DimK)
 	utils.VectorAdd(pk.RingNu, roundedAz_bc, roundedDelta, Az_bc_Delta)
 
-	computedC := primitives.LowNormHash(pk.Ring, pk.A, pk.Btilde, Az_bc_Delta, mu, Kappa)
+	computedC := primitives.LowNormHash(pk.Ring, pk.A, pk.Btilde, Az_bc_Delta, mu, params.Kappa)
 	if !pk.Ring.Equal(c, computedC) {
 		return false
 	}
 
-	Delta := utils.RestoreVector(pk.Ring, pk.RingNu, roundedDelta, Nu)
-	utils.ConvertVectorFromNTT(pk.Ring, z)
-	return CheckL2Norm(pk.Ring, Delta, z)
+	Delta := utils.RestoreVector(pk.Ring, pk.RingNu, roundedDelta, params.Nu)
+	z_copy := utils.InitializeVector(pk.Ring, params.DimEll)
+	utils.VectorAdd(pk.Ring, z, z_copy, z_copy)
+	utils.ConvertVectorFromNTT(pk.Ring, z_copy)
+	return params.CheckL2Norm(pk.Ring, Delta, z_copy)
 }
 
 // CheckL2Norm checks if the L2 norm of the vector of Delta is less than or equal to Bsquare
-func CheckL2Norm(r *ring.Ring, Delta structs.Vector[ring.Poly], z structs.Vector[ring.Poly]) bool {
+func (p *Params) CheckL2Norm(r *ring.Ring, Delta structs.Vector[ring.Poly], z structs.Vector[ring.Poly]) bool {
 	sumSquares := big.NewInt(0)
-	qBig := new(big.Int).SetUint64(Q)
+	qBig := new(big.Int).SetUint64(p.Q)
 	halfQ := new(big.Int).Div(qBig, big.NewInt(2))
 
 	DeltaCoeffsBigInt := make(structs.Vector[[]*big.Int], r.N())
@@ -308,9 +566,5 @@ func CheckL2Norm(r *ring.Ring, Delta structs.Vector[ring.Poly], z structs.Vector
 		}
 	}
 
-	log.Println("Sum of Squares:", sumSquares)
-	log.Println("Bsquare:", Bsquare)
-
-	Bsquare, _ := new(big.Int).SetString(Bsquare, 10)
-	return sumSquares.Cmp(Bsquare) <= 0
+	return sumSquares.Cmp(p.bsquare()) <= 0
 }
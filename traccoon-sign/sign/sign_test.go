@@ -0,0 +1,371 @@
+package sign
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"slices"
+	"testing"
+	"traccoon-sign/primitives"
+	"traccoon-sign/utils"
+
+	"github.com/tuneinsight/lattigo/v5/ring"
+	"github.com/tuneinsight/lattigo/v5/utils/sampling"
+	"github.com/tuneinsight/lattigo/v5/utils/structs"
+)
+
+// signRounds runs the three rounds of signing for the active parties T, with
+// tamper altering the messages of round 2 and 3 before they are delivered.
+func signRounds(t *testing.T, T []int, N int, tamper func(msgs2, msgs3 map[int][]byte)) (*PublicKey, map[int][]byte, map[int][]byte, error) {
+	t.Helper()
+	pk, sks, err := NewThresholdKeys(len(T), N, DefaultParams)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mu := message(t, pk)
+
+	parties := make(map[int]*Party)
+	msgs1 := make(map[int][]byte)
+	strd1 := make(map[int]StRound1)
+	for _, ID := range T {
+		parties[ID] = NewParty(ID, pk)
+		msgs1[ID], strd1[ID] = parties[ID].SignRound1(pk)
+	}
+
+	msgs2 := make(map[int][]byte)
+	strd2 := make(map[int]StRound2)
+	for _, ID := range T {
+		msgs2[ID], strd2[ID] = parties[ID].SignRound2(pk, msgs1, strd1[ID], mu, T)
+	}
+
+	msgs3 := make(map[int][]byte)
+	tamper(msgs2, nil)
+	for _, ID := range T {
+		msgs3[ID], err = parties[ID].SignRound3(pk, &sks[ID], msgs2, strd2[ID], mu, T, N)
+		if err != nil {
+			return pk, msgs2, msgs3, err
+		}
+	}
+	tamper(nil, msgs3)
+	return pk, msgs2, msgs3, nil
+}
+
+// message returns the digest signed for the test message under pk.
+func message(t *testing.T, pk *PublicKey) []byte {
+	t.Helper()
+	mu, err := Mu(pk, []byte("Message"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return mu
+}
+
+func requireCulprits(t *testing.T, err error, round int, culprits []int) {
+	t.Helper()
+	var abortErr *AbortError
+	if !errors.As(err, &abortErr) {
+		t.Fatalf("got %v instead of an AbortError", err)
+	}
+	if abortErr.Round != round || !slices.Equal(abortErr.Parties, culprits) {
+		t.Fatalf("got %v, want parties %v in round %d", err, culprits, round)
+	}
+}
+
+func TestIdentifiableAborts(t *testing.T) {
+	T := []int{0, 2, 3}
+	N := 4
+
+	t.Run("honest", func(t *testing.T) {
+		pk, msgs2, msgs3, err := signRounds(t, T, N, func(_, _ map[int][]byte) {})
+		if err != nil {
+			t.Fatal(err)
+		}
+		mu := message(t, pk)
+		c, z, Delta, err := NewParty(T[0], pk).SignFinalize(pk, msgs2, msgs3, mu, T, N)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !verify(pk, z, mu, c, Delta) {
+			t.Fatal("invalid signature")
+		}
+	})
+
+	t.Run("wrong w", func(t *testing.T) {
+		_, _, _, err := signRounds(t, T, N, func(msgs2, _ map[int][]byte) {
+			if msgs2 != nil {
+				msgs2[2] = bytes.Clone(msgs2[0])
+			}
+		})
+		requireCulprits(t, err, 2, []int{2})
+	})
+
+	t.Run("malformed w", func(t *testing.T) {
+		_, _, _, err := signRounds(t, T, N, func(msgs2, _ map[int][]byte) {
+			if msgs2 != nil {
+				msgs2[0] = msgs2[0][:10]
+				delete(msgs2, 3)
+			}
+		})
+		requireCulprits(t, err, 2, []int{0, 3})
+	})
+
+	t.Run("wrong z", func(t *testing.T) {
+		pk, msgs2, msgs3, err := signRounds(t, T, N, func(_, msgs3 map[int][]byte) {
+			if msgs3 != nil {
+				msgs3[3] = bytes.Clone(msgs3[3])
+				msgs3[3][len(msgs3[3])-1] ^= 1
+			}
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, _, _, err = NewParty(T[0], pk).SignFinalize(pk, msgs2, msgs3, message(t, pk), T, N)
+		requireCulprits(t, err, 3, []int{3})
+	})
+
+	t.Run("z of another party", func(t *testing.T) {
+		pk, msgs2, msgs3, err := signRounds(t, T, N, func(_, msgs3 map[int][]byte) {
+			if msgs3 != nil {
+				msgs3[0], msgs3[2] = msgs3[2], msgs3[0]
+			}
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, _, _, err = NewParty(T[0], pk).SignFinalize(pk, msgs2, msgs3, message(t, pk), T, N)
+		requireCulprits(t, err, 3, []int{0, 2})
+	})
+}
+
+// TestBoundW checks that a response is accepted when w - (A*z - c*t) is
+// BoundW(N) in every coefficient, and rejected one above.
+func TestBoundW(t *testing.T) {
+	T := []int{0, 2, 3}
+	N := 4
+	pk, msgs2, msgs3, err := signRounds(t, T, N, func(_, _ map[int][]byte) {})
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := pk.Ring
+	p := pk.Params
+
+	w_sum := utils.InitializeVector(r, p.DimK)
+	for _, ID := range T {
+		w_j, err := readVector(msgs2[ID], p.DimK)
+		if err != nil {
+			t.Fatal(err)
+		}
+		utils.VectorAdd(r, w_sum, w_j, w_sum)
+	}
+	roundedH := utils.RoundVector(r, pk.RingNu, w_sum, p.Nu)
+	c := primitives.LowNormHash(r, pk.A, pk.Btilde, roundedH, message(t, pk), p.Kappa)
+
+	ID := T[1]
+	z, err := readVector(msgs3[ID], p.DimEll)
+	if err != nil {
+		t.Fatal(err)
+	}
+	commitment := pk.ShareCommitments[Recover(T, []int{0, 1, 2, 3}, "")[ID]]
+
+	// A*z - c*t, to which w adds the offset
+	Az_ct := utils.InitializeVector(r, p.DimK)
+	utils.MatrixVectorMul(r, pk.A, z, Az_ct)
+	ct := utils.InitializeVector(r, p.DimK)
+	utils.VectorAdd(r, commitment, ct, ct)
+	utils.ConvertVectorToNTT(r, ct)
+	utils.VectorPolyMul(r, ct, c, ct)
+	utils.VectorSub(r, Az_ct, ct, Az_ct)
+	utils.ConvertVectorFromNTT(r, Az_ct)
+
+	for _, tc := range []struct {
+		offset uint64
+		ok     bool
+	}{
+		{p.BoundW(N), true},
+		{p.BoundW(N) + 1, false},
+	} {
+		w := utils.InitializeVector(r, p.DimK)
+		for i := range w {
+			for k := range w[i].Coeffs[0] {
+				w[i].Coeffs[0][k] = tc.offset
+			}
+		}
+		utils.VectorAdd(r, w, Az_ct, w)
+		if verifyResponse(pk, N, c, commitment, w, z) != tc.ok {
+			t.Errorf("offset %d: accepted is %v", tc.offset, !tc.ok)
+		}
+	}
+}
+
+// TestResponsesAtBound has every party commit to an r* with coefficients of
+// BoundStar instead of sampling it. Each response then passes the checks of
+// its party, but with a set whose B is below the sum of such responses, the
+// combined signature does not verify and SignFinalize must say so.
+func TestResponsesAtBound(t *testing.T) {
+	p := *DefaultParams
+	p.B = 3162277660168.379
+	p.Bsquare = "10000000000000000000000000"
+	T := []int{0, 1, 2}
+	N := 3
+
+	sign := func(t *testing.T, atBound bool) error {
+		pk, sks, err := NewThresholdKeys(len(T), N, &p)
+		if err != nil {
+			t.Fatal(err)
+		}
+		mu := message(t, pk)
+		r := pk.Ring
+
+		parties := make(map[int]*Party)
+		msgs1 := make(map[int][]byte)
+		strd1 := make(map[int]StRound1)
+		for _, ID := range T {
+			parties[ID] = NewParty(ID, pk)
+			msgs1[ID], strd1[ID] = parties[ID].SignRound1(pk)
+			if !atBound {
+				continue
+			}
+
+			r_star := make(structs.Vector[ring.Poly], p.DimEll)
+			for i := range r_star {
+				r_star[i] = r.NewPoly()
+				for k := range r_star[i].Coeffs[0] {
+					r_star[i].Coeffs[0][k] = uint64(p.BoundStar)
+				}
+			}
+			utils.ConvertVectorToNTT(r, r_star)
+			w := utils.InitializeVector(r, p.DimK)
+			utils.MatrixVectorMul(r, pk.A, r_star, w)
+			utils.ConvertVectorFromNTT(r, w)
+			msgs1[ID] = primitives.HashCommitment(pk.A, pk.Btilde, w, ID)
+			strd1[ID] = StRound1{W: w, Rstar: r_star}
+		}
+
+		msgs2 := make(map[int][]byte)
+		strd2 := make(map[int]StRound2)
+		for _, ID := range T {
+			msgs2[ID], strd2[ID] = parties[ID].SignRound2(pk, msgs1, strd1[ID], mu, T)
+		}
+		msgs3 := make(map[int][]byte)
+		for _, ID := range T {
+			if msgs3[ID], err = parties[ID].SignRound3(pk, &sks[ID], msgs2, strd2[ID], mu, T, N); err != nil {
+				t.Fatal(err)
+			}
+		}
+		_, _, _, err = parties[T[0]].SignFinalize(pk, msgs2, msgs3, mu, T, N)
+		return err
+	}
+
+	if err := sign(t, false); err != nil {
+		t.Fatalf("honest responses: %v", err)
+	}
+	if err := sign(t, true); !errors.Is(err, ErrInvalidSignature) {
+		t.Fatalf("responses at the bound: got %v instead of ErrInvalidSignature", err)
+	}
+}
+
+// seededTranscript deals the keys of 2 out of 3 parties with the parameters
+// p from a seed filled with b, parties 0 and 2 signing the test message with
+// randomness keyed by b and their ID. It returns the encoded verification
+// key, commitments to the key shares, private keys and signature.
+func seededTranscript(t *testing.T, p *Params, b byte) [][]byte {
+	t.Helper()
+	var seed [SeedSize]byte
+	for i := range seed {
+		seed[i] = b
+	}
+	T, N := []int{0, 2}, 3
+	pk, sks, err := NewThresholdKeysFromSeed(&seed, len(T), N, p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mu := message(t, pk)
+
+	parties := make(map[int]*Party)
+	msgs1 := make(map[int][]byte)
+	strd1 := make(map[int]StRound1)
+	for _, ID := range T {
+		parties[ID] = NewParty(ID, pk)
+		parties[ID].Rand, _ = sampling.NewKeyedPRNG([]byte{b, byte(ID)})
+		msgs1[ID], strd1[ID] = parties[ID].SignRound1(pk)
+	}
+	msgs2 := make(map[int][]byte)
+	strd2 := make(map[int]StRound2)
+	for _, ID := range T {
+		msgs2[ID], strd2[ID] = parties[ID].SignRound2(pk, msgs1, strd1[ID], mu, T)
+	}
+	msgs3 := make(map[int][]byte)
+	for _, ID := range T {
+		if msgs3[ID], err = parties[ID].SignRound3(pk, &sks[ID], msgs2, strd2[ID], mu, T, N); err != nil {
+			t.Fatal(err)
+		}
+	}
+	c, z, Delta, err := parties[T[0]].SignFinalize(pk, msgs2, msgs3, mu, T, N)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !verify(pk, z, mu, c, Delta) {
+		t.Fatal("invalid signature")
+	}
+
+	var transcript [][]byte
+	pkBytes, err := pk.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	cmtsBytes, err := pk.MarshalCommitments()
+	if err != nil {
+		t.Fatal(err)
+	}
+	transcript = append(transcript, pkBytes, cmtsBytes)
+	for _, sk := range sks {
+		skBytes, err := sk.MarshalBinary()
+		if err != nil {
+			t.Fatal(err)
+		}
+		transcript = append(transcript, skBytes)
+	}
+	sigBytes, err := (&Signature{Params: pk.Params, C: c, Z: z, Delta: Delta}).MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return append(transcript, sigBytes)
+}
+
+func TestSeededTranscript(t *testing.T) {
+	first, second := seededTranscript(t, DefaultParams, 1), seededTranscript(t, DefaultParams, 1)
+	if !slices.EqualFunc(first, second, bytes.Equal) {
+		t.Fatal("transcript not reproduced from the same seeds")
+	}
+	other := seededTranscript(t, DefaultParams, 2)
+	for i := range first {
+		if bytes.Equal(first[i], other[i]) {
+			t.Fatalf("element %d of the transcript unchanged with other seeds", i)
+		}
+	}
+}
+
+func TestKnownAnswer(t *testing.T) {
+	// SHA-256 of the encoded verification key, commitments, private keys and
+	// signature
+	for _, tc := range []struct {
+		params *Params
+		want   string
+	}{
+		{TRaccoon128, "2bb8ebd67ee4e81b7de0e9f4c79dd73fa80cca1dafe47e4d926b731ca3a2c568"},
+		{TRaccoon128N256, "44fca31cdbea7c7095b71c50300a69d75f3f0ed82d7e90f1dc6e9ce336e02eb1"},
+		{TRaccoon128N64, "22f2bda5608788d6e237a825c53511f09fbef53b788b02534070b9a932731de5"},
+		{TRaccoon128N16, "ffd836cdbceb23c020e60a8eacbdea17474f10f57fa57e049344a261edb66dde"},
+	} {
+		t.Run(tc.params.Name, func(t *testing.T) {
+			h := sha256.New()
+			for _, data := range seededTranscript(t, tc.params, 0) {
+				h.Write(data)
+			}
+			if got := hex.EncodeToString(h.Sum(nil)); got != tc.want {
+				t.Fatalf("transcript hashes to %s instead of %s", got, tc.want)
+			}
+		})
+	}
+}
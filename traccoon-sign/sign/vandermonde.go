@@ -40,19 +40,19 @@ func share_n(r *ring.Ring, sampler *ring.GaussianSampler, x structs.Vector[ring.
 	}
 
 	newIdx := idx + "N:"
-	S := utils.InitializeVector(r, DimEll)
+	S := utils.InitializeVector(r, len(x))
 
 	for _, user := range P {
 		if user == minP {
 			continue
 		}
 		userIdx := fmt.Sprintf("%s%d", newIdx, user-minP)
-		D[user][userIdx] = utils.SamplePolyVector(r, DimEll, sampler, false, false)
+		D[user][userIdx] = utils.SamplePolyVector(r, len(x), sampler, false, false)
 		utils.VectorAdd(r, S, D[user][userIdx], S)
 	}
 
 	zeroIdx := fmt.Sprintf("%s%d", newIdx, 0)
-	D[minP][zeroIdx] = utils.InitializeVector(r, DimEll)
+	D[minP][zeroIdx] = utils.InitializeVector(r, len(x))
 	utils.VectorSub(r, x, S, D[minP][zeroIdx])
 
 	return D
@@ -90,8 +90,8 @@ func share_2(r *ring.Ring, sampler *ring.GaussianSampler, x structs.Vector[ring.
 	newIdx := idx + "B:"
 
 	for i := 0; i < n; i++ {
-		x0 := utils.SamplePolyVector(r, DimEll, sampler, false, false)
-		x1 := utils.InitializeVector(r, DimEll)
+		x0 := utils.SamplePolyVector(r, len(x), sampler, false, false)
+		x1 := utils.InitializeVector(r, len(x))
 		utils.VectorSub(r, x, x0, x1)
 		y := [2]structs.Vector[ring.Poly]{x0, x1}
 		for _, user := range P {
@@ -158,8 +158,8 @@ func Share(r *ring.Ring, sampler *ring.GaussianSampler, x structs.Vector[ring.Po
 		} else if k == T {
 			rec_D = append(rec_D, Share(r, sampler, x, P_L, T, idx_L))
 		} else {
-			x0 := utils.SamplePolyVector(r, DimEll, sampler, false, false)
-			x1 := utils.InitializeVector(r, DimEll)
+			x0 := utils.SamplePolyVector(r, len(x), sampler, false, false)
+			x1 := utils.InitializeVector(r, len(x))
 			utils.VectorSub(r, x, x0, x1)
 			rec_D = append(rec_D, Share(r, sampler, x0, P_L, k, idx_L))
 			rec_D = append(rec_D, Share(r, sampler, x1, P_R, T-k, idx_R))
@@ -15,7 +15,7 @@ import (
 // ============== Helper functions for testing ==============
 
 func recover(r *ring.Ring, D ShareMap, R IndexMap) structs.Vector[ring.Poly] {
-	res := utils.InitializeVector(r, DimEll)
+	res := utils.InitializeVector(r, DefaultParams.DimEll)
 	for usr, index := range R {
 		utils.VectorAdd(r, D[usr][index], res, res) // Process each index
 	}
@@ -63,8 +63,8 @@ func TestVandermondeRecover(t *testing.T) {
 		for i := 0; i < N; i++ {
 			P[i] = i
 		}
-		// x := utils.SamplePolyVector(r, DimEll+DimK, gaussianSampler, false, false)
-		x := utils.InitializeVector(r, DimEll)
+		// x := utils.SamplePolyVector(r, DefaultParams.DimEll+DefaultParams.DimK, gaussianSampler, false, false)
+		x := utils.InitializeVector(r, DefaultParams.DimEll)
 		D := Share(r, gaussianSampler, x, P, T, "")
 		var NBITER = 20
 		for i := 0; i < NBITER; i++ {